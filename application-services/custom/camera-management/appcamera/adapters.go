@@ -0,0 +1,205 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
+	"github.com/pkg/errors"
+)
+
+// CameraAdapter describes how the camera management app interacts with a specific type of camera.
+// Support for a new type of camera (and its device service) is added by implementing this interface
+// and registering it via CameraManagementApp.RegisterCameraAdapter.
+type CameraAdapter interface {
+	// Type returns the type of camera handled by this adapter.
+	Type() CameraType
+	// ServiceName returns the name of the device service providing this type of camera. It may be
+	// empty if the cameras are not provided by a single well-known device service.
+	ServiceName() string
+	// Supports returns true if the adapter is able to manage the specified device.
+	Supports(device dtos.Device) bool
+	// StreamUri returns the uri of the video stream to feed into the pipeline.
+	StreamUri(device dtos.Device, sr StartPipelineRequest) (string, error)
+	// Features returns the capabilities of the camera.
+	Features(device dtos.Device) (CameraFeatures, error)
+	// StartStreaming is called before a pipeline is started, for cameras that need to be told to
+	// start streaming. Cameras that are always streaming should simply return nil.
+	StartStreaming(device dtos.Device, sr StartPipelineRequest) error
+	// StopStreaming is called after a pipeline has been stopped, and reverts StartStreaming.
+	StopStreaming(device dtos.Device) error
+	// DefaultStreamConfig fills in the camera specific parts of the request used to start the default pipeline.
+	DefaultStreamConfig(device dtos.Device, sr *StartPipelineRequest) error
+	// SecretName returns the name of the secret holding the stream credentials, or an empty string
+	// if no credentials should be added to the stream uri.
+	SecretName() string
+}
+
+// RegisterCameraAdapter adds support for a new type of camera. Adapters are matched against devices
+// in the order they are registered, so this must be called before Run.
+func (app *CameraManagementApp) RegisterCameraAdapter(adapter CameraAdapter) {
+	app.adapters = append(app.adapters, adapter)
+}
+
+// getCameraAdapter returns the adapter for the specified device, preferring the adapter that
+// matches the device's service name before falling back to checking the device's protocols.
+func (app *CameraManagementApp) getCameraAdapter(device dtos.Device) (CameraAdapter, error) {
+	for _, adapter := range app.adapters {
+		if name := adapter.ServiceName(); name != "" && name == device.ServiceName {
+			return adapter, nil
+		}
+	}
+	for _, adapter := range app.adapters {
+		if adapter.Supports(device) {
+			return adapter, nil
+		}
+	}
+	return nil, errors.Errorf("no camera adapter found for device %s of device service %s", device.Name, device.ServiceName)
+}
+
+// getDeviceAdapter looks up the device by name and returns it along with its adapter.
+func (app *CameraManagementApp) getDeviceAdapter(deviceName string) (dtos.Device, CameraAdapter, error) {
+	device, err := app.getDeviceByName(deviceName)
+	if err != nil {
+		return dtos.Device{}, nil, err
+	}
+	adapter, err := app.getCameraAdapter(device)
+	if err != nil {
+		return dtos.Device{}, nil, err
+	}
+	return device, adapter, nil
+}
+
+// onvifAdapter handles cameras provided by the device-onvif-camera device service.
+type onvifAdapter struct {
+	app *CameraManagementApp
+}
+
+func (a *onvifAdapter) Type() CameraType {
+	return Onvif
+}
+
+func (a *onvifAdapter) ServiceName() string {
+	return a.app.appConfig().OnvifDeviceServiceName
+}
+
+func (a *onvifAdapter) Supports(device dtos.Device) bool {
+	_, ok := device.Protocols["Onvif"]
+	return ok
+}
+
+func (a *onvifAdapter) StreamUri(device dtos.Device, sr StartPipelineRequest) (string, error) {
+	if sr.Onvif == nil {
+		return "", errors.New("missing required stream configuration")
+	}
+	return a.app.getOnvifStreamUri(device.Name, sr.Onvif.ProfileToken)
+}
+
+func (a *onvifAdapter) Features(device dtos.Device) (CameraFeatures, error) {
+	features := CameraFeatures{CameraType: Onvif}
+	caps, err := a.app.getCapabilities(device.Name)
+	if err != nil {
+		return CameraFeatures{}, errors.Wrapf(err, "unable to get device capabilities")
+	}
+	if caps.Capabilities.PTZ.XAddr != "" {
+		features.PTZ = true
+
+		if ptzConfigs, err := a.app.getPTZConfiguration(device.Name); err != nil {
+			a.app.lc.Errorf("Error calling Get PTZ Configuration for device %s: %s", device.Name, err.Error())
+		} else {
+			features.Zoom = ptzConfigs.PTZConfiguration[0].ZoomLimits != nil
+		}
+	}
+	return features, nil
+}
+
+func (a *onvifAdapter) StartStreaming(_ dtos.Device, _ StartPipelineRequest) error {
+	// onvif cameras are always streaming
+	return nil
+}
+
+func (a *onvifAdapter) StopStreaming(_ dtos.Device) error {
+	return nil
+}
+
+func (a *onvifAdapter) DefaultStreamConfig(device dtos.Device, sr *StartPipelineRequest) error {
+	profileResponse, err := a.app.getProfiles(device.Name)
+	if err != nil {
+		return errors.Errorf("failed to get profiles for device %s, message: %v", device.Name, err)
+	}
+
+	a.app.lc.Debugf("Onvif profile information found for device: %s message: %v", device.Name, profileResponse)
+	sr.Onvif = &OnvifPipelineConfig{
+		ProfileToken: string(profileResponse.Profiles[0].Token),
+	}
+	return nil
+}
+
+func (a *onvifAdapter) SecretName() string {
+	return onvifAuth
+}
+
+// usbAdapter handles cameras provided by the device-usb-camera device service.
+type usbAdapter struct {
+	app *CameraManagementApp
+}
+
+func (a *usbAdapter) Type() CameraType {
+	return USB
+}
+
+func (a *usbAdapter) ServiceName() string {
+	return a.app.appConfig().USBDeviceServiceName
+}
+
+func (a *usbAdapter) Supports(device dtos.Device) bool {
+	_, ok := device.Protocols["USB"]
+	return ok
+}
+
+func (a *usbAdapter) StreamUri(device dtos.Device, _ StartPipelineRequest) (string, error) {
+	return a.app.getUSBStreamUri(device.Name)
+}
+
+func (a *usbAdapter) Features(_ dtos.Device) (CameraFeatures, error) {
+	return CameraFeatures{CameraType: USB}, nil
+}
+
+func (a *usbAdapter) StartStreaming(device dtos.Device, sr StartPipelineRequest) error {
+	req := USBStartStreamingRequest{}
+	if sr.USB != nil {
+		req = *sr.USB
+	}
+
+	modes, err := a.app.getUSBStreamingModes(device.Name)
+	if err != nil {
+		// do not prevent streaming when the camera cannot report its formats, the device service will validate it
+		a.app.lc.Warnf("Unable to validate streaming request, failed to get image formats of usb camera %s: %v", device.Name, err)
+	} else if req, err = validateUSBStartStreamingRequest(modes, req); err != nil {
+		return errors.Wrapf(err, "invalid streaming request for usb camera %s", device.Name)
+	}
+
+	if _, err = a.app.startStreaming(device.Name, req); err != nil {
+		return errors.Wrapf(err, "failed to start streaming usb camera %s", device.Name)
+	}
+	return nil
+}
+
+func (a *usbAdapter) StopStreaming(device dtos.Device) error {
+	if _, err := a.app.stopStreaming(device.Name); err != nil {
+		return errors.Wrapf(err, "failed to stop streaming usb camera %s", device.Name)
+	}
+	return nil
+}
+
+func (a *usbAdapter) DefaultStreamConfig(device dtos.Device, sr *StartPipelineRequest) error {
+	a.app.lc.Debugf("Usb protocol found for device: %s", device.Name)
+	sr.USB = &USBStartStreamingRequest{}
+	return nil
+}
+
+func (a *usbAdapter) SecretName() string {
+	return rtspAuth
+}
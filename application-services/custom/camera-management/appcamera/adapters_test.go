@@ -0,0 +1,101 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"testing"
+
+	sdkMocks "github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces/mocks"
+	clientMocks "github.com/edgexfoundry/go-mod-core-contracts/v3/clients/interfaces/mocks"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos/responses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newAdapterTestApp returns an app with the built-in adapters registered
+func newAdapterTestApp(rtspServiceName string) *CameraManagementApp {
+	cfg := validTestConfig()
+	cfg.OnvifDeviceServiceName = "device-onvif-camera"
+	cfg.USBDeviceServiceName = "device-usb-camera"
+	cfg.RTSPDeviceServiceName = rtspServiceName
+	app := newTestApp(cfg)
+	app.RegisterCameraAdapter(&onvifAdapter{app: app})
+	app.RegisterCameraAdapter(&usbAdapter{app: app})
+	app.RegisterCameraAdapter(&rtspAdapter{app: app})
+	return app
+}
+
+func TestGetCameraAdapter(t *testing.T) {
+	rtspProtocols := map[string]dtos.ProtocolProperties{rtspProtocol: {rtspStreamUriKey: "rtsp://camera:554/stream"}}
+
+	tests := []struct {
+		name            string
+		rtspServiceName string
+		device          dtos.Device
+		expected        CameraType
+	}{
+		{"onvif service", "", dtos.Device{ServiceName: "device-onvif-camera"}, Onvif},
+		{"usb service", "", dtos.Device{ServiceName: "device-usb-camera"}, USB},
+		{"rtsp service", "device-rtsp", dtos.Device{ServiceName: "device-rtsp"}, RTSP},
+		{"rtsp protocol", "", dtos.Device{ServiceName: "device-other", Protocols: rtspProtocols}, RTSP},
+		{"usb protocol", "", dtos.Device{ServiceName: "device-other",
+			Protocols: map[string]dtos.ProtocolProperties{"USB": {}}}, USB},
+		{"service before protocol", "", dtos.Device{ServiceName: "device-onvif-camera", Protocols: rtspProtocols}, Onvif},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := newAdapterTestApp(test.rtspServiceName)
+			adapter, err := app.getCameraAdapter(test.device)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, adapter.Type())
+		})
+	}
+
+	_, err := newAdapterTestApp("").getCameraAdapter(dtos.Device{Name: "sensor", ServiceName: "device-virtual"})
+	require.Error(t, err)
+}
+
+func TestGetAllDevicesWithoutRTSPServiceName(t *testing.T) {
+	rtspCamera := dtos.Device{Name: "rtsp-camera", ServiceName: "device-rtsp",
+		Protocols: map[string]dtos.ProtocolProperties{rtspProtocol: {rtspStreamUriKey: "rtsp://camera:554/stream"}}}
+	onvifCamera := dtos.Device{Name: "onvif-camera", ServiceName: "device-onvif-camera"}
+	sensor := dtos.Device{Name: "sensor", ServiceName: "device-virtual"}
+
+	deviceClient := &clientMocks.DeviceClient{}
+	deviceClient.On("AllDevices", mock.Anything, mock.Anything, 0, -1).Return(
+		responses.MultiDevicesResponse{Devices: []dtos.Device{rtspCamera, onvifCamera, sensor}}, nil)
+	service := &sdkMocks.ApplicationService{}
+	service.On("DeviceClient").Return(deviceClient)
+
+	app := newAdapterTestApp("")
+	app.service = service
+
+	devices, err := app.getAllDevices()
+	require.NoError(t, err)
+	assert.Equal(t, []dtos.Device{rtspCamera, onvifCamera}, devices)
+}
+
+func TestGetAllDevicesByServiceName(t *testing.T) {
+	rtspCamera := dtos.Device{Name: "rtsp-camera", ServiceName: "device-rtsp"}
+
+	deviceClient := &clientMocks.DeviceClient{}
+	deviceClient.On("DevicesByServiceName", mock.Anything, "device-rtsp", 0, -1).Return(
+		responses.MultiDevicesResponse{Devices: []dtos.Device{rtspCamera}}, nil)
+	deviceClient.On("DevicesByServiceName", mock.Anything, mock.Anything, 0, -1).Return(
+		responses.MultiDevicesResponse{}, nil)
+	service := &sdkMocks.ApplicationService{}
+	service.On("DeviceClient").Return(deviceClient)
+
+	app := newAdapterTestApp("device-rtsp")
+	app.service = service
+
+	devices, err := app.getAllDevices()
+	require.NoError(t, err)
+	assert.Equal(t, []dtos.Device{rtspCamera}, devices)
+	deviceClient.AssertNotCalled(t, "AllDevices", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
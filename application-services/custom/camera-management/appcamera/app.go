@@ -6,14 +6,19 @@
 package appcamera
 
 import (
+	"context"
 	"net/http"
 	"sync"
 
 	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
 	"github.com/edgexfoundry/go-mod-core-contracts/v3/clients/logger"
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/pkg/errors"
 )
 
+// ServiceKey is the key of the service, which names its configuration and secrets
+const ServiceKey = "app-camera-management"
+
 type CameraManagementApp struct {
 	service        interfaces.ApplicationService
 	lc             logger.LoggingClient
@@ -23,27 +28,74 @@ type CameraManagementApp struct {
 	ptzRangeMap    map[string]PTZRange
 	ptzRangeMutex  sync.RWMutex
 	fileServer     http.Handler
+	adapters       []CameraAdapter
+	privacyMasks   *privacyMaskStore
+	statistics     *statisticsAggregator
+	// statisticsPublisher is only set when the periodic statistics events are enabled
+	statisticsPublisher interfaces.BackgroundPublisher
+	instanceId          string
+	lease               Lease
+	// elector is only set when the instances are coordinated through leader election
+	elector     *leaderElector
+	openApiSpec *openapi3.T
+	ptz         *ptzController
+	inventory   *inventoryCache
+	catalog     catalogCache
+	// configMutex protects the custom configuration, which is updated by the Configuration Provider
+	configMutex sync.RWMutex
 }
 
 func NewCameraManagementApp(service interfaces.ApplicationService) *CameraManagementApp {
-	return &CameraManagementApp{
+	app := &CameraManagementApp{
 		service:      service,
 		lc:           service.LoggingClient(),
 		config:       &ServiceConfig{},
 		pipelinesMap: make(map[string]PipelineInfo),
 		ptzRangeMap:  make(map[string]PTZRange),
 	}
+	app.RegisterCameraAdapter(&onvifAdapter{app: app})
+	app.RegisterCameraAdapter(&usbAdapter{app: app})
+	app.RegisterCameraAdapter(&rtspAdapter{app: app})
+	return app
 }
 
 func (app *CameraManagementApp) Run() error {
-	if err := app.service.LoadCustomConfig(app.config, "AppCustom"); err != nil {
+	if err := app.service.LoadCustomConfig(app.config, customConfigSection); err != nil {
 		return errors.Wrap(err, "failed to load custom configuration")
 	}
 
+	cfg := app.appConfig()
+	if err := validateCustomConfig(cfg); err != nil {
+		return err
+	}
+	if err := app.initCoordination(); err != nil {
+		return err
+	}
+	// the configuration was validated above
+	ptzCfg, _ := parsePTZConfig(cfg.PTZ)
+	app.ptz = newPTZController(app, ptzCfg)
+	inventoryTTL, _ := parseInventoryCacheTTL(cfg.Inventory.CacheTTL)
+	app.inventory = newInventoryCache(inventoryTTL)
+
+	app.privacyMasks = newPrivacyMaskStore(cfg.PrivacyMasksFile)
+	if err := app.privacyMasks.load(); err != nil {
+		return err
+	}
+
+	if cfg.Statistics.Enabled {
+		if err := app.initStatistics(); err != nil {
+			return err
+		}
+	}
+
 	if err := app.addRoutes(); err != nil {
 		return err
 	}
 
+	if err := app.service.ListenForCustomConfigChanges(&CustomConfig{}, customConfigSection, app.onConfigChanged); err != nil {
+		return errors.Wrap(err, "failed to listen for custom configuration changes")
+	}
+
 	// Subscribe to events.
 	err := app.service.SetDefaultFunctionsPipeline(
 		app.processEdgeXDeviceSystemEvent)
@@ -56,20 +108,57 @@ func (app *CameraManagementApp) Run() error {
 		app.lc.Errorf("Unable to query EVAM pipeline statuses. Is EVAM running? %s", err.Error())
 	}
 
-	devices, err := app.getAllDevices()
-	if err != nil {
-		app.lc.Errorf("no devices found: %s", err.Error())
+	// background tasks run until the service stops, which is waited for to let them clean up
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	if app.elector != nil {
+		// the default pipelines are started once this instance is elected
+		app.elector.run(ctx, wg)
 	} else {
-		for _, device := range devices {
-			if err = app.startDefaultPipeline(device); err != nil {
-				app.lc.Errorf("Error starting default pipeline for %s, %v", device.Name, err)
-			}
+		app.startDefaultPipelines()
+	}
+
+	app.runCatalogRefresh(ctx, wg)
+
+	if app.statistics != nil {
+		if err = app.runStatistics(ctx, wg); err != nil {
+			return err
 		}
 	}
 
-	if err = app.service.Run(); err != nil {
+	err = app.service.Run()
+	// apply the shutdown policy even if the service failed, so that no pipelines are leaked
+	app.logShutdownReport(app.shutdown())
+	if err != nil {
 		return errors.Wrap(err, "failed to run pipeline")
 	}
 
 	return nil
 }
+
+// appConfig returns the current custom configuration, which may be updated at any time by the
+// Configuration Provider.
+func (app *CameraManagementApp) appConfig() CustomConfig {
+	app.configMutex.RLock()
+	defer app.configMutex.RUnlock()
+	return app.config.AppCustom
+}
+
+// startDefaultPipelines starts the default pipeline of every camera which does not have a pipeline running.
+func (app *CameraManagementApp) startDefaultPipelines() {
+	devices, err := app.getAllDevices()
+	if err != nil {
+		app.lc.Errorf("no devices found: %s", err.Error())
+		return
+	}
+	for _, device := range devices {
+		if err = app.startDefaultPipeline(device); err != nil {
+			app.lc.Errorf("Error starting default pipeline for %s, %v", device.Name, err)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const defaultCatalogRefreshInterval = 5 * time.Minute
+
+// PipelineParameter is a parameter of a pipeline, as described by its JSON schema
+type PipelineParameter struct {
+	Type        string        `json:"type,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Default     interface{}   `json:"default,omitempty"`
+	Enum        []interface{} `json:"enum,omitempty"`
+	Minimum     *float64      `json:"minimum,omitempty"`
+	Maximum     *float64      `json:"maximum,omitempty"`
+}
+
+// CatalogPipeline is a pipeline template which can be started for a camera
+type CatalogPipeline struct {
+	Name        string                       `json:"name"`
+	Version     string                       `json:"version"`
+	Type        string                       `json:"type"`
+	Description string                       `json:"description"`
+	Parameters  map[string]PipelineParameter `json:"parameters,omitempty"`
+}
+
+// CatalogModel is a model loaded by EVAM
+type CatalogModel struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	// Precisions are the precisions the model is available in, such as FP16 or FP32
+	Precisions []string `json:"precisions"`
+	// Devices are the inference devices supporting at least one of the precisions
+	Devices []string `json:"devices"`
+}
+
+// PipelineCatalog lists the pipelines and models available in EVAM
+type PipelineCatalog struct {
+	Pipelines []CatalogPipeline `json:"pipelines"`
+	Models    []CatalogModel    `json:"models"`
+	// UpdatedAt is the time the catalog was read from EVAM
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// evamPipeline is a pipeline as returned by EVAM
+type evamPipeline struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Parameters  struct {
+		Properties map[string]PipelineParameter `json:"properties"`
+	} `json:"parameters"`
+}
+
+// evamModel is a model as returned by EVAM, whose networks are keyed by precision
+type evamModel struct {
+	Name        string                 `json:"name"`
+	Version     string                 `json:"version"`
+	Type        string                 `json:"type"`
+	Description string                 `json:"description"`
+	Networks    map[string]interface{} `json:"networks"`
+}
+
+// errUnknownPipeline is returned when starting a pipeline which is not in the catalog
+var errUnknownPipeline = errors.New("unknown pipeline")
+
+// catalogCache holds the last catalog read from EVAM
+type catalogCache struct {
+	mutex   sync.RWMutex
+	catalog PipelineCatalog
+	// loaded is false until the catalog is read, and when EVAM changes
+	loaded bool
+}
+
+// parseNetworkPreference returns the inference devices using each precision. The preference maps the devices to
+// the comma separated precisions of the networks they load, as the NETWORK_PREFERENCE of EVAM.
+func parseNetworkPreference(preference map[string]string) (map[string][]string, error) {
+	precisionDevices := make(map[string][]string)
+	for device, precisions := range preference {
+		for _, precision := range strings.Split(precisions, ",") {
+			precision = strings.TrimSpace(precision)
+			if precision == "" {
+				return nil, errors.Errorf("invalid Catalog NetworkPreference '%s' of device %s", precisions, device)
+			}
+			precisionDevices[precision] = append(precisionDevices[precision], device)
+		}
+	}
+	return precisionDevices, nil
+}
+
+func parseCatalogRefreshInterval(interval string) (time.Duration, error) {
+	if interval == "" {
+		return defaultCatalogRefreshInterval, nil
+	}
+	parsed, err := time.ParseDuration(interval)
+	if err != nil || parsed < 0 {
+		return 0, errors.Errorf("invalid Catalog RefreshInterval '%s'", interval)
+	}
+	return parsed, nil
+}
+
+// invalidate makes the next read of the catalog query EVAM.
+func (c *catalogCache) invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.loaded = false
+}
+
+func (c *catalogCache) get() (PipelineCatalog, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.catalog, c.loaded
+}
+
+// fetchCatalog reads the pipelines and models from EVAM. The devices of the models are the devices loading
+// the precisions of their networks.
+func (app *CameraManagementApp) fetchCatalog(ctx context.Context) (PipelineCatalog, error) {
+	baseUrl := app.appConfig().EvamBaseUrl
+	// the configuration was validated
+	precisionDevices, _ := parseNetworkPreference(app.appConfig().Catalog.NetworkPreference)
+
+	var pipelines []evamPipeline
+	if err := issueGetRequest(ctx, &pipelines, baseUrl, "/pipelines"); err != nil {
+		return PipelineCatalog{}, errors.Wrap(err, "GET request to query all EVAM pipelines failed")
+	}
+	var models []evamModel
+	if err := issueGetRequest(ctx, &models, baseUrl, "/models"); err != nil {
+		return PipelineCatalog{}, errors.Wrap(err, "GET request to query all EVAM models failed")
+	}
+
+	catalog := PipelineCatalog{
+		Pipelines: make([]CatalogPipeline, 0, len(pipelines)),
+		Models:    make([]CatalogModel, 0, len(models)),
+		UpdatedAt: time.Now(),
+	}
+	for _, p := range pipelines {
+		catalog.Pipelines = append(catalog.Pipelines, CatalogPipeline{
+			Name:        p.Name,
+			Version:     p.Version,
+			Type:        p.Type,
+			Description: p.Description,
+			Parameters:  p.Parameters.Properties,
+		})
+	}
+	sort.Slice(catalog.Pipelines, func(i, j int) bool {
+		a, b := catalog.Pipelines[i], catalog.Pipelines[j]
+		return a.Name < b.Name || (a.Name == b.Name && a.Version < b.Version)
+	})
+
+	for _, m := range models {
+		model := CatalogModel{
+			Name:        m.Name,
+			Version:     m.Version,
+			Type:        m.Type,
+			Description: m.Description,
+			Precisions:  make([]string, 0, len(m.Networks)),
+			Devices:     make([]string, 0),
+		}
+		devices := make(map[string]bool)
+		for precision := range m.Networks {
+			model.Precisions = append(model.Precisions, precision)
+			for _, device := range precisionDevices[precision] {
+				devices[device] = true
+			}
+		}
+		for device := range devices {
+			model.Devices = append(model.Devices, device)
+		}
+		sort.Strings(model.Precisions)
+		sort.Strings(model.Devices)
+		catalog.Models = append(catalog.Models, model)
+	}
+	sort.Slice(catalog.Models, func(i, j int) bool {
+		a, b := catalog.Models[i], catalog.Models[j]
+		return a.Name < b.Name || (a.Name == b.Name && a.Version < b.Version)
+	})
+
+	return catalog, nil
+}
+
+// refreshCatalog reads the catalog from EVAM and caches it.
+func (app *CameraManagementApp) refreshCatalog(ctx context.Context) (PipelineCatalog, error) {
+	catalog, err := app.fetchCatalog(ctx)
+	if err != nil {
+		return PipelineCatalog{}, err
+	}
+	app.catalog.mutex.Lock()
+	app.catalog.catalog = catalog
+	app.catalog.loaded = true
+	app.catalog.mutex.Unlock()
+	return catalog, nil
+}
+
+// getCatalog returns the cached catalog, which is read from EVAM if it is not loaded yet or refresh is true.
+func (app *CameraManagementApp) getCatalog(ctx context.Context, refresh bool) (PipelineCatalog, error) {
+	if catalog, loaded := app.catalog.get(); loaded && !refresh {
+		return catalog, nil
+	}
+	return app.refreshCatalog(ctx)
+}
+
+// runCatalogRefresh refreshes the catalog in the background, at the Catalog RefreshInterval.
+func (app *CameraManagementApp) runCatalogRefresh(ctx context.Context, wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		refresh := true
+		for {
+			if refresh {
+				if _, err := app.refreshCatalog(ctx); err != nil && ctx.Err() == nil {
+					app.lc.Warnf("Failed to refresh the pipeline catalog: %v", err)
+				}
+			}
+
+			// the interval is read every time, as it may be updated by the Configuration Provider.
+			// It was validated, and 0 disables the periodic refresh until it is updated.
+			interval, _ := parseCatalogRefreshInterval(app.appConfig().Catalog.RefreshInterval)
+			refresh = interval > 0
+			if !refresh {
+				interval = defaultCatalogRefreshInterval
+			}
+			timer := time.NewTimer(interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}()
+}
+
+// validatePipelineTemplate checks that the pipeline is in the catalog. The pipeline is not rejected when
+// the catalog cannot be read, as starting it then reports why EVAM is not available.
+func (app *CameraManagementApp) validatePipelineTemplate(ctx context.Context, name string, version string) error {
+	catalog, err := app.getCatalog(ctx, false)
+	if err != nil {
+		app.lc.Warnf("Unable to validate pipeline %s/%s: %v", name, version, err)
+		return nil
+	}
+	if catalog.hasPipeline(name, version) {
+		return nil
+	}
+	// the pipeline may have been added to EVAM since the catalog was read
+	if catalog, err = app.getCatalog(ctx, true); err != nil || catalog.hasPipeline(name, version) {
+		return nil
+	}
+	return errors.Wrapf(errUnknownPipeline, "%s/%s", name, version)
+}
+
+func (c PipelineCatalog) hasPipeline(name string, version string) bool {
+	_, found := c.pipeline(name, version)
+	return found
+}
+
+func (c PipelineCatalog) pipeline(name string, version string) (CatalogPipeline, bool) {
+	for _, p := range c.Pipelines {
+		if p.Name == name && p.Version == version {
+			return p, true
+		}
+	}
+	return CatalogPipeline{}, false
+}
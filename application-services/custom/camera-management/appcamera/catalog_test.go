@@ -0,0 +1,59 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNetworkPreference(t *testing.T) {
+	precisionDevices, err := parseNetworkPreference(map[string]string{"CPU": "FP32, INT8", "MYRIAD": "FP16"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"FP32": {"CPU"}, "INT8": {"CPU"}, "FP16": {"MYRIAD"}}, precisionDevices)
+
+	_, err = parseNetworkPreference(map[string]string{"CPU": "FP32,"})
+	require.Error(t, err)
+}
+
+func TestFetchCatalog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/pipelines":
+			_, _ = w.Write([]byte(`[{"name": "object_detection", "version": "person", "type": "GStreamer",
+				"parameters": {"properties": {"threshold": {"type": "number"}}}}]`))
+		case "/models":
+			_, _ = w.Write([]byte(`[{"name": "object_detection", "version": "person", "type": "IntelDLDTModel",
+				"networks": {"FP32": {"network": "FP32/person.xml"}, "FP16": {"network": "FP16/person.xml"}}},
+				{"name": "emotion_recognition", "version": "1", "networks": {"FP16-INT8": {}}}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	app := newTestApp(CustomConfig{EvamBaseUrl: server.URL, Catalog: CatalogConfig{
+		NetworkPreference: map[string]string{"CPU": "FP32", "GPU": "FP32,FP16", "MYRIAD": "FP16"},
+	}})
+
+	catalog, err := app.fetchCatalog(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, catalog.Pipelines, 1)
+	assert.Contains(t, catalog.Pipelines[0].Parameters, "threshold")
+	require.Len(t, catalog.Models, 2)
+	assert.Equal(t, "emotion_recognition", catalog.Models[0].Name)
+	assert.Equal(t, []string{"FP16-INT8"}, catalog.Models[0].Precisions)
+	assert.Empty(t, catalog.Models[0].Devices, "no device loads the precision")
+	assert.Equal(t, []string{"FP16", "FP32"}, catalog.Models[1].Precisions)
+	assert.Equal(t, []string{"CPU", "GPU", "MYRIAD"}, catalog.Models[1].Devices)
+}
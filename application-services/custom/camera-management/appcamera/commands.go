@@ -8,6 +8,7 @@ package appcamera
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/IOTechSystems/onvif/device"
 	"github.com/IOTechSystems/onvif/media"
@@ -68,36 +69,18 @@ func (app *CameraManagementApp) doPTZ(deviceName, profileToken string, x, y, zoo
 }
 
 func (app *CameraManagementApp) getCameraFeatures(deviceName string) (CameraFeatures, error) {
-	var err error
-	features := CameraFeatures{}
 	dev, err := app.getDeviceByName(deviceName)
 	if err != nil {
 		return CameraFeatures{}, err
 	}
 
-	switch dev.ServiceName {
-	case app.config.AppCustom.OnvifDeviceServiceName:
-		features.CameraType = Onvif
-		caps, err := app.getCapabilities(deviceName)
-		if err != nil {
-			return CameraFeatures{}, errors.Wrapf(err, "unable to get device capabilities")
-		}
-		if caps.Capabilities.PTZ.XAddr != "" {
-			features.PTZ = true
-
-			if ptzConfigs, err := app.getPTZConfiguration(deviceName); err != nil {
-				app.lc.Errorf("Error calling Get PTZ Configuration for device %s: %s", deviceName, err.Error())
-			} else {
-				features.Zoom = ptzConfigs.PTZConfiguration[0].ZoomLimits != nil
-			}
-		}
-	case app.config.AppCustom.USBDeviceServiceName:
-		features.CameraType = USB
-	default:
-		features.CameraType = Unknown
+	adapter, err := app.getCameraAdapter(dev)
+	if err != nil {
+		app.lc.Debugf("unable to determine camera type: %s", err.Error())
+		return CameraFeatures{CameraType: Unknown}, nil
 	}
 
-	return features, nil
+	return adapter.Features(dev)
 }
 
 func (app *CameraManagementApp) getCapabilities(deviceName string) (device.GetCapabilitiesResponse, error) {
@@ -171,31 +154,64 @@ func (app *CameraManagementApp) sendPutCommand(deviceName string, commandName st
 		})
 }
 
+// getAllDevices returns the cameras of the device services of the adapters. When an adapter has no service name,
+// such as the rtsp adapter by default, all the devices are queried and the ones no adapter supports are left out.
 func (app *CameraManagementApp) getAllDevices() ([]dtos.Device, error) {
-	onvifResponse, err1 := app.service.DeviceClient().DevicesByServiceName(context.Background(), app.config.AppCustom.OnvifDeviceServiceName, 0, -1)
-	usbResponse, err2 := app.service.DeviceClient().DevicesByServiceName(context.Background(), app.config.AppCustom.USBDeviceServiceName, 0, -1)
-
-	// if both failed, throw an error
-	if err1 != nil && err2 != nil {
-		return nil, fmt.Errorf("failed to get devices for the device services: %v, %v", err1, err2)
+	for _, adapter := range app.adapters {
+		if adapter.ServiceName() == "" {
+			return app.getAllSupportedDevices()
+		}
 	}
 
 	var devices []dtos.Device
-	if err1 == nil {
-		// if the first one succeeded, just overwrite the slice
-		devices = onvifResponse.Devices
-	}
-	if err2 == nil {
-		// if the second one succeeded, append all items
-		for _, d := range usbResponse.Devices {
-			devices = append(devices, d)
+	var serviceNames []string
+	var errs []error
+
+	queried := make(map[string]bool)
+	for _, adapter := range app.adapters {
+		serviceName := adapter.ServiceName()
+		if queried[serviceName] {
+			continue
 		}
+		queried[serviceName] = true
+		serviceNames = append(serviceNames, serviceName)
+
+		resp, err := app.service.DeviceClient().DevicesByServiceName(context.Background(), serviceName, 0, -1)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		devices = append(devices, resp.Devices...)
+	}
+
+	// if all of them failed, throw an error
+	if len(errs) > 0 && len(errs) == len(serviceNames) {
+		return nil, fmt.Errorf("failed to get devices for the device services: %v", errs)
 	}
 
 	if len(devices) <= 0 {
-		return nil, errors.Errorf("no devices registered yet for the device services %s or %s",
-			app.config.AppCustom.OnvifDeviceServiceName, app.config.AppCustom.USBDeviceServiceName)
+		return nil, errors.Errorf("no devices registered yet for the device services %s",
+			strings.Join(serviceNames, ", "))
 	}
 
 	return devices, nil
 }
+
+// getAllSupportedDevices returns the devices of all the device services which are supported by a camera adapter.
+func (app *CameraManagementApp) getAllSupportedDevices() ([]dtos.Device, error) {
+	resp, err := app.service.DeviceClient().AllDevices(context.Background(), nil, 0, -1)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get devices")
+	}
+
+	var devices []dtos.Device
+	for _, device := range resp.Devices {
+		if _, err := app.getCameraAdapter(device); err == nil {
+			devices = append(devices, device)
+		}
+	}
+	if len(devices) <= 0 {
+		return nil, errors.New("no camera devices registered yet")
+	}
+	return devices, nil
+}
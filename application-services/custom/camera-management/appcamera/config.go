@@ -9,11 +9,106 @@ package appcamera
 type CustomConfig struct {
 	OnvifDeviceServiceName string
 	USBDeviceServiceName   string
+	RTSPDeviceServiceName  string
 	EvamBaseUrl            string
 	MqttAddress            string
 	MqttTopic              string
 	DefaultPipelineName    string
 	DefaultPipelineVersion string
+	PrivacyMasksFile       string
+	Statistics             StatisticsConfig
+	ShutdownPolicy         string
+	ShutdownTimeout        string
+	StreamOutput           StreamOutputConfig
+	Coordination           CoordinationConfig
+	PTZ                    PTZConfig
+	Discovery              DiscoveryConfig
+	Inventory              InventoryConfig
+	MqttDestination        MqttDestinationConfig
+	Catalog                CatalogConfig
+	// RestartPipelinesOnChange restarts the running pipelines when the configuration of their destinations
+	// is updated by the Configuration Provider
+	RestartPipelinesOnChange bool
+}
+
+// MqttDestinationConfig holds the values for the connection of EVAM to the broker receiving the inference events
+type MqttDestinationConfig struct {
+	// SecretName is the secret holding the username and password of the broker
+	SecretName string
+	ClientId   string
+	// CaCert, ClientCert and ClientKey are paths in the EVAM container
+	CaCert     string
+	ClientCert string
+	ClientKey  string
+}
+
+// CatalogConfig holds the values for the catalog of the pipelines and models available in EVAM
+type CatalogConfig struct {
+	// RefreshInterval is the interval at which the catalog is read from EVAM, 0 disables the periodic refresh
+	RefreshInterval string
+	// NetworkPreference maps the inference devices to the comma separated precisions of the networks EVAM loads
+	// for them, and must match the NETWORK_PREFERENCE of EVAM
+	NetworkPreference map[string]string
+}
+
+// InventoryConfig holds the values for the camera inventory
+type InventoryConfig struct {
+	// CacheTTL is how long the cameras read from the device services are cached
+	CacheTTL string
+}
+
+// DiscoveryConfig holds the values for the approval of discovered cameras
+type DiscoveryConfig struct {
+	// PipelineTemplates maps device profile names to the name/version of the pipeline started on approval
+	PipelineTemplates map[string]string
+}
+
+// PTZConfig holds the values for the serialization and rate limiting of the PTZ commands sent to each camera
+type PTZConfig struct {
+	MinInterval         string
+	MaxQueueLength      int
+	MaxCoalescedMoves   int
+	DefaultLockDuration string
+	MaxLockDuration     string
+}
+
+// CoordinationConfig holds the values for the leader election between multiple instances of the service
+type CoordinationConfig struct {
+	Mode          string
+	InstanceId    string
+	LeaseDuration string
+	RenewInterval string
+	LeaseFile     string
+	MqttBrokerUrl string
+	LeaseTopic    string
+	StoreUrl      string
+	LeaseKey      string
+}
+
+// StreamOutputConfig holds the values for the output streams of the pipelines, which are used to build
+// the urls the inference results can be viewed at
+type StreamOutputConfig struct {
+	Protocol  string
+	Host      string
+	RtspPort  int
+	WebRTCUrl string
+	HLSUrl    string
+}
+
+// StatisticsConfig holds the values for the detection statistics aggregation
+type StatisticsConfig struct {
+	Enabled       bool
+	MqttBrokerUrl string
+	// CaCert, ClientCert and ClientKey are paths in the container of this service, enabling TLS with the
+	// MqttBrokerUrl broker independently of the MqttDestination certificates, which are paths in the EVAM container
+	CaCert          string
+	ClientCert      string
+	ClientKey       string
+	BucketSize      string
+	Retention       string
+	PublishInterval string
+	PublishTopic    string
+	StoreFile       string
 }
 
 // ServiceConfig a struct that wraps CustomConfig which holds the values for driver configuration
@@ -0,0 +1,252 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	bootstrapInterfaces "github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap/interfaces"
+	"github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap/secret"
+	"github.com/pkg/errors"
+)
+
+const (
+	// noCoordination runs a single instance, which is always the leader
+	noCoordination = "none"
+	// fileCoordination uses a lease file, which must be shared by all the instances
+	fileCoordination = "file"
+	// messageBusCoordination uses lease heartbeats exchanged over the MQTT message bus
+	messageBusCoordination = "messagebus"
+	// storeCoordination locks a key of the Consul store used as the EdgeX Configuration Provider
+	storeCoordination = "store"
+
+	defaultLeaseDuration = 15 * time.Second
+	defaultRenewInterval = 5 * time.Second
+)
+
+// Lease is a time limited lock shared by all the instances of the service. The instance holding the
+// lease is the leader, which is the only one starting pipelines and running the schedulers.
+type Lease interface {
+	// Acquire acquires or renews the lease for the holder until it expires after the specified duration,
+	// unless it is held by another instance. It returns the current holder of the lease, which is
+	// empty if unknown.
+	Acquire(ctx context.Context, holder string, duration time.Duration) (string, error)
+	// Release gives up the lease if it is held by the holder.
+	Release(holder string) error
+}
+
+// coordinationConfig holds the parsed values of the CoordinationConfig
+type coordinationConfig struct {
+	instanceId    string
+	leaseDuration time.Duration
+	renewInterval time.Duration
+}
+
+func parseCoordinationConfig(cfg CoordinationConfig) (coordinationConfig, error) {
+	parsed := coordinationConfig{
+		instanceId:    cfg.InstanceId,
+		leaseDuration: defaultLeaseDuration,
+		renewInterval: defaultRenewInterval,
+	}
+	switch cfg.Mode {
+	case "", noCoordination, fileCoordination, messageBusCoordination, storeCoordination:
+	default:
+		return parsed, errors.Errorf("invalid Coordination Mode '%s', must be one of %s, %s, %s or %s",
+			cfg.Mode, noCoordination, fileCoordination, messageBusCoordination, storeCoordination)
+	}
+
+	var err error
+	if cfg.LeaseDuration != "" {
+		if parsed.leaseDuration, err = time.ParseDuration(cfg.LeaseDuration); err != nil || parsed.leaseDuration <= 0 {
+			return parsed, errors.Errorf("invalid Coordination LeaseDuration '%s'", cfg.LeaseDuration)
+		}
+	}
+	if cfg.RenewInterval != "" {
+		if parsed.renewInterval, err = time.ParseDuration(cfg.RenewInterval); err != nil || parsed.renewInterval <= 0 {
+			return parsed, errors.Errorf("invalid Coordination RenewInterval '%s'", cfg.RenewInterval)
+		}
+	}
+	if parsed.renewInterval >= parsed.leaseDuration {
+		return parsed, errors.Errorf("invalid Coordination RenewInterval '%s', must be less than the LeaseDuration",
+			cfg.RenewInterval)
+	}
+	if cfg.Mode == storeCoordination && parsed.leaseDuration < minStoreLeaseDuration {
+		return parsed, errors.Errorf("invalid Coordination LeaseDuration '%s', must be at least %s when the Mode is %s",
+			cfg.LeaseDuration, minStoreLeaseDuration, storeCoordination)
+	}
+
+	if parsed.instanceId == "" {
+		if parsed.instanceId, err = os.Hostname(); err != nil {
+			return parsed, errors.Wrap(err, "Coordination InstanceId is not set and the hostname is unknown")
+		}
+	}
+	return parsed, nil
+}
+
+// UseLease replaces the lease used for the leader election, for example to share it through a store
+// which is not supported out of the box. This must be called before Run.
+func (app *CameraManagementApp) UseLease(lease Lease) {
+	app.lease = lease
+}
+
+// initCoordination creates the lease of the configured coordination mode. It must be called before the service is run.
+func (app *CameraManagementApp) initCoordination() error {
+	cfg := app.appConfig().Coordination
+	parsed, err := parseCoordinationConfig(cfg)
+	if err != nil {
+		return err
+	}
+	app.instanceId = parsed.instanceId
+
+	if app.lease == nil {
+		switch cfg.Mode {
+		case fileCoordination:
+			app.lease = newFileLease(cfg.LeaseFile)
+		case messageBusCoordination:
+			app.lease = newMessageBusLease(app.lc, cfg.MqttBrokerUrl, cfg.LeaseTopic, parsed.instanceId)
+		case storeCoordination:
+			if app.lease, err = newStoreLease(cfg.StoreUrl, cfg.LeaseKey, app.storeToken); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+
+	app.elector = &leaderElector{
+		app:           app,
+		lease:         app.lease,
+		instanceId:    parsed.instanceId,
+		leaseDuration: parsed.leaseDuration,
+		renewInterval: parsed.renewInterval,
+	}
+	app.lc.Infof("Leader election enabled for instance %s", parsed.instanceId)
+	return nil
+}
+
+// storeToken returns the ACL token of the Consul store, which is empty when the service is not secured.
+func (app *CameraManagementApp) storeToken() (string, error) {
+	provider, ok := app.service.SecretProvider().(bootstrapInterfaces.SecretProviderExt)
+	if !ok {
+		return "", nil
+	}
+	return provider.GetAccessToken(secret.TokenTypeConsul, ServiceKey)
+}
+
+// isLeader returns true if this instance is allowed to start pipelines and run the schedulers.
+func (app *CameraManagementApp) isLeader() bool {
+	if app.elector == nil {
+		return true
+	}
+	leader, _ := app.elector.status()
+	return leader
+}
+
+// getLeader returns the name of the leader instance, which is empty if unknown.
+func (app *CameraManagementApp) getLeader() string {
+	if app.elector == nil {
+		return app.instanceId
+	}
+	_, holder := app.elector.status()
+	return holder
+}
+
+// leaderElector periodically renews the lease, and takes over the duties of the leader when it is acquired.
+type leaderElector struct {
+	app           *CameraManagementApp
+	lease         Lease
+	instanceId    string
+	leaseDuration time.Duration
+	renewInterval time.Duration
+
+	mutex sync.RWMutex
+	// leader is true while this instance holds the lease
+	leader bool
+	// holder is the last known holder of the lease
+	holder string
+	// renewed is when the lease was last successfully renewed by this instance
+	renewed time.Time
+}
+
+func (e *leaderElector) status() (bool, string) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return e.leader, e.holder
+}
+
+// run renews the lease until the context is done, at which point the lease is released.
+func (e *leaderElector) run(ctx context.Context, wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(e.renewInterval)
+		defer ticker.Stop()
+
+		e.renew(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				if leader, _ := e.status(); leader {
+					if err := e.lease.Release(e.instanceId); err != nil {
+						e.app.lc.Errorf("Failed to release the leader lease: %v", err)
+					}
+				}
+				return
+			case <-ticker.C:
+				e.renew(ctx)
+			}
+		}
+	}()
+}
+
+func (e *leaderElector) renew(ctx context.Context) {
+	now := time.Now()
+	holder, err := e.lease.Acquire(ctx, e.instanceId, e.leaseDuration)
+
+	e.mutex.Lock()
+	wasLeader := e.leader
+	if err != nil {
+		e.app.lc.Errorf("Failed to renew the leader lease: %v", err)
+		// keep leading until the lease would have expired, as no other instance can take it over before that
+		if e.leader && now.Sub(e.renewed) >= e.leaseDuration {
+			e.leader = false
+			e.holder = ""
+		}
+	} else {
+		e.leader = holder == e.instanceId
+		e.holder = holder
+		if e.leader {
+			e.renewed = now
+		}
+	}
+	isLeader := e.leader
+	e.mutex.Unlock()
+
+	switch {
+	case isLeader && !wasLeader:
+		e.app.lc.Infof("Instance %s is now the leader", e.instanceId)
+		e.app.onElected()
+	case !isLeader && wasLeader:
+		e.app.lc.Warnf("Instance %s is no longer the leader", e.instanceId)
+	case !isLeader:
+		// keep the pipelines up to date, so that the read-only routes can be served by any instance
+		if err = e.app.queryAllPipelineStatuses(); err != nil {
+			e.app.lc.Errorf("Unable to query EVAM pipeline statuses: %v", err)
+		}
+	}
+}
+
+// onElected takes over the pipelines started by the previous leader, and starts any missing default pipeline.
+func (app *CameraManagementApp) onElected() {
+	if err := app.queryAllPipelineStatuses(); err != nil {
+		app.lc.Errorf("Unable to query EVAM pipeline statuses. Is EVAM running? %s", err.Error())
+	}
+	app.startDefaultPipelines()
+}
@@ -12,8 +12,9 @@ import (
 )
 
 const (
-	rtspAuth  = "rtspAuth"
-	onvifAuth = "onvifAuth"
+	rtspAuth       = "rtspAuth"
+	onvifAuth      = "onvifAuth"
+	rtspCameraAuth = "rtspCameraAuth"
 )
 
 // tryGetCredentials will attempt one time to get the camera credentials from the
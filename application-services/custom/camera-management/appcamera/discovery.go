@@ -0,0 +1,227 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	bootstrapInterfaces "github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap/interfaces"
+	"github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap/secret"
+	clientInterfaces "github.com/edgexfoundry/go-mod-core-contracts/v3/clients/interfaces"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos/requests"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/models"
+	"github.com/pkg/errors"
+)
+
+// deviceServiceDiscoveryPath is the route of the device services starting a discovery
+const deviceServiceDiscoveryPath = common.ApiBase + "/discovery"
+
+// DiscoveryResponse lists the device services discovery was triggered on
+type DiscoveryResponse struct {
+	Services []string `json:"services"`
+}
+
+// ApproveDeviceRequest is the body of the request approving a provisional device. All the fields are optional.
+type ApproveDeviceRequest struct {
+	// ProfileName defaults to the current profile of the device
+	ProfileName string   `json:"profileName,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	// PipelineName and PipelineVersion default to the pipeline template of the device profile, or the default pipeline
+	PipelineName    string `json:"pipelineName,omitempty"`
+	PipelineVersion string `json:"pipelineVersion,omitempty"`
+}
+
+// ApproveDeviceResponse holds the approved device and the pipeline started for it, if any
+type ApproveDeviceResponse struct {
+	Device   dtos.Device   `json:"device"`
+	Pipeline *PipelineInfo `json:"pipeline,omitempty"`
+	// PipelineError is set when the device was approved, but the pipeline failed to start
+	PipelineError string `json:"pipelineError,omitempty"`
+}
+
+// discoveryServiceNames returns the names of the configured device services supporting discovery.
+func (app *CameraManagementApp) discoveryServiceNames() []string {
+	var names []string
+	cfg := app.appConfig()
+	for _, name := range []string{cfg.OnvifDeviceServiceName, cfg.USBDeviceServiceName} {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// isDiscoveryService returns true if the service is one of the configured device services supporting discovery.
+func (app *CameraManagementApp) isDiscoveryService(serviceName string) bool {
+	for _, name := range app.discoveryServiceNames() {
+		if name == serviceName {
+			return true
+		}
+	}
+	return false
+}
+
+// triggerDiscovery asks the device service to discover new devices. Discovery runs in the background,
+// and discovered devices are added by the provision watchers of the device service.
+func (app *CameraManagementApp) triggerDiscovery(ctx context.Context, serviceName string) error {
+	resp, edgexErr := app.service.DeviceServiceClient().DeviceServiceByName(ctx, serviceName)
+	if edgexErr != nil {
+		return errors.Wrapf(edgexErr, "failed to get device service %s", serviceName)
+	}
+
+	// the device service clients of the SDK do not support discovery, so the request is sent the same way,
+	// authenticated in secure mode and limited to the request timeout of the service
+	reqUrl := strings.TrimSuffix(resp.Service.BaseAddress, "/") + deviceServiceDiscoveryPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqUrl, nil)
+	if err != nil {
+		return err
+	}
+	if err = app.authInjector().AddAuthenticationData(req); err != nil {
+		return errors.Wrap(err, "failed to authenticate the discovery request")
+	}
+	client := &http.Client{Timeout: app.service.RequestTimeout()}
+	res, err := client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to trigger discovery on device service %s", serviceName)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(res.Body)
+		return errors.Errorf("device service %s failed to start discovery: %d %s",
+			serviceName, res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// authInjector authenticates the requests sent to the other EdgeX services, which does nothing when not secured.
+func (app *CameraManagementApp) authInjector() clientInterfaces.AuthenticationInjector {
+	provider, _ := app.service.SecretProvider().(bootstrapInterfaces.SecretProviderExt)
+	return secret.NewJWTSecretProvider(provider)
+}
+
+// getProvisionalDevices returns the devices of the discovery services which are locked, waiting to be approved.
+func (app *CameraManagementApp) getProvisionalDevices(ctx context.Context) ([]dtos.Device, error) {
+	devices := make([]dtos.Device, 0)
+	for _, serviceName := range app.discoveryServiceNames() {
+		resp, edgexErr := app.service.DeviceClient().DevicesByServiceName(ctx, serviceName, 0, -1)
+		if edgexErr != nil {
+			return nil, errors.Wrapf(edgexErr, "failed to get devices of device service %s", serviceName)
+		}
+		for _, device := range resp.Devices {
+			if device.AdminState == models.Locked {
+				devices = append(devices, device)
+			}
+		}
+	}
+	return devices, nil
+}
+
+// pipelineTemplateFor returns the pipeline name and version to start for a newly approved device.
+func (app *CameraManagementApp) pipelineTemplateFor(device dtos.Device, req ApproveDeviceRequest) (string, string, error) {
+	if req.PipelineName != "" && req.PipelineVersion != "" {
+		return req.PipelineName, req.PipelineVersion, nil
+	}
+
+	cfg := app.appConfig()
+	if template, found := cfg.Discovery.PipelineTemplates[device.ProfileName]; found {
+		name, version, ok := strings.Cut(template, "/")
+		if !ok || name == "" || version == "" {
+			return "", "", errors.Errorf("invalid Discovery PipelineTemplates value '%s' for profile %s, "+
+				"must be name/version", template, device.ProfileName)
+		}
+		return name, version, nil
+	}
+	return cfg.DefaultPipelineName, cfg.DefaultPipelineVersion, nil
+}
+
+var (
+	// errNotProvisional is returned when approving a device which is not waiting to be approved
+	errNotProvisional = errors.New("device is not provisional")
+	// errUnknownProfile is returned when approving a device with a profile which does not exist
+	errUnknownProfile = errors.New("unknown device profile")
+)
+
+// approveDevice unlocks a provisional device with the requested profile and labels, and starts the pipeline
+// template matching its profile.
+func (app *CameraManagementApp) approveDevice(ctx context.Context, deviceName string, req ApproveDeviceRequest) (ApproveDeviceResponse, error) {
+	resp, edgexErr := app.service.DeviceClient().DeviceByName(ctx, deviceName)
+	if edgexErr != nil {
+		return ApproveDeviceResponse{}, errors.Wrapf(edgexErr, "failed to get device %s", deviceName)
+	}
+	device := resp.Device
+	if !app.isDiscoveryService(device.ServiceName) || device.AdminState != models.Locked {
+		return ApproveDeviceResponse{}, errors.Wrapf(errNotProvisional, "device %s", deviceName)
+	}
+
+	if req.ProfileName != "" && req.ProfileName != device.ProfileName {
+		if _, edgexErr = app.service.DeviceProfileClient().DeviceProfileByName(ctx, req.ProfileName); edgexErr != nil {
+			if edgexErr.Code() == http.StatusNotFound {
+				return ApproveDeviceResponse{}, errors.Wrapf(errUnknownProfile, "%s", req.ProfileName)
+			}
+			return ApproveDeviceResponse{}, errors.Wrapf(edgexErr, "failed to get device profile %s", req.ProfileName)
+		}
+		device.ProfileName = req.ProfileName
+	}
+	name, version, err := app.pipelineTemplateFor(device, req)
+	if err != nil {
+		return ApproveDeviceResponse{}, err
+	}
+
+	device.AdminState = models.Unlocked
+	update := dtos.UpdateDevice{
+		Name:        &device.Name,
+		AdminState:  &device.AdminState,
+		ProfileName: &device.ProfileName,
+	}
+	if req.Labels != nil {
+		device.Labels = req.Labels
+		update.Labels = req.Labels
+	}
+	if _, edgexErr = app.service.DeviceClient().Update(ctx, []requests.UpdateDeviceRequest{
+		requests.NewUpdateDeviceRequest(update)}); edgexErr != nil {
+		return ApproveDeviceResponse{}, errors.Wrapf(edgexErr, "failed to update device %s", deviceName)
+	}
+	app.lc.Infof("Approved device %s with profile %s", device.Name, device.ProfileName)
+
+	res := ApproveDeviceResponse{Device: device}
+	if name == "" || version == "" {
+		app.lc.Warnf("no pipeline template specified, skip starting pipeline for device %s", device.Name)
+		return res, nil
+	}
+	if app.isPipelineRunning(device.Name) {
+		app.lc.Debugf("pipeline is already running for device %s", device.Name)
+	} else if err = app.startPipelineTemplate(device, name, version); err != nil {
+		// the device stays approved, the pipeline can be started again from the pipeline routes
+		res.PipelineError = err.Error()
+		return res, nil
+	}
+	if info, found := app.getPipelineInfo(device.Name); found {
+		res.Pipeline = &info
+	}
+	return res, nil
+}
+
+// discoveryServicesFor returns the discovery services matching the optional service query parameter.
+func (app *CameraManagementApp) discoveryServicesFor(service string) ([]string, error) {
+	names := app.discoveryServiceNames()
+	if service == "" {
+		if len(names) == 0 {
+			return nil, errors.New("no device service supporting discovery is configured")
+		}
+		return names, nil
+	}
+	if !app.isDiscoveryService(service) {
+		return nil, errors.Errorf("device service %s does not support discovery, must be one of %s",
+			service, strings.Join(names, ", "))
+	}
+	return []string{service}, nil
+}
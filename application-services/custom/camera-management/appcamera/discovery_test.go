@@ -0,0 +1,118 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sdkMocks "github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces/mocks"
+	bootstrapMocks "github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap/interfaces/mocks"
+	clientMocks "github.com/edgexfoundry/go-mod-core-contracts/v3/clients/interfaces/mocks"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos/responses"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newDiscoveryTestApp(t *testing.T, baseAddress string, timeout time.Duration) *CameraManagementApp {
+	dsClient := &clientMocks.DeviceServiceClient{}
+	dsClient.On("DeviceServiceByName", mock.Anything, "device-onvif-camera").Return(
+		responses.DeviceServiceResponse{Service: dtos.DeviceService{Name: "device-onvif-camera", BaseAddress: baseAddress}}, nil)
+
+	service := &sdkMocks.ApplicationService{}
+	service.On("DeviceServiceClient").Return(dsClient)
+	service.On("RequestTimeout").Return(timeout)
+	service.On("SecretProvider").Return(&bootstrapMocks.SecretProvider{})
+
+	app := newTestApp(CustomConfig{OnvifDeviceServiceName: "device-onvif-camera"})
+	app.service = service
+	return app
+}
+
+func TestTriggerDiscovery(t *testing.T) {
+	requested := make(chan *http.Request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested <- r
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"apiVersion": "v3", "statusCode": 202}`))
+	}))
+	defer server.Close()
+
+	app := newDiscoveryTestApp(t, server.URL, time.Second)
+	require.NoError(t, app.triggerDiscovery(context.Background(), "device-onvif-camera"))
+
+	r := <-requested
+	assert.Equal(t, http.MethodPost, r.Method)
+	assert.Equal(t, common.ApiBase+"/discovery", r.URL.Path)
+}
+
+func TestTriggerDiscoveryError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusLocked)
+		_, _ = w.Write([]byte(`{"apiVersion": "v3", "statusCode": 423, "message": "device service is locked"}`))
+	}))
+	defer server.Close()
+
+	app := newDiscoveryTestApp(t, server.URL, time.Second)
+	err := app.triggerDiscovery(context.Background(), "device-onvif-camera")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "device service is locked")
+}
+
+func TestTriggerDiscoveryTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	app := newDiscoveryTestApp(t, server.URL, 100*time.Millisecond)
+	start := time.Now()
+	require.Error(t, app.triggerDiscovery(context.Background(), "device-onvif-camera"))
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestStartDefaultPipeline(t *testing.T) {
+	config := CustomConfig{DefaultPipelineName: "object_detection", DefaultPipelineVersion: "person"}
+
+	tests := []struct {
+		name        string
+		config      CustomConfig
+		adminState  string
+		running     bool
+		expectStart bool
+	}{
+		{"unlocked", config, models.Unlocked, false, true},
+		{"locked is skipped", config, models.Locked, false, false},
+		{"already running", config, models.Unlocked, true, false},
+		{"no default pipeline", CustomConfig{}, models.Unlocked, false, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := newTestApp(test.config)
+			if test.running {
+				app.pipelinesMap["camera"] = PipelineInfo{Id: "1"}
+			}
+
+			err := app.startDefaultPipeline(dtos.Device{Name: "camera", ServiceName: "unknown", AdminState: test.adminState})
+			if test.expectStart {
+				// the test app has no camera adapter, so starting the pipeline fails once it is attempted
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "no camera adapter found")
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -17,13 +17,12 @@ import (
 	"github.com/IOTechSystems/onvif/media"
 	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
 	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/models"
 	"github.com/pkg/errors"
 )
 
 const (
 	Aborted = "ABORTED"
-
-	evamRtspPort = 8555
 )
 
 // Note: DLStreamer Pipeline Server / EVAM APIs can be viewed here:
@@ -38,6 +37,15 @@ type PipelineInfo struct {
 	// Version is the second part of the pipeline's full name. In the case of 'object_detection/person_vehicle_bike'
 	// the version is 'person_vehicle_bike'
 	Version string `json:"version,omitempty"`
+	// ViewUrls are the urls the inference results can be viewed at
+	ViewUrls ViewUrls `json:"viewUrls"`
+	// request is the request the pipeline was started with, which is unknown for the pipelines found in EVAM
+	request *StartPipelineRequest
+}
+
+// startedByApp returns true if this instance started the pipeline, rather than finding it running in EVAM.
+func (info PipelineInfo) startedByApp() bool {
+	return info.request != nil
 }
 
 type PipelineInfoStatus struct {
@@ -77,15 +85,6 @@ func (app *CameraManagementApp) getPipelineInfo(camera string) (PipelineInfo, bo
 	return val, found
 }
 
-func (app *CameraManagementApp) queryStreamUri(deviceName string, sr StartPipelineRequest) (string, error) {
-	if sr.USB != nil {
-		return app.getUSBStreamUri(deviceName)
-	} else if sr.Onvif != nil {
-		return app.getOnvifStreamUri(deviceName, sr.Onvif.ProfileToken)
-	}
-	return "", errors.New("missing required stream configuration")
-}
-
 func (app *CameraManagementApp) getOnvifStreamUri(deviceName string, profileToken string) (string, error) {
 	req := StreamUriRequest{ProfileToken: profileToken}
 	resp := media.GetStreamUriResponse{}
@@ -105,47 +104,51 @@ func (app *CameraManagementApp) getUSBStreamUri(deviceName string) (string, erro
 }
 
 func (app *CameraManagementApp) startPipeline(deviceName string, sr StartPipelineRequest) error {
-	streamUri, err := app.queryStreamUri(deviceName, sr)
+	if err := app.validatePipelineTemplate(context.Background(), sr.PipelineName, sr.PipelineVersion); err != nil {
+		return err
+	}
+	device, adapter, err := app.getDeviceAdapter(deviceName)
+	if err != nil {
+		return err
+	}
+
+	streamUri, err := adapter.StreamUri(device, sr)
 	if err != nil {
 		return err
 	}
 	app.lc.Infof("Received stream uri for the device %s: %s", deviceName, streamUri)
 
-	// set the secret name to be the onvif one by default
-	secretName := onvifAuth
-	// if device is usb camera, start streaming first
-	if sr.USB != nil {
-		_, err := app.startStreaming(deviceName, *sr.USB)
-		if err != nil {
-			return errors.Wrapf(err, "failed to start streaming usb camera %s", deviceName)
-		}
-		// for usb cameras, use the rtspAuth instead
-		secretName = rtspAuth
+	// some cameras (such as usb cameras) need to be told to start streaming first
+	if err = adapter.StartStreaming(device, sr); err != nil {
+		return err
 	}
+	secretName := adapter.SecretName()
 
-	body, err := app.createPipelineRequestBody(streamUri, deviceName, secretName)
+	// the body holds the credentials of the camera and the broker, so it is never logged
+	body, credentials, err := app.createPipelineRequestBody(streamUri, deviceName, secretName, sr)
 	if err != nil {
 		return errors.Wrapf(err, "failed to create DLStreamer pipeline request body")
 	}
 
 	info := PipelineInfo{
-		Name:    sr.PipelineName,
-		Version: sr.PipelineVersion,
+		Name:     sr.PipelineName,
+		Version:  sr.PipelineVersion,
+		ViewUrls: app.getViewUrls(deviceName),
+		request:  &sr,
 	}
 	var res interface{}
-	baseUrl, err := url.Parse(app.config.AppCustom.EvamBaseUrl)
+	baseUrl, err := url.Parse(app.appConfig().EvamBaseUrl)
 	if err != nil {
 		return err
 	}
 	reqPath := path.Join("/pipelines", info.Name, info.Version)
 
 	if err = issuePostRequest(context.Background(), &res, baseUrl.String(), reqPath, body); err != nil {
-		err = errors.Wrap(err, "POST request to start EVAM pipeline failed")
-		// if we started the streaming on usb camera, we need to stop it
-		if sr.USB != nil {
-			if _, err2 := app.stopStreaming(deviceName); err2 != nil {
-				err = errors.Wrapf(err, "failed to stop streaming usb camera %s", deviceName)
-			}
+		// EVAM may echo the invalid parts of the request in its error responses
+		err = errors.Wrap(redactCredentials(err, credentials), "POST request to start EVAM pipeline failed")
+		// if we started the streaming on the camera, we need to stop it
+		if err2 := adapter.StopStreaming(device); err2 != nil {
+			app.lc.Errorf("failed to stop streaming on device %s after the pipeline failed to start: %s", deviceName, err2.Error())
 		}
 		return err
 	}
@@ -156,7 +159,11 @@ func (app *CameraManagementApp) startPipeline(deviceName string, sr StartPipelin
 	}
 
 	app.lc.Infof("Successfully started EVAM pipeline for the device %s", deviceName)
-	app.lc.Infof("View inference results at 'rtsp://<SYSTEM_IP_ADDRESS>:%d/%s'", evamRtspPort, deviceName)
+	for _, viewUrl := range []string{info.ViewUrls.Rtsp, info.ViewUrls.WebRTC, info.ViewUrls.HLS} {
+		if viewUrl != "" {
+			app.lc.Infof("View inference results at '%s'", viewUrl)
+		}
+	}
 
 	return nil
 }
@@ -164,7 +171,7 @@ func (app *CameraManagementApp) startPipeline(deviceName string, sr StartPipelin
 func (app *CameraManagementApp) stopPipeline(deviceName string, id string) error {
 	var res interface{}
 
-	if err := issueDeleteRequest(context.Background(), &res, app.config.AppCustom.EvamBaseUrl, path.Join("/pipelines", id)); err != nil {
+	if err := issueDeleteRequest(context.Background(), &res, app.appConfig().EvamBaseUrl, path.Join("/pipelines", id)); err != nil {
 		return errors.Wrap(err, "DELETE request to stop EVAM pipeline failed")
 	}
 	app.lc.Infof("Successfully stopped EVAM pipeline for the device %s", deviceName)
@@ -177,16 +184,29 @@ func (app *CameraManagementApp) stopPipeline(deviceName string, id string) error
 	return nil
 }
 
-func (app *CameraManagementApp) createPipelineRequestBody(streamUri string, deviceName string, secretName string) ([]byte, error) {
+// createPipelineRequestBody returns the body of the request starting the pipeline, along with the credentials
+// it holds.
+func (app *CameraManagementApp) createPipelineRequestBody(streamUri string, deviceName string, secretName string,
+	sr StartPipelineRequest) ([]byte, []string, error) {
+
 	uri, err := url.Parse(streamUri)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	metadata, err := app.metadataDestination(deviceName, sr.PipelineName, sr.PipelineVersion)
+	if err != nil {
+		return nil, nil, err
 	}
+	credentials := []string{metadata.Password}
 
-	if creds, err := app.tryGetCredentials(secretName); err != nil {
-		app.lc.Warnf("Error retrieving %s secret from the SecretStore: %s", secretName, err.Error())
-	} else {
-		uri.User = url.UserPassword(creds.Username, creds.Password)
+	if secretName != "" {
+		if creds, err := app.tryGetCredentials(secretName); err != nil {
+			app.lc.Warnf("Error retrieving %s secret from the SecretStore: %s", secretName, err.Error())
+		} else {
+			uri.User = url.UserPassword(creds.Username, creds.Password)
+			credentials = append(credentials, creds.Password)
+		}
 	}
 
 	pipelineData := PipelineRequest{
@@ -195,30 +215,42 @@ func (app *CameraManagementApp) createPipelineRequestBody(streamUri string, devi
 			Type: "uri",
 		},
 		Destination: Destination{
-			Metadata: Metadata{
-				Type:  "mqtt",
-				Host:  app.config.AppCustom.MqttAddress,
-				Topic: app.config.AppCustom.MqttTopic,
-			},
-			Frame: Frame{
-				Type: "rtsp",
-				Path: deviceName,
-			},
+			Metadata: metadata,
+			Frame:    app.streamOutputFrame(deviceName),
+		},
+		Tags: map[string]interface{}{
+			cameraTag: deviceName,
 		},
 	}
 
+	// only pass the privacy masks when the camera has any, as EVAM rejects parameters which
+	// are not defined by the pipeline
+	if masks := app.privacyMasks.get(deviceName); len(masks) > 0 {
+		catalog, err := app.getCatalog(context.Background(), false)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "unable to check that the pipeline supports the privacy masks of %s", deviceName)
+		}
+		pipeline, found := catalog.pipeline(sr.PipelineName, sr.PipelineVersion)
+		if !found {
+			pipeline = CatalogPipeline{Name: sr.PipelineName, Version: sr.PipelineVersion}
+		}
+		if pipelineData.Parameters, err = privacyMaskParameters(pipeline, masks); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	pipeline, err := json.Marshal(pipelineData)
 	if err != nil {
-		return pipeline, err
+		return nil, nil, err
 	}
 
-	return pipeline, nil
+	return pipeline, credentials, nil
 }
 
 func (app *CameraManagementApp) getPipelineStatus(deviceName string) (interface{}, error) {
 	if info, found := app.getPipelineInfo(deviceName); found {
 		var res interface{}
-		if err := issueGetRequest(context.Background(), &res, app.config.AppCustom.EvamBaseUrl, path.Join("/pipelines", "status", info.Id)); err != nil {
+		if err := issueGetRequest(context.Background(), &res, app.appConfig().EvamBaseUrl, path.Join("/pipelines", "status", info.Id)); err != nil {
 			return nil, errors.Wrap(err, "GET request to query EVAM pipeline status failed")
 		}
 		return res, nil
@@ -248,6 +280,14 @@ func (app *CameraManagementApp) processEdgeXDeviceSystemEvent(_ interfaces.AppFu
 		return false, fmt.Errorf("failed to decode device details: %v", err)
 	}
 
+	// every instance serves the inventory, so its cache is invalidated even when not the leader
+	app.inventory.invalidate(device.Name)
+
+	if !app.isLeader() {
+		app.lc.Debugf("Ignoring %s system event of device %s, as this instance is not the leader", systemEvent.Action, device.Name)
+		return false, nil
+	}
+
 	switch systemEvent.Action {
 	case common.SystemEventActionAdd:
 		if err = app.startDefaultPipeline(device); err != nil {
@@ -267,7 +307,15 @@ func (app *CameraManagementApp) processEdgeXDeviceSystemEvent(_ interfaces.AppFu
 	return false, nil
 }
 
+// startDefaultPipeline starts the default pipeline for the device, unless it already has a pipeline or is locked.
+// Locked devices are either provisional cameras waiting to be approved, whose pipeline is started on approval,
+// or devices disabled by an administrator, whose pipeline can be started from the pipeline routes.
 func (app *CameraManagementApp) startDefaultPipeline(device dtos.Device) error {
+	if device.AdminState == models.Locked {
+		app.lc.Infof("device %s is locked, skip starting its default pipeline", device.Name)
+		return nil
+	}
+
 	pipelineRunning := app.isPipelineRunning(device.Name)
 
 	if pipelineRunning {
@@ -277,35 +325,34 @@ func (app *CameraManagementApp) startDefaultPipeline(device dtos.Device) error {
 
 	app.lc.Debugf("pipeline is not running for device %s", device.Name)
 
-	if app.config.AppCustom.DefaultPipelineName == "" || app.config.AppCustom.DefaultPipelineVersion == "" {
+	cfg := app.appConfig()
+	if cfg.DefaultPipelineName == "" || cfg.DefaultPipelineVersion == "" {
 		app.lc.Warnf("no default pipeline name/version specified, skip starting pipeline for device %s", device.Name)
 		return nil
 	}
 
+	app.lc.Debugf("Starting default pipeline for device %s", device.Name)
+	return app.startPipelineTemplate(device, cfg.DefaultPipelineName, cfg.DefaultPipelineVersion)
+}
+
+// startPipelineTemplate starts the EVAM pipeline name/version for the device, with the default stream
+// configuration of its camera type.
+func (app *CameraManagementApp) startPipelineTemplate(device dtos.Device, name string, version string) error {
 	startPipelineRequest := StartPipelineRequest{
-		PipelineName:    app.config.AppCustom.DefaultPipelineName,
-		PipelineVersion: app.config.AppCustom.DefaultPipelineVersion,
+		PipelineName:    name,
+		PipelineVersion: version,
 	}
 
-	protocol, ok := device.Protocols["Onvif"]
-	if ok {
-		app.lc.Debugf("Onvif protocol information found for device: %s message: %v", device.Name, protocol)
-		profileResponse, err := app.getProfiles(device.Name)
-		if err != nil {
-			return fmt.Errorf("failed to get profiles for device %s, message: %v", device.Name, err)
-
-		}
+	adapter, err := app.getCameraAdapter(device)
+	if err != nil {
+		return err
+	}
 
-		app.lc.Debugf("Onvif profile information found for device: %s message: %v", device.Name, profileResponse)
-		startPipelineRequest.Onvif = &OnvifPipelineConfig{
-			ProfileToken: string(profileResponse.Profiles[0].Token),
-		}
-	} else if _, ok := device.Protocols["USB"]; ok {
-		app.lc.Debugf("Usb protocol found for device: %s", device.Name)
-		startPipelineRequest.USB = &USBStartStreamingRequest{}
+	app.lc.Debugf("%s camera found for device: %s", adapter.Type(), device.Name)
+	if err = adapter.DefaultStreamConfig(device, &startPipelineRequest); err != nil {
+		return err
 	}
 
-	app.lc.Debugf("Starting default pipeline for device %s", device.Name)
 	if err := app.startPipeline(device.Name, startPipelineRequest); err != nil {
 		return fmt.Errorf("pipeline failed to start for device %s, message: %v", device.Name, err)
 
@@ -315,26 +362,32 @@ func (app *CameraManagementApp) startDefaultPipeline(device dtos.Device) error {
 }
 
 // queryAllPipelineStatuses queries EVAM for all pipeline statuses, attempts to link them to devices, and then
-// insert them into the pipeline map.
+// replaces the pipeline map with them.
 func (app *CameraManagementApp) queryAllPipelineStatuses() error {
 	var statuses []PipelineStatus
-	if err := issueGetRequest(context.Background(), &statuses, app.config.AppCustom.EvamBaseUrl, path.Join("/pipelines", "status")); err != nil {
+	if err := issueGetRequest(context.Background(), &statuses, app.appConfig().EvamBaseUrl, path.Join("/pipelines", "status")); err != nil {
 		return errors.Wrap(err, "GET request to query EVAM pipeline statuses failed")
 	}
 
+	pipelines := make(map[string]PipelineInfo)
+
 	for _, status := range statuses {
 		if status.State == Aborted {
 			continue // ignore stopped pipelines
 		}
 
 		var resp PipelineInformationResponse
-		if err := issueGetRequest(context.Background(), &resp, app.config.AppCustom.EvamBaseUrl, path.Join("/pipelines", status.Id)); err != nil {
+		if err := issueGetRequest(context.Background(), &resp, app.appConfig().EvamBaseUrl, path.Join("/pipelines", status.Id)); err != nil {
 			app.lc.Errorf("GET request to query EVAM pipeline %s info failed: %s", status.Id, err.Error())
 			continue
 		}
 
-		// assume the destination streaming path is the camera name, because that is how it is when we created the pipeline instance
+		// assume the destination streaming path (or webrtc peer id) is the camera name, because that is how it is
+		// when we created the pipeline instance
 		deviceName := resp.Request.Destination.Frame.Path
+		if deviceName == "" {
+			deviceName = resp.Request.Destination.Frame.PeerId
+		}
 		// ensure the device actually exists
 		if _, err := app.getDeviceByName(deviceName); err != nil {
 			app.lc.Warnf("Unable to determine device name from EVAM pipeline %s: %s", status.Id, err.Error())
@@ -342,19 +395,46 @@ func (app *CameraManagementApp) queryAllPipelineStatuses() error {
 		}
 
 		info := PipelineInfo{
-			Id:      resp.Id,
-			Name:    resp.Request.Pipeline.Name,
-			Version: resp.Request.Pipeline.Version,
+			Id:       resp.Id,
+			Name:     resp.Request.Pipeline.Name,
+			Version:  resp.Request.Pipeline.Version,
+			ViewUrls: app.getViewUrls(deviceName),
 		}
-		app.deletePipelineInfo(deviceName) // delete the info in case it already exists
-		// add pipeline info to map to ensure we track it
-		if err := app.addPipelineInfo(deviceName, info); err != nil {
-			app.lc.Errorf("Error adding pipeline info to map: %s", err.Error())
+		pipelines[deviceName] = info
+	}
+
+	// replace the map, so that it also forgets the pipelines stopped by other instances, but remember
+	// the requests of the pipelines this instance started
+	app.pipelinesMutex.Lock()
+	for camera, info := range pipelines {
+		if known, found := app.pipelinesMap[camera]; found && known.Id == info.Id {
+			info.request = known.request
+			pipelines[camera] = info
 		}
 	}
+	app.pipelinesMap = pipelines
+	app.pipelinesMutex.Unlock()
 	return nil
 }
 
+// getPipelineInfoStatus returns the pipeline info of the specified camera, including its view urls, along with
+// the pipeline status. found is false if no pipeline is running for the camera.
+func (app *CameraManagementApp) getPipelineInfoStatus(deviceName string) (res PipelineInfoStatus, found bool, err error) {
+	info, found := app.getPipelineInfo(deviceName)
+	if !found {
+		return PipelineInfoStatus{}, false, nil
+	}
+
+	res = PipelineInfoStatus{
+		Camera: deviceName,
+		Info:   info,
+	}
+	if err = issueGetRequest(context.Background(), &res.Status, app.appConfig().EvamBaseUrl, path.Join("/pipelines", "status", info.Id)); err != nil {
+		return PipelineInfoStatus{}, true, errors.Wrap(err, "GET request to query EVAM pipeline status failed")
+	}
+	return res, true, nil
+}
+
 func (app *CameraManagementApp) getAllPipelineStatuses() (map[string]PipelineInfoStatus, error) {
 	response := make(map[string]PipelineInfoStatus)
 	// pre-create the response object using a read lock to minimize the time we hold the lock
@@ -369,7 +449,7 @@ func (app *CameraManagementApp) getAllPipelineStatuses() (map[string]PipelineInf
 
 	// loop through the partially filled response map to fill in the missing data. we do not need to hold the lock here.
 	for camera, data := range response {
-		if err := issueGetRequest(context.Background(), &data.Status, app.config.AppCustom.EvamBaseUrl, path.Join("/pipelines", "status", data.Info.Id)); err != nil {
+		if err := issueGetRequest(context.Background(), &data.Status, app.appConfig().EvamBaseUrl, path.Join("/pipelines", "status", data.Info.Id)); err != nil {
 			return nil, errors.Wrap(err, "GET request to query EVAM pipeline failed")
 		}
 		// overwrite the changed result in the map
@@ -378,11 +458,3 @@ func (app *CameraManagementApp) getAllPipelineStatuses() (map[string]PipelineInf
 
 	return response, nil
 }
-
-func (app *CameraManagementApp) getPipelines() (interface{}, error) {
-	var res interface{}
-	if err := issueGetRequest(context.Background(), &res, app.config.AppCustom.EvamBaseUrl, "/pipelines"); err != nil {
-		return nil, errors.Wrap(err, "GET request to query all EVAM pipelines failed")
-	}
-	return res, nil
-}
@@ -0,0 +1,82 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+const minPolygonPoints = 3
+
+// Point is a position within a camera frame. The coordinates are relative to the frame size,
+// ranging from 0.0 (left/top) to 1.0 (right/bottom), so that they are independent of the stream resolution.
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+func (p Point) validate() error {
+	if p.X < 0 || p.X > 1 || p.Y < 0 || p.Y > 1 {
+		return errors.Errorf("point (%v, %v) is out of range, coordinates must be between 0.0 and 1.0", p.X, p.Y)
+	}
+	return nil
+}
+
+// validatePolygon checks that the points describe a polygon within the frame.
+func validatePolygon(points []Point) error {
+	if len(points) < minPolygonPoints {
+		return errors.Errorf("a polygon must have at least %d points", minPolygonPoints)
+	}
+	for _, p := range points {
+		if err := p.validate(); err != nil {
+			return err
+		}
+	}
+	if polygonArea(points) == 0 {
+		return errors.New("a polygon must not have all its points on a single line")
+	}
+	return nil
+}
+
+// polygonArea returns the area of the polygon, using the shoelace formula.
+func polygonArea(points []Point) float64 {
+	area := 0.0
+	for i, j := 0, len(points)-1; i < len(points); j, i = i, i+1 {
+		area += points[j].X*points[i].Y - points[i].X*points[j].Y
+	}
+	return math.Abs(area) / 2
+}
+
+// polygonContains returns true if the point lies within the polygon, using the even-odd rule.
+func polygonContains(polygon []Point, p Point) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a.Y > p.Y) != (b.Y > p.Y) &&
+			p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// cross returns the z component of the cross product of the vectors o->a and o->b. It is positive
+// when b lies to the left of o->a, negative when it lies to the right, and zero when they are collinear.
+func cross(o, a, b Point) float64 {
+	return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
+}
+
+// segmentsIntersect returns true if the segment p1->p2 properly crosses the segment q1->q2.
+func segmentsIntersect(p1, p2, q1, q2 Point) bool {
+	d1 := cross(q1, q2, p1)
+	d2 := cross(q1, q2, p2)
+	d3 := cross(p1, p2, q1)
+	d4 := cross(p1, p2, q2)
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) &&
+		((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
@@ -0,0 +1,171 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var square = []Point{{0.2, 0.2}, {0.8, 0.2}, {0.8, 0.8}, {0.2, 0.8}}
+
+func TestValidatePolygon(t *testing.T) {
+	tests := []struct {
+		name        string
+		points      []Point
+		expectError bool
+	}{
+		{"triangle", []Point{{0, 0}, {1, 0}, {0, 1}}, false},
+		{"square", square, false},
+		{"frame corners", []Point{{0, 0}, {1, 0}, {1, 1}, {0, 1}}, false},
+		{"no points", nil, true},
+		{"two points", []Point{{0, 0}, {1, 1}}, true},
+		{"x out of range", []Point{{0, 0}, {1.1, 0}, {0, 1}}, true},
+		{"negative y", []Point{{0, 0}, {1, -0.1}, {0, 1}}, true},
+		{"collinear points", []Point{{0.1, 0.1}, {0.5, 0.5}, {0.9, 0.9}}, true},
+		{"repeated point", []Point{{0.1, 0.1}, {0.1, 0.1}, {0.1, 0.1}}, true},
+		{"collinear point on an edge", []Point{{0, 0}, {0.5, 0}, {1, 0}, {0, 1}}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validatePolygon(test.points)
+			if test.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPolygonContains(t *testing.T) {
+	// concave 'L' shape, whose notch is the top right quarter
+	lShape := []Point{{0, 0}, {0.5, 0}, {0.5, 0.5}, {1, 0.5}, {1, 1}, {0, 1}}
+
+	tests := []struct {
+		name     string
+		polygon  []Point
+		point    Point
+		expected bool
+	}{
+		{"centre", square, Point{0.5, 0.5}, true},
+		{"left of square", square, Point{0.1, 0.5}, false},
+		{"below square", square, Point{0.5, 0.9}, false},
+		// the edges are half-open, so that a point on the border of adjacent zones is only counted once
+		{"top left vertex", square, Point{0.2, 0.2}, true},
+		{"top right vertex", square, Point{0.8, 0.2}, false},
+		{"bottom right vertex", square, Point{0.8, 0.8}, false},
+		{"bottom left vertex", square, Point{0.2, 0.8}, false},
+		{"left edge", square, Point{0.2, 0.5}, true},
+		{"right edge", square, Point{0.8, 0.5}, false},
+		// points collinear with an edge, outside of the polygon
+		{"collinear with top edge", square, Point{0.9, 0.2}, false},
+		{"collinear with left edge", square, Point{0.2, 0.9}, false},
+		{"concave inside", lShape, Point{0.25, 0.25}, true},
+		{"concave notch", lShape, Point{0.75, 0.25}, false},
+		{"concave lower", lShape, Point{0.75, 0.75}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, polygonContains(test.polygon, test.point))
+		})
+	}
+}
+
+func TestCross(t *testing.T) {
+	o, a := Point{0, 0.5}, Point{1, 0.5}
+	// the y axis of the frame points down, so a positive cross product is on the right-hand side of o->a
+	assert.Greater(t, cross(o, a, Point{0.5, 0.8}), 0.0)
+	assert.Less(t, cross(o, a, Point{0.5, 0.2}), 0.0)
+	assert.Zero(t, cross(o, a, Point{0.25, 0.5}))
+}
+
+func TestSegmentsIntersect(t *testing.T) {
+	tests := []struct {
+		name     string
+		p1, p2   Point
+		q1, q2   Point
+		expected bool
+	}{
+		{"crossing", Point{0.5, 0.2}, Point{0.5, 0.8}, Point{0, 0.5}, Point{1, 0.5}, true},
+		{"crossing reversed", Point{0.5, 0.8}, Point{0.5, 0.2}, Point{1, 0.5}, Point{0, 0.5}, true},
+		{"diagonal crossing", Point{0, 0}, Point{1, 1}, Point{0, 1}, Point{1, 0}, true},
+		{"parallel", Point{0, 0.2}, Point{1, 0.2}, Point{0, 0.5}, Point{1, 0.5}, false},
+		{"collinear overlapping", Point{0, 0.5}, Point{0.6, 0.5}, Point{0.4, 0.5}, Point{1, 0.5}, false},
+		{"collinear disjoint", Point{0, 0.5}, Point{0.2, 0.5}, Point{0.4, 0.5}, Point{1, 0.5}, false},
+		{"ends on the line", Point{0.5, 0.2}, Point{0.5, 0.5}, Point{0, 0.5}, Point{1, 0.5}, false},
+		{"starts on the line", Point{0.5, 0.5}, Point{0.5, 0.8}, Point{0, 0.5}, Point{1, 0.5}, false},
+		{"passes the end of the line", Point{0.5, 0.2}, Point{0.5, 0.8}, Point{0, 0.5}, Point{0.4, 0.5}, false},
+		{"through the end of the line", Point{0.4, 0.2}, Point{0.4, 0.8}, Point{0, 0.5}, Point{0.4, 0.5}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, segmentsIntersect(test.p1, test.p2, test.q1, test.q2))
+		})
+	}
+}
+
+func trackedPerson(id int, position Point) InferenceObject {
+	return InferenceObject{
+		Id: id,
+		Detection: Detection{
+			Label: "person",
+			BoundingBox: BoundingBox{
+				XMin: position.X - 0.05,
+				XMax: position.X + 0.05,
+				YMin: position.Y - 0.2,
+				YMax: position.Y,
+			},
+		},
+	}
+}
+
+func TestLineCrossingDirection(t *testing.T) {
+	line := CountingLine{Name: "door", Start: Point{0, 0.5}, End: Point{1, 0.5}}
+
+	tests := []struct {
+		name        string
+		line        CountingLine
+		from, to    Point
+		elapsed     time.Duration
+		expectedIn  int
+		expectedOut int
+	}{
+		{"top to bottom is in", line, Point{0.5, 0.3}, Point{0.5, 0.7}, time.Second, 1, 0},
+		{"bottom to top is out", line, Point{0.5, 0.7}, Point{0.5, 0.3}, time.Second, 0, 1},
+		{"reversed line swaps direction", CountingLine{Name: "door", Start: line.End, End: line.Start},
+			Point{0.5, 0.3}, Point{0.5, 0.7}, time.Second, 0, 1},
+		{"diagonal top to bottom is in", line, Point{0.2, 0.3}, Point{0.8, 0.7}, time.Second, 1, 0},
+		{"moving along the line", line, Point{0.2, 0.5}, Point{0.8, 0.5}, time.Second, 0, 0},
+		{"stopping on the line", line, Point{0.5, 0.3}, Point{0.5, 0.5}, time.Second, 0, 0},
+		{"beyond the end of the line", CountingLine{Name: "door", Start: Point{0, 0.5}, End: Point{0.4, 0.5}},
+			Point{0.5, 0.3}, Point{0.5, 0.7}, time.Second, 0, 0},
+		{"track timed out", line, Point{0.5, 0.3}, Point{0.5, 0.7}, trackTimeout + time.Second, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newStatisticsAggregator("", time.Minute, time.Hour)
+			s.lines["camera"] = []CountingLine{test.line}
+			start := time.Now().Truncate(time.Minute)
+
+			s.process("camera", InferenceEvent{Objects: []InferenceObject{trackedPerson(1, test.from)}}, start)
+			s.process("camera", InferenceEvent{Objects: []InferenceObject{trackedPerson(1, test.to)}}, start.Add(test.elapsed))
+
+			in, out := 0, 0
+			for key, b := range s.buckets {
+				if key.zone == "door" {
+					in += b.In
+					out += b.Out
+				}
+			}
+			require.Equal(t, test.expectedIn, in, "in")
+			require.Equal(t, test.expectedOut, out, "out")
+		})
+	}
+}
@@ -0,0 +1,290 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+const (
+	// cameraTag is the name of the pipeline tag holding the camera name. EVAM adds the tags to every inference event.
+	cameraTag = "camera"
+
+	// statisticsResourceName is the source and resource name of the summary events
+	statisticsResourceName = "DetectionStatistics"
+
+	statisticsClientId     = "app-camera-management-statistics"
+	statisticsPublishQueue = 10
+)
+
+// statisticsConfig holds the parsed durations of the StatisticsConfig
+type statisticsConfig struct {
+	bucketSize      time.Duration
+	retention       time.Duration
+	publishInterval time.Duration
+}
+
+func parseStatisticsConfig(cfg StatisticsConfig) (statisticsConfig, error) {
+	var parsed statisticsConfig
+	var err error
+	if parsed.bucketSize, err = time.ParseDuration(cfg.BucketSize); err != nil || parsed.bucketSize <= 0 {
+		return parsed, errors.Errorf("invalid Statistics BucketSize '%s'", cfg.BucketSize)
+	}
+	if parsed.retention, err = time.ParseDuration(cfg.Retention); err != nil || parsed.retention < parsed.bucketSize {
+		return parsed, errors.Errorf("invalid Statistics Retention '%s', must be at least the BucketSize", cfg.Retention)
+	}
+	if parsed.publishInterval, err = time.ParseDuration(cfg.PublishInterval); err != nil || parsed.publishInterval < 0 ||
+		parsed.publishInterval%parsed.bucketSize != 0 {
+		return parsed, errors.Errorf("invalid Statistics PublishInterval '%s', must be a multiple of the BucketSize", cfg.PublishInterval)
+	}
+	return parsed, nil
+}
+
+// tlsBrokerSchemes are the schemes of the broker urls connecting with TLS
+var tlsBrokerSchemes = []string{"ssl", "tls", "mqtts", "wss"}
+
+// validateStatisticsConfig checks the Statistics configuration when the statistics are enabled.
+func validateStatisticsConfig(cfg CustomConfig) error {
+	if !cfg.Statistics.Enabled {
+		return nil
+	}
+	if _, err := parseStatisticsConfig(cfg.Statistics); err != nil {
+		return err
+	}
+	if (cfg.Statistics.ClientCert == "") != (cfg.Statistics.ClientKey == "") {
+		return errors.New("both Statistics ClientCert and ClientKey must be set")
+	}
+	brokerUrl, err := url.Parse(cfg.Statistics.MqttBrokerUrl)
+	if err != nil {
+		return errors.Wrapf(err, "invalid Statistics MqttBrokerUrl '%s'", cfg.Statistics.MqttBrokerUrl)
+	}
+	// the subscriber sends the same credentials as EVAM, so it must not connect in clear text to a broker
+	// EVAM only connects to with TLS
+	if cfg.MqttDestination.CaCert != "" && !isTLSBrokerScheme(brokerUrl.Scheme) {
+		return errors.Errorf("invalid Statistics MqttBrokerUrl '%s', must use one of the %v schemes "+
+			"when the MqttDestination uses TLS", cfg.Statistics.MqttBrokerUrl, tlsBrokerSchemes)
+	}
+	return nil
+}
+
+func isTLSBrokerScheme(scheme string) bool {
+	for _, s := range tlsBrokerSchemes {
+		if s == scheme {
+			return true
+		}
+	}
+	return false
+}
+
+// statisticsTLSConfig returns the TLS configuration of the subscriber to the inference events,
+// or nil when the broker url does not use TLS.
+func statisticsTLSConfig(cfg StatisticsConfig) (*tls.Config, error) {
+	brokerUrl, err := url.Parse(cfg.MqttBrokerUrl)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid Statistics MqttBrokerUrl '%s'", cfg.MqttBrokerUrl)
+	}
+	if !isTLSBrokerScheme(brokerUrl.Scheme) {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.CaCert != "" {
+		caCert, err := os.ReadFile(cfg.CaCert)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read the Statistics CaCert")
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caCert) {
+			return nil, errors.Errorf("no certificate found in the Statistics CaCert %s", cfg.CaCert)
+		}
+	}
+	if cfg.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load the Statistics ClientCert and ClientKey")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// initStatistics loads the statistics store and creates the publisher of the summary events.
+// It must be called before the service is run.
+func (app *CameraManagementApp) initStatistics() error {
+	cfg, err := parseStatisticsConfig(app.appConfig().Statistics)
+	if err != nil {
+		return err
+	}
+
+	app.statistics = newStatisticsAggregator(app.appConfig().Statistics.StoreFile, cfg.bucketSize, cfg.retention)
+	if err = app.statistics.load(); err != nil {
+		return err
+	}
+
+	if cfg.publishInterval > 0 {
+		app.statisticsPublisher, err = app.service.AddBackgroundPublisherWithTopic(statisticsPublishQueue,
+			app.appConfig().Statistics.PublishTopic)
+		if err != nil {
+			return errors.Wrap(err, "failed to create statistics publisher")
+		}
+	}
+
+	return nil
+}
+
+// runStatistics subscribes to the inference events and maintains the statistics until the context is done.
+func (app *CameraManagementApp) runStatistics(ctx context.Context, wg *sync.WaitGroup) error {
+	cfg, err := parseStatisticsConfig(app.appConfig().Statistics)
+	if err != nil {
+		return err
+	}
+
+	tlsConfig, err := statisticsTLSConfig(app.appConfig().Statistics)
+	if err != nil {
+		return err
+	}
+
+	opts := mqtt.NewClientOptions()
+	opts.AddBroker(app.appConfig().Statistics.MqttBrokerUrl)
+	if tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
+	}
+	// client ids must be unique, otherwise the instances would keep disconnecting each other
+	opts.SetClientID(statisticsClientId + "-" + app.instanceId)
+	opts.SetAutoReconnect(true)
+	opts.SetConnectRetry(true)
+	if secretName := app.appConfig().MqttDestination.SecretName; secretName != "" {
+		// the broker receiving the inference events requires the same credentials as EVAM
+		creds, err := app.tryGetCredentials(secretName)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get the %s secret of the metadata broker", secretName)
+		}
+		opts.SetUsername(creds.Username)
+		opts.SetPassword(creds.Password)
+	}
+	topic := app.inferenceSubscriptionTopic()
+	opts.SetOnConnectHandler(func(client mqtt.Client) {
+		// (re-)subscribe every time the connection is established
+		token := client.Subscribe(topic, 0, app.onInferenceEvent)
+		if token.Wait() && token.Error() != nil {
+			app.lc.Errorf("Failed to subscribe to inference events on topic %s: %v", topic, token.Error())
+			return
+		}
+		app.lc.Infof("Subscribed to inference events on topic %s", topic)
+	})
+
+	client := mqtt.NewClient(opts)
+	// with ConnectRetry set, the connection is retried in the background and this does not block
+	client.Connect()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		pruneTicker := time.NewTicker(cfg.bucketSize)
+		defer pruneTicker.Stop()
+
+		var publishTick <-chan time.Time
+		if app.statisticsPublisher != nil {
+			publishTicker := time.NewTicker(cfg.publishInterval)
+			defer publishTicker.Stop()
+			publishTick = publishTicker.C
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				client.Disconnect(250)
+				if err := app.statistics.prune(time.Now()); err != nil {
+					app.lc.Errorf("Failed to save detection statistics: %v", err)
+				}
+				return
+			case now := <-pruneTicker.C:
+				if err := app.statistics.prune(now); err != nil {
+					app.lc.Errorf("Failed to save detection statistics: %v", err)
+				}
+			case now := <-publishTick:
+				// summarize the last complete interval
+				end := now.Truncate(cfg.publishInterval)
+				app.publishStatistics(end.Add(-cfg.publishInterval), end, cfg.publishInterval)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// onInferenceEvent is called for every inference event published by EVAM
+func (app *CameraManagementApp) onInferenceEvent(_ mqtt.Client, msg mqtt.Message) {
+	event := InferenceEvent{}
+	if err := json.Unmarshal(msg.Payload(), &event); err != nil {
+		app.lc.Debugf("Ignoring invalid inference event: %v", err)
+		return
+	}
+
+	camera, ok := event.Tags[cameraTag].(string)
+	if !ok || camera == "" {
+		app.lc.Debugf("Ignoring inference event without the %s tag from source %s", cameraTag, event.Source)
+		return
+	}
+
+	app.statistics.process(camera, event, time.Now())
+}
+
+// publishStatistics publishes an EdgeX event per camera summarizing the statistics between start and end.
+func (app *CameraManagementApp) publishStatistics(start, end time.Time, interval time.Duration) {
+	if !app.isLeader() {
+		// every instance aggregates the statistics, but only the leader publishes them
+		return
+	}
+	for _, camera := range app.statistics.cameras() {
+		buckets, err := app.statistics.query(camera, "", "", start, end, interval)
+		if err != nil {
+			app.lc.Errorf("Failed to query detection statistics of camera %s: %v", camera, err)
+			continue
+		}
+		if len(buckets) == 0 {
+			continue
+		}
+
+		device, err := app.getDeviceByName(camera)
+		if err != nil {
+			app.lc.Errorf("Failed to query device %s: %v", camera, err)
+			continue
+		}
+
+		event := dtos.NewEvent(device.ProfileName, camera, statisticsResourceName)
+		event.AddObjectReading(statisticsResourceName, buckets)
+		payload, err := json.Marshal(event)
+		if err != nil {
+			app.lc.Errorf("Failed to marshal detection statistics event: %v", err)
+			continue
+		}
+
+		ctx := app.service.BuildContext(uuid.NewString(), common.ContentTypeJSON)
+		ctx.AddValue(interfaces.PROFILENAME, event.ProfileName)
+		ctx.AddValue(interfaces.DEVICENAME, event.DeviceName)
+		ctx.AddValue(interfaces.SOURCENAME, event.SourceName)
+		if err = app.statisticsPublisher.Publish(payload, ctx); err != nil {
+			app.lc.Errorf("Failed to publish detection statistics event: %v", err)
+		}
+	}
+}
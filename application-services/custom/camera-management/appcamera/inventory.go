@@ -0,0 +1,316 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/IOTechSystems/onvif/device"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/models"
+	"github.com/pkg/errors"
+)
+
+const (
+	deviceInformationCommand = "DeviceInformation"
+
+	defaultInventoryCacheTTL = time.Minute
+	// inventoryConcurrency is the maximum number of cameras queried at the same time
+	inventoryConcurrency = 8
+)
+
+// Health statuses of a camera, from the best to the worst
+const (
+	HealthOk       = "ok"
+	HealthDegraded = "degraded"
+	HealthOffline  = "offline"
+	HealthLocked   = "locked"
+)
+
+// DeviceInformation holds the Onvif device information of a camera
+type DeviceInformation struct {
+	Manufacturer    string `json:"manufacturer"`
+	Model           string `json:"model"`
+	FirmwareVersion string `json:"firmwareVersion"`
+	SerialNumber    string `json:"serialNumber"`
+	HardwareId      string `json:"hardwareId"`
+}
+
+// CameraHealth summarizes the state of a camera and its pipeline
+type CameraHealth struct {
+	// Status is one of ok, degraded (a query or the pipeline failed), offline or locked
+	Status         string `json:"status"`
+	AdminState     string `json:"adminState"`
+	OperatingState string `json:"operatingState"`
+	// Problems describes why the camera is not ok
+	Problems []string `json:"problems,omitempty"`
+}
+
+// CameraInventoryItem joins everything known about a camera
+type CameraInventoryItem struct {
+	Device   dtos.Device    `json:"device"`
+	Features CameraFeatures `json:"features"`
+	// DeviceInformation is only set for Onvif cameras
+	DeviceInformation *DeviceInformation  `json:"deviceInformation,omitempty"`
+	Pipeline          *PipelineInfoStatus `json:"pipeline,omitempty"`
+	Health            CameraHealth        `json:"health"`
+	// UpdatedAt is the time the device service was last queried for the camera
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// inventoryEntry is the cached part of an inventory item, which is read from the device services
+type inventoryEntry struct {
+	device    dtos.Device
+	features  CameraFeatures
+	info      *DeviceInformation
+	problems  []string
+	fetchedAt time.Time
+}
+
+// inventoryCache caches the cameras read from the device services until the TTL expires, or a device system
+// event for the camera is received. Pipelines are not cached, as they are known by this service.
+type inventoryCache struct {
+	ttl     time.Duration
+	mutex   sync.Mutex
+	devices []string
+	// listedAt is zero when the list of devices must be refreshed
+	listedAt time.Time
+	entries  map[string]inventoryEntry
+	// generation is incremented on invalidation, so that entries read before are not cached
+	generation uint64
+}
+
+func newInventoryCache(ttl time.Duration) *inventoryCache {
+	return &inventoryCache{
+		ttl:     ttl,
+		entries: make(map[string]inventoryEntry),
+	}
+}
+
+func parseInventoryCacheTTL(ttl string) (time.Duration, error) {
+	if ttl == "" {
+		return defaultInventoryCacheTTL, nil
+	}
+	parsed, err := time.ParseDuration(ttl)
+	if err != nil || parsed < 0 {
+		return 0, errors.Errorf("invalid Inventory CacheTTL '%s'", ttl)
+	}
+	return parsed, nil
+}
+
+// setTTL applies an updated TTL to the cached cameras.
+func (c *inventoryCache) setTTL(ttl time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.ttl = ttl
+}
+
+// invalidate removes the camera from the cache, along with the list of devices.
+func (c *inventoryCache) invalidate(camera string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.entries, camera)
+	c.listedAt = time.Time{}
+	c.generation++
+}
+
+// invalidateAll empties the cache.
+func (c *inventoryCache) invalidateAll() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries = make(map[string]inventoryEntry)
+	c.listedAt = time.Time{}
+	c.generation++
+}
+
+// fresh returns true if the time the cache was filled in is within the TTL. The mutex must be held.
+func (c *inventoryCache) fresh(t time.Time) bool {
+	return !t.IsZero() && time.Since(t) < c.ttl
+}
+
+// getInventory returns the inventory of all the cameras, reading the cameras which are not cached
+// from the device services.
+func (app *CameraManagementApp) getInventory(ctx context.Context, refresh bool) ([]CameraInventoryItem, error) {
+	cache := app.inventory
+	if refresh {
+		cache.invalidateAll()
+	}
+
+	cache.mutex.Lock()
+	names := cache.devices
+	listed := cache.fresh(cache.listedAt)
+	generation := cache.generation
+	cache.mutex.Unlock()
+
+	if !listed {
+		devices, err := app.getAllDevices()
+		if err != nil {
+			return nil, err
+		}
+		names = make([]string, 0, len(devices))
+		for _, dev := range devices {
+			names = append(names, dev.Name)
+		}
+		sort.Strings(names)
+
+		cache.mutex.Lock()
+		if cache.generation == generation {
+			cache.devices = names
+			cache.listedAt = time.Now()
+		}
+		cache.mutex.Unlock()
+	}
+
+	items := make([]CameraInventoryItem, len(names))
+	errs := make([]error, len(names))
+	sem := make(chan struct{}, inventoryConcurrency)
+	wg := sync.WaitGroup{}
+	for i, name := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, name string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			items[i], errs[i] = app.getCameraInventory(ctx, name, false)
+		}(i, name)
+	}
+	wg.Wait()
+
+	// cameras deleted since the list was cached are skipped
+	res := make([]CameraInventoryItem, 0, len(items))
+	for i, item := range items {
+		if errs[i] != nil {
+			app.lc.Warnf("Skipping camera %s in the inventory: %v", names[i], errs[i])
+			continue
+		}
+		res = append(res, item)
+	}
+	return res, nil
+}
+
+// getCameraInventory returns the inventory of a camera, which is read from the device service unless
+// it is cached.
+func (app *CameraManagementApp) getCameraInventory(ctx context.Context, camera string, refresh bool) (CameraInventoryItem, error) {
+	cache := app.inventory
+	if refresh {
+		cache.invalidate(camera)
+	}
+
+	cache.mutex.Lock()
+	entry, found := cache.entries[camera]
+	cached := found && cache.fresh(entry.fetchedAt)
+	generation := cache.generation
+	cache.mutex.Unlock()
+
+	if !cached {
+		var err error
+		if entry, err = app.fetchInventoryEntry(ctx, camera); err != nil {
+			return CameraInventoryItem{}, err
+		}
+		cache.mutex.Lock()
+		if cache.generation == generation {
+			cache.entries[camera] = entry
+		}
+		cache.mutex.Unlock()
+	}
+
+	item := CameraInventoryItem{
+		Device:            entry.device,
+		Features:          entry.features,
+		DeviceInformation: entry.info,
+		UpdatedAt:         entry.fetchedAt,
+	}
+	problems := append([]string(nil), entry.problems...)
+
+	status, found, err := app.getPipelineInfoStatus(camera)
+	if err != nil {
+		problems = append(problems, err.Error())
+	} else if found {
+		item.Pipeline = &status
+		if state := pipelineState(status.Status); state == "ERROR" || state == Aborted {
+			problems = append(problems, "pipeline state is "+state)
+		}
+	}
+
+	item.Health = cameraHealth(entry.device, problems)
+	return item, nil
+}
+
+// fetchInventoryEntry reads the camera from the device service.
+func (app *CameraManagementApp) fetchInventoryEntry(ctx context.Context, camera string) (inventoryEntry, error) {
+	resp, edgexErr := app.service.DeviceClient().DeviceByName(ctx, camera)
+	if edgexErr != nil {
+		return inventoryEntry{}, errors.Wrapf(edgexErr, "failed to get device %s", camera)
+	}
+	entry := inventoryEntry{
+		device:    resp.Device,
+		fetchedAt: time.Now(),
+	}
+
+	adapter, err := app.getCameraAdapter(entry.device)
+	if err != nil {
+		entry.features = CameraFeatures{CameraType: Unknown}
+		return entry, nil
+	}
+	// locked devices do not accept commands
+	if entry.device.AdminState == models.Locked {
+		entry.features = CameraFeatures{CameraType: adapter.Type()}
+		return entry, nil
+	}
+
+	if entry.features, err = adapter.Features(entry.device); err != nil {
+		entry.features = CameraFeatures{CameraType: adapter.Type()}
+		entry.problems = append(entry.problems, "failed to get features: "+err.Error())
+	}
+	if adapter.Type() == Onvif {
+		info := device.GetDeviceInformationResponse{}
+		if err = app.issueGetCommandForResponse(ctx, camera, deviceInformationCommand, &info); err != nil {
+			entry.problems = append(entry.problems, "failed to get device information: "+err.Error())
+		} else {
+			entry.info = &DeviceInformation{
+				Manufacturer:    info.Manufacturer,
+				Model:           info.Model,
+				FirmwareVersion: info.FirmwareVersion,
+				SerialNumber:    info.SerialNumber,
+				HardwareId:      info.HardwareId,
+			}
+		}
+	}
+	return entry, nil
+}
+
+// pipelineState returns the state of the EVAM pipeline status, or an empty string if it is unknown.
+func pipelineState(status interface{}) string {
+	if m, ok := status.(map[string]interface{}); ok {
+		if state, ok := m["state"].(string); ok {
+			return state
+		}
+	}
+	return ""
+}
+
+func cameraHealth(dev dtos.Device, problems []string) CameraHealth {
+	health := CameraHealth{
+		Status:         HealthOk,
+		AdminState:     dev.AdminState,
+		OperatingState: dev.OperatingState,
+		Problems:       problems,
+	}
+	switch {
+	case dev.AdminState == models.Locked:
+		health.Status = HealthLocked
+	case dev.OperatingState == models.Down:
+		health.Status = HealthOffline
+	case len(problems) > 0:
+		health.Status = HealthDegraded
+	}
+	return health
+}
@@ -0,0 +1,136 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdkMocks "github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces/mocks"
+	clientMocks "github.com/edgexfoundry/go-mod-core-contracts/v3/clients/interfaces/mocks"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos/responses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// inventoryAdapter supports the cameras of the device-inventory device service, without querying them
+type inventoryAdapter struct {
+	CameraAdapter
+}
+
+func (a *inventoryAdapter) Type() CameraType            { return USB }
+func (a *inventoryAdapter) ServiceName() string         { return "device-inventory" }
+func (a *inventoryAdapter) Supports(_ dtos.Device) bool { return true }
+
+func (a *inventoryAdapter) Features(_ dtos.Device) (CameraFeatures, error) {
+	return CameraFeatures{CameraType: USB}, nil
+}
+
+// newInventoryTestApp returns an app whose device service provides the camera. The reads of the camera wait for
+// fetching to be read from, when it is set.
+func newInventoryTestApp(ttl time.Duration, fetching chan struct{}) (*CameraManagementApp, *clientMocks.DeviceClient) {
+	camera := dtos.Device{Name: "camera", ServiceName: "device-inventory"}
+	deviceClient := &clientMocks.DeviceClient{}
+	deviceClient.On("DeviceByName", mock.Anything, "camera").Run(func(mock.Arguments) {
+		if fetching != nil {
+			fetching <- struct{}{}
+			<-fetching
+		}
+	}).Return(responses.DeviceResponse{Device: camera}, nil)
+	deviceClient.On("DevicesByServiceName", mock.Anything, "device-inventory", 0, -1).Return(
+		responses.MultiDevicesResponse{Devices: []dtos.Device{camera}}, nil)
+	service := &sdkMocks.ApplicationService{}
+	service.On("DeviceClient").Return(deviceClient)
+
+	app := newTestApp(validTestConfig())
+	app.service = service
+	app.inventory = newInventoryCache(ttl)
+	app.RegisterCameraAdapter(&inventoryAdapter{})
+	return app, deviceClient
+}
+
+func TestInventoryCacheTTL(t *testing.T) {
+	app, deviceClient := newInventoryTestApp(100*time.Millisecond, nil)
+
+	for i := 0; i < 2; i++ {
+		items, err := app.getInventory(context.Background(), false)
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		assert.Equal(t, USB, items[0].Features.CameraType)
+	}
+	deviceClient.AssertNumberOfCalls(t, "DevicesByServiceName", 1)
+	deviceClient.AssertNumberOfCalls(t, "DeviceByName", 1)
+
+	// the list and the camera are read again once the TTL expires
+	time.Sleep(150 * time.Millisecond)
+	_, err := app.getInventory(context.Background(), false)
+	require.NoError(t, err)
+	deviceClient.AssertNumberOfCalls(t, "DevicesByServiceName", 2)
+	deviceClient.AssertNumberOfCalls(t, "DeviceByName", 2)
+
+	// a refresh ignores the cache
+	_, err = app.getCameraInventory(context.Background(), "camera", true)
+	require.NoError(t, err)
+	deviceClient.AssertNumberOfCalls(t, "DeviceByName", 3)
+
+	// nothing is cached with a zero TTL
+	app.inventory.setTTL(0)
+	_, err = app.getCameraInventory(context.Background(), "camera", false)
+	require.NoError(t, err)
+	deviceClient.AssertNumberOfCalls(t, "DeviceByName", 4)
+}
+
+func TestInventoryInvalidateDuringFetch(t *testing.T) {
+	fetching := make(chan struct{})
+	app, deviceClient := newInventoryTestApp(time.Minute, fetching)
+
+	done := make(chan error)
+	go func() {
+		_, err := app.getCameraInventory(context.Background(), "camera", false)
+		done <- err
+	}()
+	// the camera is updated while it is being read, so the entry read may be stale
+	<-fetching
+	app.inventory.invalidate("camera")
+	fetching <- struct{}{}
+	require.NoError(t, <-done)
+
+	app.inventory.mutex.Lock()
+	_, cached := app.inventory.entries["camera"]
+	app.inventory.mutex.Unlock()
+	assert.False(t, cached, "the entry read before the invalidation is not cached")
+
+	go func() {
+		_, err := app.getCameraInventory(context.Background(), "camera", false)
+		done <- err
+	}()
+	<-fetching
+	fetching <- struct{}{}
+	require.NoError(t, <-done)
+	deviceClient.AssertNumberOfCalls(t, "DeviceByName", 2)
+}
+
+func TestInventoryInvalidatedByDeviceSystemEvent(t *testing.T) {
+	app, deviceClient := newInventoryTestApp(time.Minute, nil)
+
+	_, err := app.getInventory(context.Background(), false)
+	require.NoError(t, err)
+	deviceClient.AssertNumberOfCalls(t, "DeviceByName", 1)
+
+	event := dtos.NewSystemEvent(common.DeviceSystemEventType, common.SystemEventActionUpdate, "core-metadata",
+		"device-inventory", nil, dtos.Device{Name: "camera", ServiceName: "device-inventory"})
+	_, result := app.processEdgeXDeviceSystemEvent(nil, event)
+	require.Nil(t, result)
+
+	_, err = app.getInventory(context.Background(), false)
+	require.NoError(t, err)
+	deviceClient.AssertNumberOfCalls(t, "DevicesByServiceName", 2)
+	deviceClient.AssertNumberOfCalls(t, "DeviceByName", 2)
+}
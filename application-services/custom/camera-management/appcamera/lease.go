@@ -0,0 +1,373 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/clients/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/pkg/errors"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+const (
+	leaseClientIdPrefix = "app-camera-management-lease-"
+	leaseQos            = 1
+
+	// minStoreLeaseDuration is the minimum TTL of a Consul session
+	minStoreLeaseDuration = 10 * time.Second
+	// storeLockDelay is how long Consul prevents the lease from being acquired once the session of the
+	// leader is invalidated, which defaults to 15s
+	storeLockDelay = time.Second
+)
+
+// leaseRecord is the persisted state of a file lease, and the heartbeat of a message bus lease
+type leaseRecord struct {
+	Holder  string    `json:"holder"`
+	Expires time.Time `json:"expires"`
+	// Leader is set in heartbeats by the instance which holds the lease
+	Leader bool `json:"leader,omitempty"`
+	// Duration is how long a heartbeat is valid for, so that receivers do not depend on synchronized clocks
+	Duration string `json:"duration,omitempty"`
+}
+
+// fileLease stores the lease in a file, which must be shared by all the instances, for example through
+// a shared volume. Updates are serialized using an exclusive lock file next to it.
+type fileLease struct {
+	filename string
+}
+
+func newFileLease(filename string) *fileLease {
+	return &fileLease{filename: filename}
+}
+
+func (l *fileLease) Acquire(_ context.Context, holder string, duration time.Duration) (string, error) {
+	unlock, err := l.lock(duration)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	record := leaseRecord{}
+	if err = readJSONFile(l.filename, &record); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	if record.Holder != "" && record.Holder != holder && now.Before(record.Expires) {
+		return record.Holder, nil
+	}
+
+	record = leaseRecord{Holder: holder, Expires: now.Add(duration)}
+	if err = writeJSONFile(l.filename, record); err != nil {
+		return "", err
+	}
+	return holder, nil
+}
+
+func (l *fileLease) Release(holder string) error {
+	unlock, err := l.lock(0)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	record := leaseRecord{}
+	if err = readJSONFile(l.filename, &record); err != nil {
+		return err
+	}
+	if record.Holder != holder {
+		return nil
+	}
+	if err = os.Remove(l.filename); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to remove lease file %s", l.filename)
+	}
+	return nil
+}
+
+// lock creates the lock file, and returns the function removing it. A lock file older than staleAfter
+// is left over by an instance which died while holding it, and is removed.
+func (l *fileLease) lock(staleAfter time.Duration) (func(), error) {
+	lockFile := l.filename + ".lock"
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(lockFile, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_ = f.Close()
+			return func() { _ = os.Remove(lockFile) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, errors.Wrapf(err, "failed to create lock file %s", lockFile)
+		}
+		info, err := os.Stat(lockFile)
+		if err != nil || staleAfter <= 0 || time.Since(info.ModTime()) < staleAfter {
+			break
+		}
+		_ = os.Remove(lockFile)
+	}
+	return nil, errors.Errorf("lease file %s is locked by another instance", l.filename)
+}
+
+// messageBusLease elects the leader by exchanging heartbeats over the MQTT message bus. Every instance
+// publishes its heartbeat to <topic>/<instance id>. The instance claiming the lease keeps it for as long
+// as its heartbeats are received, otherwise the instance with the lowest id claims it. A new instance
+// listens for a full lease duration before claiming the lease, to learn about the others.
+type messageBusLease struct {
+	lc         logger.LoggingClient
+	client     mqtt.Client
+	topic      string
+	instanceId string
+
+	mutex      sync.Mutex
+	started    time.Time
+	leading    bool
+	heartbeats map[string]leaseRecord
+}
+
+func newMessageBusLease(lc logger.LoggingClient, brokerUrl string, topic string, instanceId string) *messageBusLease {
+	l := &messageBusLease{
+		lc:         lc,
+		topic:      strings.TrimSuffix(topic, "/"),
+		instanceId: instanceId,
+		heartbeats: make(map[string]leaseRecord),
+	}
+
+	opts := mqtt.NewClientOptions()
+	opts.AddBroker(brokerUrl)
+	opts.SetClientID(leaseClientIdPrefix + instanceId)
+	opts.SetAutoReconnect(true)
+	opts.SetConnectRetry(true)
+	opts.SetOnConnectHandler(func(client mqtt.Client) {
+		// (re-)subscribe every time the connection is established
+		token := client.Subscribe(l.topic+"/+", leaseQos, l.onHeartbeat)
+		if token.Wait() && token.Error() != nil {
+			lc.Errorf("Failed to subscribe to leader lease heartbeats on topic %s: %v", l.topic, token.Error())
+		}
+	})
+	l.client = mqtt.NewClient(opts)
+	return l
+}
+
+func (l *messageBusLease) Acquire(ctx context.Context, holder string, duration time.Duration) (string, error) {
+	now := time.Now()
+
+	l.mutex.Lock()
+	if l.started.IsZero() {
+		l.started = now
+		// with ConnectRetry set, the connection is retried in the background and this does not block
+		l.client.Connect()
+	}
+	leading := l.leading
+	l.mutex.Unlock()
+
+	// do not hold up the renewal for long when the broker is unreachable
+	if err := l.publish(ctx, holder, duration/3, leaseRecord{
+		Holder:   holder,
+		Leader:   leading,
+		Duration: duration.String(),
+	}); err != nil {
+		return "", err
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if now.Sub(l.started) < duration {
+		// still learning about the other instances
+		return "", nil
+	}
+
+	// an instance which claims the lease keeps it, and the lowest id wins if several instances claim it
+	leader := ""
+	candidate := holder
+	for other, record := range l.heartbeats {
+		if now.After(record.Expires) {
+			delete(l.heartbeats, other)
+			continue
+		}
+		if record.Leader && (leader == "" || other < leader) {
+			leader = other
+		}
+		if other < candidate {
+			candidate = other
+		}
+	}
+	if l.leading && (leader == "" || holder < leader) {
+		leader = holder
+	}
+	if leader == "" {
+		leader = candidate
+	}
+
+	l.leading = leader == holder
+	return leader, nil
+}
+
+func (l *messageBusLease) Release(holder string) error {
+	l.mutex.Lock()
+	l.leading = false
+	l.mutex.Unlock()
+
+	// an empty heartbeat tells the other instances that this one is gone
+	token := l.client.Publish(l.topic+"/"+holder, leaseQos, false, []byte{})
+	token.WaitTimeout(time.Second)
+	l.client.Disconnect(250)
+	return token.Error()
+}
+
+func (l *messageBusLease) publish(ctx context.Context, holder string, timeout time.Duration, record leaseRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal lease heartbeat")
+	}
+	token := l.client.Publish(l.topic+"/"+holder, leaseQos, false, data)
+	select {
+	case <-token.Done():
+		return errors.Wrap(token.Error(), "failed to publish lease heartbeat")
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(timeout):
+		return errors.New("timed out publishing lease heartbeat")
+	}
+}
+
+func (l *messageBusLease) onHeartbeat(_ mqtt.Client, msg mqtt.Message) {
+	holder := strings.TrimPrefix(msg.Topic(), l.topic+"/")
+	if holder == l.instanceId {
+		// our own heartbeat
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if len(msg.Payload()) == 0 {
+		delete(l.heartbeats, holder)
+		return
+	}
+
+	record := leaseRecord{}
+	if err := json.Unmarshal(msg.Payload(), &record); err != nil {
+		l.lc.Warnf("Ignoring invalid lease heartbeat on topic %s: %v", msg.Topic(), err)
+		return
+	}
+	duration, err := time.ParseDuration(record.Duration)
+	if err != nil || record.Holder != holder {
+		l.lc.Warnf("Ignoring invalid lease heartbeat on topic %s", msg.Topic())
+		return
+	}
+	// use the local clock, so that the instances do not depend on synchronized clocks
+	record.Expires = time.Now().Add(duration)
+	l.heartbeats[holder] = record
+}
+
+// storeLease holds the lease in a key of the Consul store used as the EdgeX Configuration Provider. The key
+// is locked by the session of the leader, which Consul invalidates once it is no longer renewed, deleting the key.
+type storeLease struct {
+	client *api.Client
+	key    string
+	// token returns the ACL token of the store, which is empty when the store does not require one
+	token func() (string, error)
+
+	mutex   sync.Mutex
+	session string
+	// sessionToken is the token the session was created with
+	sessionToken string
+}
+
+func newStoreLease(storeUrl string, key string, token func() (string, error)) (*storeLease, error) {
+	u, err := url.Parse(storeUrl)
+	if err != nil || u.Host == "" {
+		return nil, errors.Errorf("invalid Coordination StoreUrl '%s'", storeUrl)
+	}
+	cfg := api.DefaultConfig()
+	cfg.Address = u.Host
+	cfg.Scheme = u.Scheme
+	client, err := api.NewClient(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the lease store client")
+	}
+	return &storeLease{client: client, key: strings.Trim(key, "/"), token: token}, nil
+}
+
+func (l *storeLease) Acquire(ctx context.Context, holder string, duration time.Duration) (string, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.session != "" {
+		entry, _, err := l.client.Session().Renew(l.session, l.writeOptions(ctx))
+		if err != nil {
+			return "", errors.Wrap(err, "failed to renew the lease store session")
+		}
+		if entry == nil {
+			// the session expired, so the lease was lost
+			l.session = ""
+		}
+	}
+	if l.session == "" {
+		token, err := l.token()
+		if err != nil {
+			return "", errors.Wrap(err, "failed to get the lease store token")
+		}
+		l.sessionToken = token
+		id, _, err := l.client.Session().Create(&api.SessionEntry{
+			Name:      holder,
+			TTL:       duration.String(),
+			Behavior:  api.SessionBehaviorDelete,
+			LockDelay: storeLockDelay,
+		}, l.writeOptions(ctx))
+		if err != nil {
+			return "", errors.Wrap(err, "failed to create the lease store session")
+		}
+		l.session = id
+	}
+
+	acquired, _, err := l.client.KV().Acquire(&api.KVPair{Key: l.key, Value: []byte(holder), Session: l.session},
+		l.writeOptions(ctx))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to acquire the lease key %s", l.key)
+	}
+	if acquired {
+		return holder, nil
+	}
+
+	pair, _, err := l.client.KV().Get(l.key, (&api.QueryOptions{Token: l.sessionToken}).WithContext(ctx))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read the lease key %s", l.key)
+	}
+	if pair == nil || pair.Session == "" {
+		// released, but not acquired yet because of the lock delay
+		return "", nil
+	}
+	return string(pair.Value), nil
+}
+
+func (l *storeLease) Release(_ string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.session == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// destroying the session releases the key, and deletes it
+	_, err := l.client.Session().Destroy(l.session, l.writeOptions(ctx))
+	l.session = ""
+	return errors.Wrap(err, "failed to destroy the lease store session")
+}
+
+func (l *storeLease) writeOptions(ctx context.Context) *api.WriteOptions {
+	return (&api.WriteOptions{Token: l.sessionToken}).WithContext(ctx)
+}
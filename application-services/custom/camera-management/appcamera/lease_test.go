@@ -0,0 +1,181 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConsul implements the session and kv lock endpoints of Consul used by the store lease
+type fakeConsul struct {
+	mutex    sync.Mutex
+	sessions map[string]string
+	nextId   int
+	// lock is the session holding the key, and value its value
+	lock   string
+	value  string
+	tokens []string
+}
+
+func newFakeConsul() *fakeConsul {
+	return &fakeConsul{sessions: make(map[string]string)}
+}
+
+// expire invalidates the session as Consul does when its TTL elapses, deleting the key it holds
+func (c *fakeConsul) expire(session string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.sessions, session)
+	if c.lock == session {
+		c.lock, c.value = "", ""
+	}
+}
+
+func (c *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.tokens = append(c.tokens, r.Header.Get("X-Consul-Token"))
+
+	switch {
+	case r.URL.Path == "/v1/session/create":
+		var entry struct{ Name string }
+		_ = json.NewDecoder(r.Body).Decode(&entry)
+		c.nextId++
+		id := fmt.Sprintf("session-%d", c.nextId)
+		c.sessions[id] = entry.Name
+		_ = json.NewEncoder(w).Encode(map[string]string{"ID": id})
+	case strings.HasPrefix(r.URL.Path, "/v1/session/renew/"):
+		id := strings.TrimPrefix(r.URL.Path, "/v1/session/renew/")
+		if _, found := c.sessions[id]; !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]map[string]string{{"ID": id}})
+	case strings.HasPrefix(r.URL.Path, "/v1/session/destroy/"):
+		id := strings.TrimPrefix(r.URL.Path, "/v1/session/destroy/")
+		delete(c.sessions, id)
+		if c.lock == id {
+			c.lock, c.value = "", ""
+		}
+		_, _ = w.Write([]byte("true"))
+	case strings.HasPrefix(r.URL.Path, "/v1/kv/") && r.Method == http.MethodPut:
+		session := r.URL.Query().Get("acquire")
+		value, _ := io.ReadAll(r.Body)
+		acquired := c.lock == "" || c.lock == session
+		if acquired {
+			c.lock, c.value = session, string(value)
+		}
+		_ = json.NewEncoder(w).Encode(acquired)
+	case strings.HasPrefix(r.URL.Path, "/v1/kv/") && r.Method == http.MethodGet:
+		if c.lock == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]map[string]interface{}{{
+			"Key":     strings.TrimPrefix(r.URL.Path, "/v1/kv/"),
+			"Value":   base64.StdEncoding.EncodeToString([]byte(c.value)),
+			"Session": c.lock,
+		}})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestStoreLease(t *testing.T) {
+	consul := newFakeConsul()
+	server := httptest.NewServer(consul)
+	defer server.Close()
+
+	token := func() (string, error) { return "acl-token", nil }
+	first, err := newStoreLease(server.URL, "/edgex/leader/", token)
+	require.NoError(t, err)
+	second, err := newStoreLease(server.URL, "edgex/leader", token)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	holder, err := first.Acquire(ctx, "instance-1", minStoreLeaseDuration)
+	require.NoError(t, err)
+	assert.Equal(t, "instance-1", holder)
+
+	// the lease is held by the first instance
+	holder, err = second.Acquire(ctx, "instance-2", minStoreLeaseDuration)
+	require.NoError(t, err)
+	assert.Equal(t, "instance-1", holder)
+
+	// renewing keeps the same session
+	holder, err = first.Acquire(ctx, "instance-1", minStoreLeaseDuration)
+	require.NoError(t, err)
+	assert.Equal(t, "instance-1", holder)
+	assert.Equal(t, "session-1", first.session)
+
+	// once the session of the leader expires, the lease is taken over
+	consul.expire(first.session)
+	holder, err = second.Acquire(ctx, "instance-2", minStoreLeaseDuration)
+	require.NoError(t, err)
+	assert.Equal(t, "instance-2", holder)
+
+	// the former leader learns that it lost the lease, and creates a new session
+	holder, err = first.Acquire(ctx, "instance-1", minStoreLeaseDuration)
+	require.NoError(t, err)
+	assert.Equal(t, "instance-2", holder)
+	assert.Equal(t, "session-3", first.session)
+
+	// releasing lets the other instance acquire it
+	require.NoError(t, second.Release("instance-2"))
+	holder, err = first.Acquire(ctx, "instance-1", minStoreLeaseDuration)
+	require.NoError(t, err)
+	assert.Equal(t, "instance-1", holder)
+
+	for _, token := range consul.tokens {
+		assert.Equal(t, "acl-token", token)
+	}
+}
+
+func TestNewStoreLeaseInvalidUrl(t *testing.T) {
+	_, err := newStoreLease("localhost", "leader", func() (string, error) { return "", nil })
+	assert.Error(t, err)
+}
+
+func TestParseCoordinationConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      CoordinationConfig
+		expectError bool
+	}{
+		{"defaults", CoordinationConfig{InstanceId: "a"}, false},
+		{"store", CoordinationConfig{Mode: storeCoordination, InstanceId: "a"}, false},
+		{"store lease too short", CoordinationConfig{Mode: storeCoordination, InstanceId: "a", LeaseDuration: "5s", RenewInterval: "1s"}, true},
+		{"file lease short", CoordinationConfig{Mode: fileCoordination, InstanceId: "a", LeaseDuration: "5s", RenewInterval: "1s"}, false},
+		{"invalid mode", CoordinationConfig{Mode: "consensus"}, true},
+		{"renew after expiry", CoordinationConfig{InstanceId: "a", LeaseDuration: "10s", RenewInterval: "10s"}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parsed, err := parseCoordinationConfig(test.config)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.config.InstanceId, parsed.instanceId)
+			assert.Less(t, parsed.renewInterval, parsed.leaseDuration)
+			assert.Greater(t, parsed.leaseDuration, time.Duration(0))
+		})
+	}
+}
@@ -0,0 +1,156 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// privacyMasksParameter is the name of the pipeline parameter used to pass the privacy masks to EVAM.
+	// Pipelines which support privacy masking, such as object_detection/privacy, declare it as an object
+	// whose masks are blurred by their gvapython element.
+	privacyMasksParameter = "privacy-masks"
+)
+
+// errMasksNotSupported is returned when starting a pipeline which cannot blur the privacy masks of the camera
+var errMasksNotSupported = errors.New("pipeline does not support privacy masks")
+
+// PrivacyMask is a named polygon region of a camera's view which must be blurred.
+type PrivacyMask struct {
+	Name   string  `json:"name"`
+	Points []Point `json:"points"`
+}
+
+// Validate checks that the mask describes a valid polygon.
+func (m PrivacyMask) Validate() error {
+	if m.Name == "" {
+		return errors.New("privacy mask name is required")
+	}
+	if err := validatePolygon(m.Points); err != nil {
+		return errors.Wrapf(err, "invalid privacy mask %s", m.Name)
+	}
+	return nil
+}
+
+// privacyMaskStore keeps the privacy masks for each camera, and persists them to a json file.
+type privacyMaskStore struct {
+	filename string
+	masks    map[string][]PrivacyMask
+	mutex    sync.RWMutex
+}
+
+func newPrivacyMaskStore(filename string) *privacyMaskStore {
+	return &privacyMaskStore{
+		filename: filename,
+		masks:    make(map[string][]PrivacyMask),
+	}
+}
+
+// load reads the persisted masks from disk. A missing file is not an error.
+func (s *privacyMaskStore) load() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return readJSONFile(s.filename, &s.masks)
+}
+
+// save writes the masks to disk. The caller must hold the lock.
+func (s *privacyMaskStore) save() error {
+	return writeJSONFile(s.filename, s.masks)
+}
+
+// get returns a copy of the masks for the camera.
+func (s *privacyMaskStore) get(camera string) []PrivacyMask {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	masks := make([]PrivacyMask, len(s.masks[camera]))
+	copy(masks, s.masks[camera])
+	return masks
+}
+
+// put adds the mask to the camera, replacing any existing mask with the same name.
+func (s *privacyMaskStore) put(camera string, mask PrivacyMask) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	masks := s.masks[camera]
+	replaced := false
+	for i := range masks {
+		if masks[i].Name == mask.Name {
+			masks[i] = mask
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		masks = append(masks, mask)
+	}
+	s.masks[camera] = masks
+	return s.save()
+}
+
+// delete removes the named mask from the camera.
+func (s *privacyMaskStore) delete(camera string, name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	masks := s.masks[camera]
+	for i := range masks {
+		if masks[i].Name == name {
+			masks = append(masks[:i], masks[i+1:]...)
+			if len(masks) == 0 {
+				delete(s.masks, camera)
+			} else {
+				s.masks[camera] = masks
+			}
+			return s.save()
+		}
+	}
+	return errors.Errorf("privacy mask %s not found for camera %s", name, camera)
+}
+
+// privacyMaskParameters returns the pipeline parameters passing the masks to the pipeline. A pipeline
+// which does not declare the privacy masks parameter is rejected, rather than streaming the camera unmasked.
+func privacyMaskParameters(pipeline CatalogPipeline, masks []PrivacyMask) (map[string]interface{}, error) {
+	if len(masks) == 0 {
+		return nil, nil
+	}
+	if param, found := pipeline.Parameters[privacyMasksParameter]; !found || param.Type != "object" {
+		return nil, errors.Wrapf(errMasksNotSupported, "%s/%s does not declare the %s parameter",
+			pipeline.Name, pipeline.Version, privacyMasksParameter)
+	}
+	return map[string]interface{}{
+		privacyMasksParameter: map[string]interface{}{"masks": masks},
+	}, nil
+}
+
+func (app *CameraManagementApp) putPrivacyMask(camera string, mask PrivacyMask) error {
+	if err := app.privacyMasks.put(camera, mask); err != nil {
+		return err
+	}
+	app.warnMasksNotApplied(camera)
+	return nil
+}
+
+func (app *CameraManagementApp) deletePrivacyMask(camera string, name string) error {
+	if err := app.privacyMasks.delete(camera, name); err != nil {
+		return err
+	}
+	app.warnMasksNotApplied(camera)
+	return nil
+}
+
+// warnMasksNotApplied lets the user know that the running pipeline still uses the old masks, as the
+// masks are only passed to EVAM when a pipeline is started.
+func (app *CameraManagementApp) warnMasksNotApplied(camera string) {
+	if app.isPipelineRunning(camera) {
+		app.lc.Warnf("Privacy masks for camera %s changed while a pipeline is running. "+
+			"The pipeline must be restarted for the changes to take effect.", camera)
+	}
+}
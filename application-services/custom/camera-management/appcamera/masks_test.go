@@ -0,0 +1,142 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const privacyPipelineFile = "../edge-video-analytics/pipelines-extra/object_detection/privacy/pipeline.json"
+
+// loadPipelineDefinition reads a pipeline definition, both as the catalog sees it and as its raw JSON schema
+func loadPipelineDefinition(t *testing.T, file string) (CatalogPipeline, map[string]interface{}) {
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+
+	var p evamPipeline
+	require.NoError(t, json.Unmarshal(data, &p))
+	var raw struct {
+		Parameters map[string]interface{} `json:"parameters"`
+	}
+	require.NoError(t, json.Unmarshal(data, &raw))
+
+	dir := filepath.Dir(file)
+	return CatalogPipeline{
+		Name:       filepath.Base(filepath.Dir(dir)),
+		Version:    filepath.Base(dir),
+		Type:       p.Type,
+		Parameters: p.Parameters.Properties,
+	}, raw.Parameters
+}
+
+// validateSchema checks the value against the subset of JSON schema used by the pipeline definitions
+func validateSchema(schema map[string]interface{}, value interface{}, path string) error {
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected object, got %T", path, value)
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for key, v := range obj {
+			propSchema, declared := properties[key].(map[string]interface{})
+			if !declared {
+				return fmt.Errorf("%s: property %s is not declared", path, key)
+			}
+			if err := validateSchema(propSchema, v, path+"."+key); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected array, got %T", path, value)
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		for i, v := range arr {
+			if err := validateSchema(items, v, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s: expected string, got %T", path, value)
+		}
+	case "number":
+		n, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("%s: expected number, got %T", path, value)
+		}
+		if min, ok := schema["minimum"].(float64); ok && n < min {
+			return fmt.Errorf("%s: %v is below the minimum %v", path, n, min)
+		}
+		if max, ok := schema["maximum"].(float64); ok && n > max {
+			return fmt.Errorf("%s: %v is above the maximum %v", path, n, max)
+		}
+	}
+	return nil
+}
+
+func TestPrivacyMaskParameters(t *testing.T) {
+	pipeline, schema := loadPipelineDefinition(t, privacyPipelineFile)
+	require.Equal(t, "object_detection", pipeline.Name)
+	require.Equal(t, "privacy", pipeline.Version)
+
+	masks := []PrivacyMask{
+		{Name: "sidewalk", Points: []Point{{X: 0, Y: 0.8}, {X: 1, Y: 0.8}, {X: 1, Y: 1}, {X: 0, Y: 1}}},
+		{Name: "window", Points: []Point{{X: 0.1, Y: 0.1}, {X: 0.3, Y: 0.1}, {X: 0.2, Y: 0.3}}},
+	}
+
+	noMaskParam := pipeline
+	noMaskParam.Parameters = map[string]PipelineParameter{"detection-device": pipeline.Parameters["detection-device"]}
+
+	tests := []struct {
+		name        string
+		pipeline    CatalogPipeline
+		masks       []PrivacyMask
+		expectError bool
+	}{
+		{"masked camera", pipeline, masks, false},
+		{"no masks", pipeline, nil, false},
+		{"no masks, pipeline without parameter", noMaskParam, nil, false},
+		{"masked camera, pipeline without parameter", noMaskParam, masks, true},
+		{"masked camera, unknown pipeline", CatalogPipeline{Name: "object_detection", Version: "unknown"}, masks, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			params, err := privacyMaskParameters(test.pipeline, test.masks)
+			if test.expectError {
+				require.Error(t, err)
+				assert.ErrorIs(t, err, errMasksNotSupported)
+				return
+			}
+			require.NoError(t, err)
+
+			request, err := json.Marshal(PipelineRequest{Parameters: params})
+			require.NoError(t, err)
+			var decoded struct {
+				Parameters map[string]interface{} `json:"parameters"`
+			}
+			require.NoError(t, json.Unmarshal(request, &decoded))
+			if len(test.masks) == 0 {
+				assert.Empty(t, decoded.Parameters)
+				return
+			}
+
+			// EVAM rejects parameters which are not declared by the pipeline
+			require.NoError(t, validateSchema(schema, decoded.Parameters, "parameters"))
+			masksParam := decoded.Parameters[privacyMasksParameter].(map[string]interface{})
+			assert.Len(t, masksParam["masks"], len(test.masks))
+		})
+	}
+}
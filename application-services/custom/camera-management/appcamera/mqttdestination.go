@@ -0,0 +1,111 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// pipelinePlaceholder and versionPlaceholder are replaced by the name and version of the pipeline
+	// in the MqttTopic and MqttDestination ClientId templates, along with the cameraPlaceholder
+	pipelinePlaceholder = "{pipeline}"
+	versionPlaceholder  = "{version}"
+
+	mqttSingleLevelWildcard = "+"
+)
+
+// mqttTopicPlaceholders are the placeholders supported by the MqttTopic template
+var mqttTopicPlaceholders = []string{cameraPlaceholder, pipelinePlaceholder, versionPlaceholder}
+
+// validateMqttTopic checks that the placeholders of the MqttTopic template occupy whole topic levels,
+// so that the inference events of all the cameras can be subscribed to with wildcards.
+func validateMqttTopic(topic string) error {
+	if topic == "" {
+		return errors.New("MqttTopic must be set")
+	}
+	for _, level := range strings.Split(topic, "/") {
+		for _, placeholder := range mqttTopicPlaceholders {
+			if strings.Contains(level, placeholder) && level != placeholder {
+				return errors.Errorf("invalid MqttTopic '%s', %s must be a whole topic level", topic, placeholder)
+			}
+		}
+		if strings.ContainsAny(level, "+#") {
+			return errors.Errorf("invalid MqttTopic '%s', wildcards are not allowed", topic)
+		}
+	}
+	return nil
+}
+
+// validateMqttDestination checks the MqttDestination configuration.
+func validateMqttDestination(cfg MqttDestinationConfig) error {
+	if (cfg.ClientCert == "") != (cfg.ClientKey == "") {
+		return errors.New("both MqttDestination ClientCert and ClientKey must be set")
+	}
+	if cfg.ClientCert != "" && cfg.CaCert == "" {
+		return errors.New("MqttDestination CaCert must be set along with the ClientCert")
+	}
+	return nil
+}
+
+// mqttTopicLevel makes the value usable as a single topic level, replacing the level separator and wildcards.
+func mqttTopicLevel(value string) string {
+	return strings.NewReplacer("/", "_", "+", "_", "#", "_").Replace(value)
+}
+
+// expandMqttTemplate replaces the placeholders of an MqttTopic or ClientId template.
+func expandMqttTemplate(template string, deviceName string, pipelineName string, pipelineVersion string) string {
+	return strings.NewReplacer(
+		cameraPlaceholder, mqttTopicLevel(deviceName),
+		pipelinePlaceholder, mqttTopicLevel(pipelineName),
+		versionPlaceholder, mqttTopicLevel(pipelineVersion),
+	).Replace(template)
+}
+
+// inferenceSubscriptionTopic returns the topic matching the inference events of all the cameras.
+func (app *CameraManagementApp) inferenceSubscriptionTopic() string {
+	topic := app.appConfig().MqttTopic
+	for _, placeholder := range mqttTopicPlaceholders {
+		topic = strings.ReplaceAll(topic, placeholder, mqttSingleLevelWildcard)
+	}
+	return topic
+}
+
+// metadataDestination returns the destination of the inference events of the camera's pipeline, including the
+// credentials of the broker when a secret is configured.
+func (app *CameraManagementApp) metadataDestination(deviceName string, pipelineName string, pipelineVersion string) (Metadata, error) {
+	appCfg := app.appConfig()
+	cfg := appCfg.MqttDestination
+	metadata := Metadata{
+		Type:  "mqtt",
+		Host:  appCfg.MqttAddress,
+		Topic: expandMqttTemplate(appCfg.MqttTopic, deviceName, pipelineName, pipelineVersion),
+	}
+	if cfg.ClientId != "" {
+		metadata.ClientId = expandMqttTemplate(cfg.ClientId, deviceName, pipelineName, pipelineVersion)
+	}
+
+	if cfg.SecretName != "" {
+		// the broker requires authentication, so the pipeline is not started without the credentials
+		creds, err := app.tryGetCredentials(cfg.SecretName)
+		if err != nil {
+			return Metadata{}, errors.Wrapf(err, "failed to get the %s secret of the metadata broker", cfg.SecretName)
+		}
+		metadata.Username = creds.Username
+		metadata.Password = creds.Password
+	}
+
+	if cfg.CaCert != "" {
+		metadata.Tls = &MetadataTls{
+			CaCert:     cfg.CaCert,
+			ClientCert: cfg.ClientCert,
+			ClientKey:  cfg.ClientKey,
+		}
+	}
+	return metadata, nil
+}
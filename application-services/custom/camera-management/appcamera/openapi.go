@@ -0,0 +1,90 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"context"
+	_ "embed"
+	"net/http"
+	"strings"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+const openApiPath = common.ApiBase + "/openapi.yaml"
+
+// openApiSpec is the OpenAPI 3 specification of the routes, which is served by the app and used to
+// validate the requests.
+//
+//go:embed openapi.yaml
+var openApiSpec []byte
+
+func loadOpenApiSpec() (*openapi3.T, error) {
+	// the error responses only need to say what is wrong, not dump the whole schema
+	openapi3.SchemaErrorDetailsDisabled = true
+
+	loader := openapi3.NewLoader()
+	spec, err := loader.LoadFromData(openApiSpec)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load the OpenAPI specification")
+	}
+	if err = spec.Validate(context.Background()); err != nil {
+		return nil, errors.Wrap(err, "invalid OpenAPI specification")
+	}
+	return spec, nil
+}
+
+// validateRequests wraps the route handler to validate the requests against the operation of the
+// OpenAPI specification. Every api route must be specified.
+func (app *CameraManagementApp) validateRequests(path, method string, f http.HandlerFunc) (http.HandlerFunc, error) {
+	if !strings.HasPrefix(path, common.ApiBase+"/") {
+		// web-ui
+		return f, nil
+	}
+
+	specPath := strings.TrimPrefix(path, common.ApiBase)
+	pathItem := app.openApiSpec.Paths.Find(specPath)
+	if pathItem == nil || pathItem.GetOperation(method) == nil {
+		return nil, errors.Errorf("route %s %s is missing from the OpenAPI specification", method, path)
+	}
+	route := &routers.Route{
+		Spec:      app.openApiSpec,
+		Path:      specPath,
+		PathItem:  pathItem,
+		Method:    method,
+		Operation: pathItem.GetOperation(method),
+	}
+	options := &openapi3filter.Options{
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		input := &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: mux.Vars(req),
+			Route:      route,
+			Options:    options,
+		}
+		if err := openapi3filter.ValidateRequest(req.Context(), input); err != nil {
+			respondErrorCode(app.lc, w, http.StatusBadRequest, InvalidRequest, err.Error(), nil)
+			return
+		}
+		f(w, req)
+	}, nil
+}
+
+func (app *CameraManagementApp) getOpenApiSpecRoute(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err := w.Write(openApiSpec); err != nil {
+		app.lc.Error(err.Error())
+	}
+}
@@ -0,0 +1,56 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	sdkMocks "github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces/mocks"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRoutesMatchOpenApiSpec checks that every api route, including the optional statistics routes, is specified,
+// and that every operation of the specification is served.
+func TestRoutesMatchOpenApiSpec(t *testing.T) {
+	spec, err := loadOpenApiSpec()
+	require.NoError(t, err)
+
+	var registered []string
+	service := &sdkMocks.ApplicationService{}
+	service.On("AddRoute", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		if path := args.String(0); strings.HasPrefix(path, common.ApiBase+"/") {
+			registered = append(registered, args.String(2)+" "+strings.TrimPrefix(path, common.ApiBase))
+		}
+	}).Return(nil)
+
+	app := newTestApp(validTestConfig())
+	app.service = service
+	app.statistics = &statisticsAggregator{}
+	require.NoError(t, app.addRoutes())
+
+	for _, route := range registered {
+		method, path, _ := strings.Cut(route, " ")
+		pathItem := spec.Paths.Find(path)
+		require.NotNil(t, pathItem, "route %s is missing from the OpenAPI specification", route)
+		assert.NotNil(t, pathItem.GetOperation(method), "route %s is missing from the OpenAPI specification", route)
+	}
+	assert.Contains(t, registered, "GET "+strings.TrimPrefix(statisticsPath, common.ApiBase))
+
+	var specified []string
+	for path, pathItem := range spec.Paths {
+		for method := range pathItem.Operations() {
+			specified = append(specified, method+" "+path)
+		}
+	}
+	sort.Strings(registered)
+	sort.Strings(specified)
+	assert.Equal(t, specified, registered, "every operation of the OpenAPI specification is served")
+}
@@ -0,0 +1,321 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	dtosCommon "github.com/edgexfoundry/go-mod-core-contracts/v3/dtos/common"
+	"github.com/pkg/errors"
+)
+
+const (
+	// PTZOperatorHeader identifies the operator sending a PTZ command, which is checked against the PTZ lock
+	PTZOperatorHeader = "X-PTZ-Operator"
+
+	defaultPTZMinInterval         = 200 * time.Millisecond
+	defaultPTZMaxQueueLength      = 10
+	defaultPTZMaxCoalescedMoves   = 5
+	defaultPTZDefaultLockDuration = time.Minute
+	defaultPTZMaxLockDuration     = 10 * time.Minute
+)
+
+var errPTZQueueFull = errors.New("too many PTZ commands are queued for the camera")
+
+// PTZLock gives an operator exclusive control of the PTZ of a camera until it expires
+type PTZLock struct {
+	Owner   string    `json:"owner"`
+	Expires time.Time `json:"expires"`
+}
+
+// PTZLockRequest is the body of the request acquiring or renewing a PTZ lock
+type PTZLockRequest struct {
+	Owner string `json:"owner"`
+	// Duration defaults to the configured DefaultLockDuration
+	Duration string `json:"duration,omitempty"`
+}
+
+// PTZLockedError is returned when the PTZ of a camera is locked by another operator
+type PTZLockedError struct {
+	Camera string
+	Lock   PTZLock
+}
+
+func (e *PTZLockedError) Error() string {
+	return fmt.Sprintf("the PTZ of camera %s is locked by %s until %s", e.Camera, e.Lock.Owner,
+		e.Lock.Expires.Format(time.RFC3339))
+}
+
+// ptzConfig holds the parsed values of the PTZConfig
+type ptzConfig struct {
+	minInterval         time.Duration
+	maxQueueLength      int
+	maxCoalescedMoves   int
+	defaultLockDuration time.Duration
+	maxLockDuration     time.Duration
+}
+
+func parsePTZConfig(cfg PTZConfig) (ptzConfig, error) {
+	parsed := ptzConfig{
+		minInterval:         defaultPTZMinInterval,
+		maxQueueLength:      defaultPTZMaxQueueLength,
+		maxCoalescedMoves:   defaultPTZMaxCoalescedMoves,
+		defaultLockDuration: defaultPTZDefaultLockDuration,
+		maxLockDuration:     defaultPTZMaxLockDuration,
+	}
+
+	var err error
+	if cfg.MinInterval != "" {
+		if parsed.minInterval, err = time.ParseDuration(cfg.MinInterval); err != nil || parsed.minInterval < 0 {
+			return parsed, errors.Errorf("invalid PTZ MinInterval '%s'", cfg.MinInterval)
+		}
+	}
+	if cfg.MaxQueueLength != 0 {
+		if cfg.MaxQueueLength < 0 {
+			return parsed, errors.Errorf("invalid PTZ MaxQueueLength %d", cfg.MaxQueueLength)
+		}
+		parsed.maxQueueLength = cfg.MaxQueueLength
+	}
+	if cfg.MaxCoalescedMoves != 0 {
+		if cfg.MaxCoalescedMoves < 0 {
+			return parsed, errors.Errorf("invalid PTZ MaxCoalescedMoves %d", cfg.MaxCoalescedMoves)
+		}
+		parsed.maxCoalescedMoves = cfg.MaxCoalescedMoves
+	}
+	if cfg.MaxLockDuration != "" {
+		if parsed.maxLockDuration, err = time.ParseDuration(cfg.MaxLockDuration); err != nil || parsed.maxLockDuration <= 0 {
+			return parsed, errors.Errorf("invalid PTZ MaxLockDuration '%s'", cfg.MaxLockDuration)
+		}
+	}
+	if cfg.DefaultLockDuration != "" {
+		if parsed.defaultLockDuration, err = time.ParseDuration(cfg.DefaultLockDuration); err != nil ||
+			parsed.defaultLockDuration <= 0 || parsed.defaultLockDuration > parsed.maxLockDuration {
+			return parsed, errors.Errorf("invalid PTZ DefaultLockDuration '%s', must not exceed the MaxLockDuration",
+				cfg.DefaultLockDuration)
+		}
+	}
+	return parsed, nil
+}
+
+// ptzCommand is a relative move, or a preset to go to, waiting in the queue of a camera
+type ptzCommand struct {
+	profile string
+	// preset is only set for preset commands
+	preset  string
+	x, y, z float64
+	// moves is the number of relative moves coalesced into this command
+	moves   int
+	waiters []chan ptzResult
+}
+
+type ptzResult struct {
+	res dtosCommon.BaseResponse
+	err error
+}
+
+// ptzQueue holds the commands waiting to be sent to a camera, and its lock
+type ptzQueue struct {
+	pending  []*ptzCommand
+	running  bool
+	lastSent time.Time
+	lock     *PTZLock
+}
+
+// ptzController serializes the PTZ commands sent to each camera, so that cameras receive at most one
+// command at a time, and no more often than the configured MinInterval. Relative moves queued while
+// waiting are coalesced into a single move.
+type ptzController struct {
+	app    *CameraManagementApp
+	cfg    ptzConfig
+	mutex  sync.Mutex
+	queues map[string]*ptzQueue
+}
+
+func newPTZController(app *CameraManagementApp, cfg ptzConfig) *ptzController {
+	return &ptzController{
+		app:    app,
+		cfg:    cfg,
+		queues: make(map[string]*ptzQueue),
+	}
+}
+
+// setConfig applies an updated configuration to the commands queued from now on.
+func (c *ptzController) setConfig(cfg ptzConfig) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.cfg = cfg
+}
+
+// queue returns the queue of the camera, creating it if needed. The mutex must be held.
+func (c *ptzController) queue(camera string) *ptzQueue {
+	q, found := c.queues[camera]
+	if !found {
+		q = &ptzQueue{}
+		c.queues[camera] = q
+	}
+	return q
+}
+
+// checkLock returns a PTZLockedError if the camera is locked by another operator. The mutex must be held.
+func (c *ptzController) checkLock(camera string, q *ptzQueue, operator string) error {
+	if q.lock != nil && time.Now().Before(q.lock.Expires) && q.lock.Owner != operator {
+		return &PTZLockedError{Camera: camera, Lock: *q.lock}
+	}
+	return nil
+}
+
+// move queues a relative move of the camera, and waits for it to be sent.
+func (c *ptzController) move(ctx context.Context, camera, operator, profile string, x, y, zoom float64) (dtosCommon.BaseResponse, error) {
+	return c.submit(ctx, camera, operator, &ptzCommand{profile: profile, x: x, y: y, z: zoom, moves: 1})
+}
+
+// gotoPreset queues a move of the camera to a preset, and waits for it to be sent.
+func (c *ptzController) gotoPreset(ctx context.Context, camera, operator, profile, preset string) (dtosCommon.BaseResponse, error) {
+	return c.submit(ctx, camera, operator, &ptzCommand{profile: profile, preset: preset})
+}
+
+func (c *ptzController) submit(ctx context.Context, camera string, operator string, cmd *ptzCommand) (dtosCommon.BaseResponse, error) {
+	waiter := make(chan ptzResult, 1)
+
+	c.mutex.Lock()
+	q := c.queue(camera)
+	if err := c.checkLock(camera, q, operator); err != nil {
+		c.mutex.Unlock()
+		return dtosCommon.BaseResponse{}, err
+	}
+
+	coalesced := false
+	if n := len(q.pending); n > 0 && cmd.preset == "" {
+		last := q.pending[n-1]
+		if last.preset == "" && last.profile == cmd.profile && last.moves < c.cfg.maxCoalescedMoves {
+			last.x += cmd.x
+			last.y += cmd.y
+			last.z += cmd.z
+			last.moves++
+			last.waiters = append(last.waiters, waiter)
+			coalesced = true
+		}
+	}
+	if !coalesced {
+		if len(q.pending) >= c.cfg.maxQueueLength {
+			c.mutex.Unlock()
+			return dtosCommon.BaseResponse{}, errPTZQueueFull
+		}
+		cmd.waiters = []chan ptzResult{waiter}
+		q.pending = append(q.pending, cmd)
+	}
+	if !q.running {
+		q.running = true
+		go c.run(camera, q)
+	}
+	c.mutex.Unlock()
+
+	select {
+	case result := <-waiter:
+		return result.res, result.err
+	case <-ctx.Done():
+		// the command is still sent, as it may have been coalesced with the commands of other requests
+		return dtosCommon.BaseResponse{}, ctx.Err()
+	}
+}
+
+// run sends the queued commands of the camera until the queue is empty.
+func (c *ptzController) run(camera string, q *ptzQueue) {
+	for {
+		c.mutex.Lock()
+		if len(q.pending) == 0 {
+			q.running = false
+			c.mutex.Unlock()
+			return
+		}
+		wait := c.cfg.minInterval - time.Since(q.lastSent)
+		c.mutex.Unlock()
+
+		if wait > 0 {
+			// moves queued in the meantime are coalesced with the pending ones
+			time.Sleep(wait)
+		}
+
+		c.mutex.Lock()
+		cmd := q.pending[0]
+		q.pending = q.pending[1:]
+		c.mutex.Unlock()
+
+		var result ptzResult
+		if cmd.preset != "" {
+			result.res, result.err = c.app.gotoPreset(camera, cmd.profile, cmd.preset)
+		} else {
+			if cmd.moves > 1 {
+				c.app.lc.Debugf("Coalesced %d PTZ moves of camera %s", cmd.moves, camera)
+			}
+			result.res, result.err = c.app.doPTZ(camera, cmd.profile, cmd.x, cmd.y, cmd.z)
+		}
+
+		c.mutex.Lock()
+		q.lastSent = time.Now()
+		c.mutex.Unlock()
+
+		for _, waiter := range cmd.waiters {
+			waiter <- result
+		}
+	}
+}
+
+// getLock returns the current PTZ lock of the camera, or nil if it is not locked.
+func (c *ptzController) getLock(camera string) *PTZLock {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	q := c.queue(camera)
+	if q.lock == nil || !time.Now().Before(q.lock.Expires) {
+		return nil
+	}
+	lock := *q.lock
+	return &lock
+}
+
+// acquireLock locks the PTZ of the camera for the owner, or renews the lock if the owner already holds it.
+func (c *ptzController) acquireLock(camera string, req PTZLockRequest) (PTZLock, error) {
+	c.mutex.Lock()
+	cfg := c.cfg
+	c.mutex.Unlock()
+
+	duration := cfg.defaultLockDuration
+	if req.Duration != "" {
+		var err error
+		if duration, err = time.ParseDuration(req.Duration); err != nil || duration <= 0 {
+			return PTZLock{}, errors.Errorf("invalid duration '%s'", req.Duration)
+		}
+	}
+	if duration > cfg.maxLockDuration {
+		return PTZLock{}, errors.Errorf("duration %v exceeds the maximum of %v", duration, cfg.maxLockDuration)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	q := c.queue(camera)
+	if err := c.checkLock(camera, q, req.Owner); err != nil {
+		return PTZLock{}, err
+	}
+	q.lock = &PTZLock{Owner: req.Owner, Expires: time.Now().Add(duration)}
+	return *q.lock, nil
+}
+
+// releaseLock unlocks the PTZ of the camera. Only the owner can release the lock, unless it is forced.
+func (c *ptzController) releaseLock(camera string, owner string, force bool) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	q := c.queue(camera)
+	if !force {
+		if err := c.checkLock(camera, q, owner); err != nil {
+			return err
+		}
+	}
+	q.lock = nil
+	return nil
+}
@@ -0,0 +1,222 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IOTechSystems/onvif/ptz"
+	sdkMocks "github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces/mocks"
+	clientMocks "github.com/edgexfoundry/go-mod-core-contracts/v3/clients/interfaces/mocks"
+	dtosCommon "github.com/edgexfoundry/go-mod-core-contracts/v3/dtos/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// sentPTZCommand is a PTZ command received by the fake command client
+type sentPTZCommand struct {
+	command string
+	x       float64
+	at      time.Time
+}
+
+// fakePTZCommands records the PTZ commands sent to the cameras, which block until release is closed when it is set
+type fakePTZCommands struct {
+	release chan struct{}
+	mutex   sync.Mutex
+	sent    []sentPTZCommand
+}
+
+func (f *fakePTZCommands) commands() []sentPTZCommand {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]sentPTZCommand(nil), f.sent...)
+}
+
+// newPTZTestController returns a controller whose commands are sent to the fake
+func newPTZTestController(cfg ptzConfig, fake *fakePTZCommands) *ptzController {
+	commandClient := &clientMocks.CommandClient{}
+	commandClient.On("IssueSetCommandByNameWithObject", mock.Anything, "camera", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			sent := sentPTZCommand{command: args.String(2), at: time.Now()}
+			if move, ok := args.Get(3).(map[string]interface{})[relativeMoveCommand].(*ptz.RelativeMove); ok {
+				sent.x = move.Translation.PanTilt.X
+			}
+			fake.mutex.Lock()
+			fake.sent = append(fake.sent, sent)
+			fake.mutex.Unlock()
+			if fake.release != nil {
+				<-fake.release
+			}
+		}).Return(dtosCommon.BaseResponse{StatusCode: 200}, nil)
+	service := &sdkMocks.ApplicationService{}
+	service.On("CommandClient").Return(commandClient)
+
+	app := newTestApp(validTestConfig())
+	app.service = service
+	return newPTZController(app, cfg)
+}
+
+// pendingMoves returns the number of moves waiting in each queued command of the camera
+func pendingMoves(c *ptzController) []int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var moves []int
+	for _, cmd := range c.queue("camera").pending {
+		moves = append(moves, len(cmd.waiters))
+	}
+	return moves
+}
+
+func TestPTZCoalescesMoves(t *testing.T) {
+	fake := &fakePTZCommands{release: make(chan struct{})}
+	c := newPTZTestController(ptzConfig{maxQueueLength: 10, maxCoalescedMoves: 3}, fake)
+
+	var wg sync.WaitGroup
+	move := func() {
+		defer wg.Done()
+		_, err := c.move(context.Background(), "camera", "", "profile", 0.1, 0, 0)
+		assert.NoError(t, err)
+	}
+
+	// the first move is sent, the next ones wait for it
+	wg.Add(1)
+	go move()
+	require.Eventually(t, func() bool { return len(fake.commands()) == 1 }, time.Second, time.Millisecond)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go move()
+	}
+	require.Eventually(t, func() bool {
+		moves := pendingMoves(c)
+		return len(moves) == 2 && moves[0]+moves[1] == 4
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []int{3, 1}, pendingMoves(c), "at most maxCoalescedMoves are coalesced")
+
+	close(fake.release)
+	wg.Wait()
+	sent := fake.commands()
+	require.Len(t, sent, 3)
+	assert.InDelta(t, 0.1, sent[0].x, 1e-9)
+	assert.InDelta(t, 0.3, sent[1].x, 1e-9)
+	assert.InDelta(t, 0.1, sent[2].x, 1e-9)
+}
+
+func TestPTZPresetsAreNotCoalesced(t *testing.T) {
+	fake := &fakePTZCommands{release: make(chan struct{})}
+	c := newPTZTestController(ptzConfig{maxQueueLength: 10, maxCoalescedMoves: 5}, fake)
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		_, _ = c.move(context.Background(), "camera", "", "profile", 0.1, 0, 0)
+	}()
+	require.Eventually(t, func() bool { return len(fake.commands()) == 1 }, time.Second, time.Millisecond)
+	go func() {
+		defer wg.Done()
+		_, _ = c.gotoPreset(context.Background(), "camera", "", "profile", "home")
+	}()
+	require.Eventually(t, func() bool { return len(pendingMoves(c)) == 1 }, time.Second, time.Millisecond)
+	go func() {
+		defer wg.Done()
+		_, _ = c.move(context.Background(), "camera", "", "profile", 0.1, 0, 0)
+	}()
+	require.Eventually(t, func() bool { return len(pendingMoves(c)) == 2 }, time.Second, time.Millisecond)
+
+	close(fake.release)
+	wg.Wait()
+	var commands []string
+	for _, sent := range fake.commands() {
+		commands = append(commands, sent.command)
+	}
+	assert.Equal(t, []string{relativeMoveCommand, gotoPresetCommand, relativeMoveCommand}, commands)
+}
+
+func TestPTZQueueFull(t *testing.T) {
+	fake := &fakePTZCommands{release: make(chan struct{})}
+	c := newPTZTestController(ptzConfig{maxQueueLength: 1, maxCoalescedMoves: 5}, fake)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, _ = c.gotoPreset(context.Background(), "camera", "", "profile", "first")
+	}()
+	require.Eventually(t, func() bool { return len(fake.commands()) == 1 }, time.Second, time.Millisecond)
+	go func() {
+		defer wg.Done()
+		_, _ = c.gotoPreset(context.Background(), "camera", "", "profile", "second")
+	}()
+	require.Eventually(t, func() bool { return len(pendingMoves(c)) == 1 }, time.Second, time.Millisecond)
+
+	_, err := c.gotoPreset(context.Background(), "camera", "", "profile", "third")
+	assert.True(t, errors.Is(err, errPTZQueueFull))
+
+	close(fake.release)
+	wg.Wait()
+	assert.Len(t, fake.commands(), 2)
+}
+
+func TestPTZMinInterval(t *testing.T) {
+	fake := &fakePTZCommands{}
+	minInterval := 100 * time.Millisecond
+	c := newPTZTestController(ptzConfig{minInterval: minInterval, maxQueueLength: 10, maxCoalescedMoves: 5}, fake)
+
+	for i := 0; i < 3; i++ {
+		_, err := c.gotoPreset(context.Background(), "camera", "", "profile", "home")
+		require.NoError(t, err)
+	}
+	sent := fake.commands()
+	require.Len(t, sent, 3)
+	for i := 1; i < len(sent); i++ {
+		assert.GreaterOrEqual(t, sent[i].at.Sub(sent[i-1].at), minInterval)
+	}
+}
+
+func TestPTZLock(t *testing.T) {
+	fake := &fakePTZCommands{}
+	c := newPTZTestController(ptzConfig{maxQueueLength: 10, maxCoalescedMoves: 5,
+		defaultLockDuration: time.Minute, maxLockDuration: 10 * time.Minute}, fake)
+
+	lock, err := c.acquireLock("camera", PTZLockRequest{Owner: "alice"})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", lock.Owner)
+	assert.Equal(t, &lock, c.getLock("camera"))
+
+	// the commands of other operators are rejected
+	_, err = c.move(context.Background(), "camera", "bob", "profile", 0.1, 0, 0)
+	var locked *PTZLockedError
+	require.True(t, errors.As(err, &locked))
+	assert.Equal(t, "alice", locked.Lock.Owner)
+	_, err = c.move(context.Background(), "camera", "alice", "profile", 0.1, 0, 0)
+	require.NoError(t, err)
+
+	// the owner renews the lock, which the other operators cannot acquire
+	renewed, err := c.acquireLock("camera", PTZLockRequest{Owner: "alice", Duration: "5m"})
+	require.NoError(t, err)
+	assert.True(t, renewed.Expires.After(lock.Expires))
+	_, err = c.acquireLock("camera", PTZLockRequest{Owner: "bob"})
+	require.True(t, errors.As(err, &locked))
+	_, err = c.acquireLock("camera", PTZLockRequest{Owner: "alice", Duration: "1h"})
+	assert.Error(t, err, "the duration exceeds the MaxLockDuration")
+
+	// only the owner releases the lock, unless it is forced
+	require.True(t, errors.As(c.releaseLock("camera", "bob", false), &locked))
+	require.NoError(t, c.releaseLock("camera", "bob", true))
+	assert.Nil(t, c.getLock("camera"))
+
+	lock, err = c.acquireLock("camera", PTZLockRequest{Owner: "bob"})
+	require.NoError(t, err)
+	assert.Equal(t, "bob", lock.Owner)
+	require.NoError(t, c.releaseLock("camera", "bob", false))
+	assert.Nil(t, c.getLock("camera"))
+}
@@ -0,0 +1,197 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// customConfigSection is the name of the section holding the CustomConfig
+const customConfigSection = "AppCustom"
+
+// pipelineRestartAttempts is how many times a pipeline is started again after it is stopped to apply the
+// updated configuration
+const pipelineRestartAttempts = 3
+
+// pipelineRestartRetryInterval is the delay between two attempts to start a pipeline again
+var pipelineRestartRetryInterval = 2 * time.Second
+
+// validateCustomConfig checks the values which are parsed when they are used, so that an invalid
+// configuration is rejected at startup or when it is updated.
+func validateCustomConfig(cfg CustomConfig) error {
+	if _, err := parseShutdownPolicy(cfg.ShutdownPolicy); err != nil {
+		return err
+	}
+	if _, err := parseShutdownTimeout(cfg.ShutdownTimeout); err != nil {
+		return err
+	}
+	if err := validateStreamOutput(cfg.StreamOutput); err != nil {
+		return err
+	}
+	if err := validateMqttTopic(cfg.MqttTopic); err != nil {
+		return err
+	}
+	if err := validateMqttDestination(cfg.MqttDestination); err != nil {
+		return err
+	}
+	if err := validateStatisticsConfig(cfg); err != nil {
+		return err
+	}
+	if _, err := parsePTZConfig(cfg.PTZ); err != nil {
+		return err
+	}
+	if _, err := parseInventoryCacheTTL(cfg.Inventory.CacheTTL); err != nil {
+		return err
+	}
+	if _, err := parseCatalogRefreshInterval(cfg.Catalog.RefreshInterval); err != nil {
+		return err
+	}
+	if _, err := parseNetworkPreference(cfg.Catalog.NetworkPreference); err != nil {
+		return err
+	}
+	return nil
+}
+
+// pipelineDestinationChanged returns true if the pipelines must be restarted for the new configuration
+// to apply to them, as EVAM only reads their destinations when they are started.
+func pipelineDestinationChanged(previous CustomConfig, updated CustomConfig) bool {
+	return previous.MqttAddress != updated.MqttAddress ||
+		previous.MqttTopic != updated.MqttTopic ||
+		previous.MqttDestination != updated.MqttDestination ||
+		previous.StreamOutput.Protocol != updated.StreamOutput.Protocol
+}
+
+// onConfigChanged applies the updated custom configuration received from the Configuration Provider.
+func (app *CameraManagementApp) onConfigChanged(raw interface{}) {
+	updated, ok := raw.(*CustomConfig)
+	if !ok {
+		app.lc.Errorf("Unable to apply the custom configuration update: unexpected type %T", raw)
+		return
+	}
+	if err := validateCustomConfig(*updated); err != nil {
+		app.lc.Errorf("Rejected the custom configuration update, the current configuration is kept: %v", err)
+		return
+	}
+
+	app.configMutex.Lock()
+	previous := app.config.AppCustom
+	app.config.AppCustom = *updated
+	app.configMutex.Unlock()
+
+	if reflect.DeepEqual(previous, *updated) {
+		app.lc.Debug("The custom configuration did not change")
+		return
+	}
+	app.lc.Info("Applying the updated custom configuration")
+
+	// these are only read at startup
+	if !reflect.DeepEqual(previous.Coordination, updated.Coordination) ||
+		!reflect.DeepEqual(previous.Statistics, updated.Statistics) ||
+		previous.PrivacyMasksFile != updated.PrivacyMasksFile ||
+		(previous.Statistics.Enabled && previous.MqttTopic != updated.MqttTopic) {
+		app.lc.Warn("The Coordination, Statistics, PrivacyMasksFile and inference events subscription settings " +
+			"are only applied when the service is restarted")
+	}
+
+	// the configuration was validated above
+	ptzCfg, _ := parsePTZConfig(updated.PTZ)
+	app.ptz.setConfig(ptzCfg)
+	inventoryTTL, _ := parseInventoryCacheTTL(updated.Inventory.CacheTTL)
+	app.inventory.setTTL(inventoryTTL)
+	if previous.OnvifDeviceServiceName != updated.OnvifDeviceServiceName ||
+		previous.USBDeviceServiceName != updated.USBDeviceServiceName ||
+		previous.RTSPDeviceServiceName != updated.RTSPDeviceServiceName {
+		app.inventory.invalidateAll()
+	}
+	if previous.EvamBaseUrl != updated.EvamBaseUrl ||
+		!reflect.DeepEqual(previous.Catalog.NetworkPreference, updated.Catalog.NetworkPreference) {
+		app.catalog.invalidate()
+	}
+
+	// the rest only matters to the leader, which manages the pipelines. The other instances read the
+	// pipelines from EVAM when they are elected.
+	if !app.isLeader() {
+		return
+	}
+
+	if previous.EvamBaseUrl != updated.EvamBaseUrl {
+		app.lc.Infof("EVAM moved from %s to %s, querying its pipelines", previous.EvamBaseUrl, updated.EvamBaseUrl)
+		if err := app.queryAllPipelineStatuses(); err != nil {
+			app.lc.Errorf("Unable to query EVAM pipeline statuses. Is EVAM running? %s", err.Error())
+		}
+	} else if pipelineDestinationChanged(previous, *updated) {
+		if updated.RestartPipelinesOnChange {
+			app.restartPipelines()
+		} else {
+			app.lc.Warn("The destinations of the running pipelines only change once they are restarted, " +
+				"set RestartPipelinesOnChange to restart them automatically")
+		}
+	}
+
+	if previous.EvamBaseUrl != updated.EvamBaseUrl ||
+		previous.DefaultPipelineName != updated.DefaultPipelineName ||
+		previous.DefaultPipelineVersion != updated.DefaultPipelineVersion {
+		// only the cameras without pipelines are affected, running pipelines are not replaced
+		app.startDefaultPipelines()
+	}
+}
+
+// restartPipelines stops and starts every running pipeline with the same request, so that the current
+// configuration applies to them.
+func (app *CameraManagementApp) restartPipelines() {
+	app.pipelinesMutex.RLock()
+	pipelines := make(map[string]PipelineInfo, len(app.pipelinesMap))
+	for camera, info := range app.pipelinesMap {
+		pipelines[camera] = info
+	}
+	app.pipelinesMutex.RUnlock()
+
+	for camera, info := range pipelines {
+		if err := app.restartPipeline(camera, info); err != nil {
+			app.lc.Errorf("Failed to restart the pipeline of camera %s: %v", camera, err)
+			continue
+		}
+		app.lc.Infof("Restarted the pipeline of camera %s", camera)
+	}
+}
+
+func (app *CameraManagementApp) restartPipeline(camera string, info PipelineInfo) error {
+	device, adapter, err := app.getDeviceAdapter(camera)
+	if err != nil {
+		return err
+	}
+	if err = app.stopPipeline(camera, info.Id); err != nil {
+		return err
+	}
+	// starting the pipeline starts streaming again
+	if err = adapter.StopStreaming(device); err != nil {
+		return errors.Wrap(err, "failed to stop streaming")
+	}
+
+	start := func() error {
+		if info.request != nil {
+			return app.startPipeline(camera, *info.request)
+		}
+		// pipelines found in EVAM are restarted with the default stream configuration of the camera
+		return app.startPipelineTemplate(device, info.Name, info.Version)
+	}
+	// the previous pipeline is stopped, so the start is retried rather than leaving the camera without a pipeline
+	// on a transient failure
+	for attempt := 1; ; attempt++ {
+		if err = start(); err == nil {
+			return nil
+		}
+		if attempt == pipelineRestartAttempts {
+			return errors.Wrapf(err, "the camera is left without a pipeline after %d attempts to start it again", attempt)
+		}
+		app.lc.Warnf("Failed to start the pipeline of camera %s again, retrying in %v: %v",
+			camera, pipelineRestartRetryInterval, err)
+		time.Sleep(pipelineRestartRetryInterval)
+	}
+}
@@ -0,0 +1,260 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sdkMocks "github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces/mocks"
+	clientMocks "github.com/edgexfoundry/go-mod-core-contracts/v3/clients/interfaces/mocks"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos/responses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// validTestConfig returns a custom configuration passing validateCustomConfig
+func validTestConfig() CustomConfig {
+	return CustomConfig{
+		MqttAddress:     "edgex-mqtt-broker:1883",
+		MqttTopic:       "incoming/data/edge-video-analytics/inference-event",
+		ShutdownPolicy:  string(LeaveRunning),
+		ShutdownTimeout: "10s",
+		StreamOutput:    StreamOutputConfig{Protocol: "rtsp", RtspPort: 8555},
+		PTZ: PTZConfig{
+			MinInterval:         "200ms",
+			MaxQueueLength:      10,
+			MaxCoalescedMoves:   5,
+			DefaultLockDuration: "1m",
+			MaxLockDuration:     "10m",
+		},
+		Inventory: InventoryConfig{CacheTTL: "30s"},
+		Catalog:   CatalogConfig{RefreshInterval: "5m"},
+	}
+}
+
+// restartEvam fails the requests starting a pipeline until failures reaches zero
+type restartEvam struct {
+	mutex    sync.Mutex
+	failures int
+	started  int
+	stopped  []string
+}
+
+func (e *restartEvam) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	switch r.Method {
+	case http.MethodDelete:
+		e.stopped = append(e.stopped, strings.TrimPrefix(r.URL.Path, "/pipelines/"))
+	case http.MethodPost:
+		if e.failures > 0 {
+			e.failures--
+			http.Error(w, "pipeline failed to start", http.StatusInternalServerError)
+			return
+		}
+		e.started++
+		_, _ = w.Write([]byte(`"restarted"`))
+		return
+	}
+	_, _ = w.Write([]byte(`{}`))
+}
+
+// fakeAdapter counts the streaming calls of the cameras of the fake device service
+type fakeAdapter struct {
+	CameraAdapter
+	mutex   sync.Mutex
+	started int
+	stopped int
+}
+
+func (a *fakeAdapter) Type() CameraType            { return "fake" }
+func (a *fakeAdapter) ServiceName() string         { return "device-fake" }
+func (a *fakeAdapter) Supports(_ dtos.Device) bool { return false }
+func (a *fakeAdapter) SecretName() string          { return "" }
+
+func (a *fakeAdapter) StreamUri(_ dtos.Device, _ StartPipelineRequest) (string, error) {
+	return "rtsp://camera:8554/stream", nil
+}
+
+func (a *fakeAdapter) StartStreaming(_ dtos.Device, _ StartPipelineRequest) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.started++
+	return nil
+}
+
+func (a *fakeAdapter) StopStreaming(_ dtos.Device) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.stopped++
+	return nil
+}
+
+// newRestartTestApp returns an app with a running pipeline for the camera of the fake device service
+func newRestartTestApp(t *testing.T, cfg CustomConfig, evam *restartEvam) (*CameraManagementApp, *fakeAdapter) {
+	server := httptest.NewServer(evam)
+	t.Cleanup(server.Close)
+	cfg.EvamBaseUrl = server.URL
+
+	deviceClient := &clientMocks.DeviceClient{}
+	deviceClient.On("DeviceByName", mock.Anything, "camera").Return(
+		responses.DeviceResponse{Device: dtos.Device{Name: "camera", ServiceName: "device-fake"}}, nil)
+	service := &sdkMocks.ApplicationService{}
+	service.On("DeviceClient").Return(deviceClient)
+
+	app := newTestApp(cfg)
+	app.service = service
+	app.ptz = newPTZController(app, ptzConfig{})
+	app.inventory = newInventoryCache(time.Minute)
+	app.privacyMasks = newPrivacyMaskStore(filepath.Join(t.TempDir(), "privacy-masks.json"))
+	app.catalog.catalog = PipelineCatalog{Pipelines: []CatalogPipeline{{Name: "object_detection", Version: "person"}}}
+	app.catalog.loaded = true
+	adapter := &fakeAdapter{}
+	app.RegisterCameraAdapter(adapter)
+	app.pipelinesMap["camera"] = PipelineInfo{Id: "running", Name: "object_detection", Version: "person",
+		request: &StartPipelineRequest{PipelineName: "object_detection", PipelineVersion: "person"}}
+	return app, adapter
+}
+
+func TestValidateCustomConfig(t *testing.T) {
+	require.NoError(t, validateCustomConfig(validTestConfig()))
+
+	tests := []struct {
+		name   string
+		update func(cfg *CustomConfig)
+	}{
+		{"shutdown policy", func(cfg *CustomConfig) { cfg.ShutdownPolicy = "stop-everything" }},
+		{"shutdown timeout", func(cfg *CustomConfig) { cfg.ShutdownTimeout = "soon" }},
+		{"stream output", func(cfg *CustomConfig) { cfg.StreamOutput.Protocol = "hls" }},
+		{"mqtt topic", func(cfg *CustomConfig) { cfg.MqttTopic = "incoming/#" }},
+		{"mqtt destination", func(cfg *CustomConfig) { cfg.MqttDestination.ClientCert = "/certs/client.crt" }},
+		{"statistics", func(cfg *CustomConfig) {
+			cfg.Statistics = StatisticsConfig{Enabled: true, MqttBrokerUrl: "tcp://localhost:1883", BucketSize: "0s"}
+		}},
+		{"ptz", func(cfg *CustomConfig) { cfg.PTZ.MinInterval = "fast" }},
+		{"inventory", func(cfg *CustomConfig) { cfg.Inventory.CacheTTL = "forever" }},
+		{"catalog", func(cfg *CustomConfig) { cfg.Catalog.RefreshInterval = "often" }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := validTestConfig()
+			test.update(&cfg)
+			require.Error(t, validateCustomConfig(cfg))
+		})
+	}
+}
+
+func TestPipelineDestinationChanged(t *testing.T) {
+	tests := []struct {
+		name     string
+		update   func(cfg *CustomConfig)
+		expected bool
+	}{
+		{"unchanged", func(cfg *CustomConfig) {}, false},
+		{"mqtt address", func(cfg *CustomConfig) { cfg.MqttAddress = "other-broker:1883" }, true},
+		{"mqtt topic", func(cfg *CustomConfig) { cfg.MqttTopic = "inference/{camera}" }, true},
+		{"mqtt destination", func(cfg *CustomConfig) { cfg.MqttDestination.SecretName = "mqttAuth" }, true},
+		{"stream output protocol", func(cfg *CustomConfig) { cfg.StreamOutput.Protocol = "webrtc" }, true},
+		{"stream output host", func(cfg *CustomConfig) { cfg.StreamOutput.Host = "viewer" }, false},
+		{"default pipeline", func(cfg *CustomConfig) { cfg.DefaultPipelineName = "vehicle" }, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			updated := validTestConfig()
+			test.update(&updated)
+			assert.Equal(t, test.expected, pipelineDestinationChanged(validTestConfig(), updated))
+		})
+	}
+}
+
+func TestOnConfigChangedRejectsInvalidConfig(t *testing.T) {
+	app, _ := newRestartTestApp(t, validTestConfig(), &restartEvam{})
+	previous := app.appConfig()
+
+	invalid := validTestConfig()
+	invalid.PTZ.MinInterval = "fast"
+	app.onConfigChanged(&invalid)
+	app.onConfigChanged(validTestConfig())
+
+	assert.Equal(t, previous, app.appConfig())
+}
+
+func TestOnConfigChangedRestartsPipelines(t *testing.T) {
+	tests := []struct {
+		name          string
+		restart       bool
+		expectStopped []string
+	}{
+		{"restart", true, []string{"running"}},
+		{"keep running", false, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			evam := &restartEvam{}
+			app, _ := newRestartTestApp(t, validTestConfig(), evam)
+
+			updated := app.appConfig()
+			updated.MqttAddress = "other-broker:1883"
+			updated.RestartPipelinesOnChange = test.restart
+			app.onConfigChanged(&updated)
+
+			assert.Equal(t, "other-broker:1883", app.appConfig().MqttAddress)
+			assert.Equal(t, test.expectStopped, evam.stopped)
+			info, found := app.getPipelineInfo("camera")
+			require.True(t, found)
+			if test.restart {
+				assert.Equal(t, "restarted", info.Id)
+			} else {
+				assert.Equal(t, "running", info.Id)
+			}
+		})
+	}
+}
+
+func TestRestartPipelineRetries(t *testing.T) {
+	pipelineRestartRetryInterval = time.Millisecond
+	defer func() { pipelineRestartRetryInterval = 2 * time.Second }()
+
+	tests := []struct {
+		name        string
+		failures    int
+		expectError bool
+	}{
+		{"first attempt", 0, false},
+		{"after failures", pipelineRestartAttempts - 1, false},
+		{"every attempt fails", pipelineRestartAttempts, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			evam := &restartEvam{failures: test.failures}
+			app, adapter := newRestartTestApp(t, validTestConfig(), evam)
+			info, _ := app.getPipelineInfo("camera")
+
+			err := app.restartPipeline("camera", info)
+
+			assert.Equal(t, []string{"running"}, evam.stopped)
+			// streaming is stopped with the previous pipeline, and after every failed attempt
+			assert.Equal(t, test.failures+1, adapter.stopped)
+			if test.expectError {
+				require.Error(t, err)
+				assert.False(t, app.isPipelineRunning("camera"))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 1, evam.started)
+			assert.Equal(t, test.failures+1, adapter.started)
+			assert.True(t, app.isPipelineRunning("camera"))
+		})
+	}
+}
@@ -6,14 +6,16 @@
 package appcamera
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"path"
+	"time"
 
-	dtosCommon "github.com/edgexfoundry/go-mod-core-contracts/v3/dtos/common"
 	"github.com/gorilla/mux"
 
 	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	edgexErrors "github.com/edgexfoundry/go-mod-core-contracts/v3/errors"
 	"github.com/pkg/errors"
 )
 
@@ -26,26 +28,58 @@ const (
 	getCamerasPath = common.ApiBase + "/cameras"
 	cameraApiBase  = getCamerasPath + "/{name}"
 
+	inventoryPath       = common.ApiBase + "/inventory"
+	cameraInventoryPath = cameraApiBase + "/inventory"
+
 	getPipelinesPath        = common.ApiBase + "/pipelines"
 	allPipelineStatusesPath = getPipelinesPath + "/status/all"
+	catalogPath             = common.ApiBase + "/catalog"
 
+	pipelinePath       = cameraApiBase + "/pipeline"
 	startPipelinePath  = cameraApiBase + "/pipeline/start"
 	stopPipelinePath   = cameraApiBase + "/pipeline/stop/{id}"
 	pipelineStatusPath = cameraApiBase + "/pipeline/status"
 
-	imageFormatsPath = cameraApiBase + "/imageformats"
+	imageFormatsPath   = cameraApiBase + "/imageformats"
+	streamingModesPath = cameraApiBase + "/streamingmodes"
 
 	getProfilesPath      = cameraApiBase + "/profiles"
 	cameraProfileApiBase = getProfilesPath + "/{profile}"
 
 	featuresPath = cameraApiBase + "/features"
 
+	privacyMasksPath = cameraApiBase + "/privacymasks"
+	privacyMaskPath  = privacyMasksPath + "/{mask}"
+
+	zonesPath      = cameraApiBase + "/zones"
+	zonePath       = zonesPath + "/{zone}"
+	linesPath      = cameraApiBase + "/lines"
+	linePath       = linesPath + "/{line}"
+	statisticsPath = cameraApiBase + "/statistics"
+
+	defaultStatisticsRange = time.Hour
+
 	ptzPath        = cameraProfileApiBase + "/ptz/{action}"
 	getPresetsPath = cameraProfileApiBase + "/presets"
 	gotoPresetPath = cameraProfileApiBase + "/presets/{preset}"
+	ptzLockPath    = cameraApiBase + "/ptz/lock"
+
+	discoveryApiPath       = common.ApiBase + "/discovery"
+	provisionalDevicesPath = discoveryApiPath + "/devices"
+	approveDevicePath      = provisionalDevicesPath + "/{name}/approve"
 )
 
 func (app *CameraManagementApp) addRoutes() error {
+	var err error
+	if app.openApiSpec, err = loadOpenApiSpec(); err != nil {
+		return err
+	}
+
+	if err := app.addRoute(
+		openApiPath, http.MethodGet, app.getOpenApiSpecRoute); err != nil {
+		return err
+	}
+
 	if err := app.addRoute(
 		startPipelinePath, http.MethodPost, app.startPipelineRoute); err != nil {
 		return err
@@ -58,6 +92,11 @@ func (app *CameraManagementApp) addRoutes() error {
 		pipelineStatusPath, http.MethodGet, app.pipelineStatusRoute); err != nil {
 		return err
 	}
+
+	if err := app.addRoute(
+		pipelinePath, http.MethodGet, app.pipelineInfoStatusRoute); err != nil {
+		return err
+	}
 	if err := app.addRoute(
 		allPipelineStatusesPath, http.MethodGet, app.allPipelineStatusesRoute); err != nil {
 		return err
@@ -76,12 +115,56 @@ func (app *CameraManagementApp) addRoutes() error {
 		getPipelinesPath, http.MethodGet, app.getPipelinesRoute); err != nil {
 		return err
 	}
+	if err := app.addRoute(
+		catalogPath, http.MethodGet, app.getCatalogRoute); err != nil {
+		return err
+	}
 
 	if err := app.addRoute(
 		ptzPath, http.MethodPost, app.ptzRoute); err != nil {
 		return err
 	}
 
+	if err := app.addRoute(
+		ptzLockPath, http.MethodGet, app.getPTZLockRoute); err != nil {
+		return err
+	}
+
+	if err := app.addRoute(
+		ptzLockPath, http.MethodPut, app.acquirePTZLockRoute); err != nil {
+		return err
+	}
+
+	if err := app.addRoute(
+		ptzLockPath, http.MethodDelete, app.releasePTZLockRoute); err != nil {
+		return err
+	}
+
+	if err := app.addRoute(
+		inventoryPath, http.MethodGet, app.getInventoryRoute); err != nil {
+		return err
+	}
+
+	if err := app.addRoute(
+		cameraInventoryPath, http.MethodGet, app.getCameraInventoryRoute); err != nil {
+		return err
+	}
+
+	if err := app.addRoute(
+		discoveryApiPath, http.MethodPost, app.triggerDiscoveryRoute); err != nil {
+		return err
+	}
+
+	if err := app.addRoute(
+		provisionalDevicesPath, http.MethodGet, app.getProvisionalDevicesRoute); err != nil {
+		return err
+	}
+
+	if err := app.addRoute(
+		approveDevicePath, http.MethodPost, app.approveDeviceRoute); err != nil {
+		return err
+	}
+
 	if err := app.addRoute(
 		getPresetsPath, http.MethodGet, app.getPresetsRoute); err != nil {
 		return err
@@ -102,6 +185,32 @@ func (app *CameraManagementApp) addRoutes() error {
 		return err
 	}
 
+	if err := app.addRoute(
+		streamingModesPath, http.MethodGet, app.getStreamingModesRoute); err != nil {
+		return err
+	}
+
+	if err := app.addRoute(
+		privacyMasksPath, http.MethodGet, app.getPrivacyMasksRoute); err != nil {
+		return err
+	}
+
+	if err := app.addRoute(
+		privacyMaskPath, http.MethodPut, app.putPrivacyMaskRoute); err != nil {
+		return err
+	}
+
+	if err := app.addRoute(
+		privacyMaskPath, http.MethodDelete, app.deletePrivacyMaskRoute); err != nil {
+		return err
+	}
+
+	if app.statistics != nil {
+		if err := app.addStatisticsRoutes(); err != nil {
+			return err
+		}
+	}
+
 	app.fileServer = http.FileServer(http.Dir(webUIDistDir))
 	// this is a bit of a hack to get refreshing working, as the path is /home
 	if err := app.addRoute("/home", http.MethodGet, app.index); err != nil {
@@ -115,13 +224,77 @@ func (app *CameraManagementApp) addRoutes() error {
 	return nil
 }
 
+func (app *CameraManagementApp) addStatisticsRoutes() error {
+	if err := app.addRoute(
+		zonesPath, http.MethodGet, app.getZonesRoute); err != nil {
+		return err
+	}
+
+	if err := app.addRoute(
+		zonePath, http.MethodPut, app.putZoneRoute); err != nil {
+		return err
+	}
+
+	if err := app.addRoute(
+		zonePath, http.MethodDelete, app.deleteZoneRoute); err != nil {
+		return err
+	}
+
+	if err := app.addRoute(
+		linesPath, http.MethodGet, app.getLinesRoute); err != nil {
+		return err
+	}
+
+	if err := app.addRoute(
+		linePath, http.MethodPut, app.putLineRoute); err != nil {
+		return err
+	}
+
+	if err := app.addRoute(
+		linePath, http.MethodDelete, app.deleteLineRoute); err != nil {
+		return err
+	}
+
+	if err := app.addRoute(
+		statisticsPath, http.MethodGet, app.getStatisticsRoute); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (app *CameraManagementApp) addRoute(path, method string, f http.HandlerFunc) error {
-	if err := app.service.AddRoute(path, f, method); err != nil {
+	f, err := app.validateRequests(path, method, f)
+	if err != nil {
+		return err
+	}
+	if method != http.MethodGet {
+		f = app.leaderOnly(f)
+	}
+	if err = app.service.AddRoute(path, f, method); err != nil {
 		return errors.Wrapf(err, "failed to add route, path=%s, method=%s", path, method)
 	}
 	return nil
 }
 
+// leaderOnly rejects the requests made to an instance which is not the leader, as only read-only
+// routes can be served by any instance.
+func (app *CameraManagementApp) leaderOnly(f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if !app.isLeader() {
+			leader := app.getLeader()
+			if leader == "" {
+				leader = "unknown"
+			}
+			respondErrorCode(app.lc, w, http.StatusServiceUnavailable, NotLeader,
+				fmt.Sprintf("This instance is not the leader, send the request to the leader instead (leader: %s)", leader),
+				map[string]interface{}{"leader": leader})
+			return
+		}
+		f(w, req)
+	}
+}
+
 // Routes
 func (app *CameraManagementApp) index(w http.ResponseWriter, req *http.Request) {
 	http.ServeFile(w, req, path.Join(webUIDistDir, "index.html"))
@@ -174,6 +347,183 @@ func (app *CameraManagementApp) getImageFormatsRoute(w http.ResponseWriter, req
 	respondJson(app.lc, w, formats)
 }
 
+func (app *CameraManagementApp) getStreamingModesRoute(w http.ResponseWriter, req *http.Request) {
+	rv := mux.Vars(req)
+	deviceName := rv["name"]
+
+	modes, err := app.getUSBStreamingModes(deviceName)
+	if err != nil {
+		respondError(app.lc, w, http.StatusInternalServerError,
+			fmt.Sprintf("Failed to get streaming modes: %v", err))
+		return
+	}
+
+	respondJson(app.lc, w, modes)
+}
+
+func (app *CameraManagementApp) getPrivacyMasksRoute(w http.ResponseWriter, req *http.Request) {
+	rv := mux.Vars(req)
+	deviceName := rv["name"]
+
+	respondJson(app.lc, w, app.privacyMasks.get(deviceName))
+}
+
+func (app *CameraManagementApp) putPrivacyMaskRoute(w http.ResponseWriter, req *http.Request) {
+	rv := mux.Vars(req)
+	deviceName := rv["name"]
+
+	mask := PrivacyMask{}
+	if !extractJSONBody(app.lc, w, req, &mask) {
+		return
+	}
+	// the name in the path always takes precedence
+	mask.Name = rv["mask"]
+
+	if err := mask.Validate(); err != nil {
+		respondError(app.lc, w, http.StatusBadRequest, fmt.Sprintf("Invalid privacy mask: %v", err))
+		return
+	}
+
+	if err := app.putPrivacyMask(deviceName, mask); err != nil {
+		respondError(app.lc, w, http.StatusInternalServerError,
+			fmt.Sprintf("Failed to save privacy mask: %v", err))
+		return
+	}
+}
+
+func (app *CameraManagementApp) deletePrivacyMaskRoute(w http.ResponseWriter, req *http.Request) {
+	rv := mux.Vars(req)
+	deviceName := rv["name"]
+
+	if err := app.deletePrivacyMask(deviceName, rv["mask"]); err != nil {
+		respondError(app.lc, w, http.StatusNotFound,
+			fmt.Sprintf("Failed to delete privacy mask: %v", err))
+		return
+	}
+}
+
+func (app *CameraManagementApp) getZonesRoute(w http.ResponseWriter, req *http.Request) {
+	rv := mux.Vars(req)
+	deviceName := rv["name"]
+
+	respondJson(app.lc, w, app.statistics.getZones(deviceName))
+}
+
+func (app *CameraManagementApp) putZoneRoute(w http.ResponseWriter, req *http.Request) {
+	rv := mux.Vars(req)
+	deviceName := rv["name"]
+
+	zone := CountingZone{}
+	if !extractJSONBody(app.lc, w, req, &zone) {
+		return
+	}
+	// the name in the path always takes precedence
+	zone.Name = rv["zone"]
+
+	if err := zone.Validate(); err != nil {
+		respondError(app.lc, w, http.StatusBadRequest, fmt.Sprintf("Invalid zone: %v", err))
+		return
+	}
+
+	if err := app.statistics.putZone(deviceName, zone); err != nil {
+		respondError(app.lc, w, http.StatusInternalServerError,
+			fmt.Sprintf("Failed to save zone: %v", err))
+		return
+	}
+}
+
+func (app *CameraManagementApp) deleteZoneRoute(w http.ResponseWriter, req *http.Request) {
+	rv := mux.Vars(req)
+	deviceName := rv["name"]
+
+	if err := app.statistics.deleteZone(deviceName, rv["zone"]); err != nil {
+		respondError(app.lc, w, http.StatusNotFound,
+			fmt.Sprintf("Failed to delete zone: %v", err))
+		return
+	}
+}
+
+func (app *CameraManagementApp) getLinesRoute(w http.ResponseWriter, req *http.Request) {
+	rv := mux.Vars(req)
+	deviceName := rv["name"]
+
+	respondJson(app.lc, w, app.statistics.getLines(deviceName))
+}
+
+func (app *CameraManagementApp) putLineRoute(w http.ResponseWriter, req *http.Request) {
+	rv := mux.Vars(req)
+	deviceName := rv["name"]
+
+	line := CountingLine{}
+	if !extractJSONBody(app.lc, w, req, &line) {
+		return
+	}
+	// the name in the path always takes precedence
+	line.Name = rv["line"]
+
+	if err := line.Validate(); err != nil {
+		respondError(app.lc, w, http.StatusBadRequest, fmt.Sprintf("Invalid line: %v", err))
+		return
+	}
+
+	if err := app.statistics.putLine(deviceName, line); err != nil {
+		respondError(app.lc, w, http.StatusInternalServerError,
+			fmt.Sprintf("Failed to save line: %v", err))
+		return
+	}
+}
+
+func (app *CameraManagementApp) deleteLineRoute(w http.ResponseWriter, req *http.Request) {
+	rv := mux.Vars(req)
+	deviceName := rv["name"]
+
+	if err := app.statistics.deleteLine(deviceName, rv["line"]); err != nil {
+		respondError(app.lc, w, http.StatusNotFound,
+			fmt.Sprintf("Failed to delete line: %v", err))
+		return
+	}
+}
+
+// getStatisticsRoute returns the detection statistics of a camera. The optional query parameters are
+// start and end (RFC3339 timestamps, defaulting to the last hour), bucket (a duration, defaulting to the
+// configured BucketSize), zone and label.
+func (app *CameraManagementApp) getStatisticsRoute(w http.ResponseWriter, req *http.Request) {
+	rv := mux.Vars(req)
+	deviceName := rv["name"]
+	query := req.URL.Query()
+
+	var err error
+	end := time.Now()
+	if value := query.Get("end"); value != "" {
+		if end, err = time.Parse(time.RFC3339, value); err != nil {
+			respondError(app.lc, w, http.StatusBadRequest, fmt.Sprintf("Invalid end: %v", err))
+			return
+		}
+	}
+	start := end.Add(-defaultStatisticsRange)
+	if value := query.Get("start"); value != "" {
+		if start, err = time.Parse(time.RFC3339, value); err != nil {
+			respondError(app.lc, w, http.StatusBadRequest, fmt.Sprintf("Invalid start: %v", err))
+			return
+		}
+	}
+	bucketSize := app.statistics.bucketSize
+	if value := query.Get("bucket"); value != "" {
+		if bucketSize, err = time.ParseDuration(value); err != nil {
+			respondError(app.lc, w, http.StatusBadRequest, fmt.Sprintf("Invalid bucket: %v", err))
+			return
+		}
+	}
+
+	buckets, err := app.statistics.query(deviceName, query.Get("zone"), query.Get("label"), start, end, bucketSize)
+	if err != nil {
+		respondError(app.lc, w, http.StatusBadRequest, fmt.Sprintf("Failed to query statistics: %v", err))
+		return
+	}
+
+	respondJson(app.lc, w, buckets)
+}
+
 func (app *CameraManagementApp) getProfilesRoute(w http.ResponseWriter, req *http.Request) {
 	rv := mux.Vars(req)
 	deviceName := rv["name"]
@@ -198,15 +548,53 @@ func (app *CameraManagementApp) getCamerasRoute(w http.ResponseWriter, _ *http.R
 	respondJson(app.lc, w, devices)
 }
 
-func (app *CameraManagementApp) getPipelinesRoute(w http.ResponseWriter, _ *http.Request) {
-	pl, err := app.getPipelines()
+func (app *CameraManagementApp) getInventoryRoute(w http.ResponseWriter, req *http.Request) {
+	items, err := app.getInventory(req.Context(), req.URL.Query().Get("refresh") == "true")
+	if err != nil {
+		respondError(app.lc, w, http.StatusInternalServerError,
+			fmt.Sprintf("Failed to get camera inventory: %v", err))
+		return
+	}
+	respondJson(app.lc, w, items)
+}
+
+func (app *CameraManagementApp) getCameraInventoryRoute(w http.ResponseWriter, req *http.Request) {
+	deviceName := mux.Vars(req)["name"]
+
+	item, err := app.getCameraInventory(req.Context(), deviceName, req.URL.Query().Get("refresh") == "true")
+	if err != nil {
+		var edgexErr edgexErrors.EdgeX
+		if errors.As(err, &edgexErr) && edgexErr.Code() == http.StatusNotFound {
+			respondError(app.lc, w, http.StatusNotFound, fmt.Sprintf("Failed to get camera inventory: %v", err))
+			return
+		}
+		respondError(app.lc, w, http.StatusInternalServerError,
+			fmt.Sprintf("Failed to get camera inventory: %v", err))
+		return
+	}
+	respondJson(app.lc, w, item)
+}
+
+func (app *CameraManagementApp) getPipelinesRoute(w http.ResponseWriter, req *http.Request) {
+	catalog, err := app.getCatalog(req.Context(), false)
 	if err != nil {
 		respondError(app.lc, w, http.StatusInternalServerError,
 			fmt.Sprintf("Failed to get pipelines: %v", err))
 		return
 	}
 
-	respondJson(app.lc, w, pl)
+	respondJson(app.lc, w, catalog.Pipelines)
+}
+
+func (app *CameraManagementApp) getCatalogRoute(w http.ResponseWriter, req *http.Request) {
+	catalog, err := app.getCatalog(req.Context(), req.URL.Query().Get("refresh") == "true")
+	if err != nil {
+		respondError(app.lc, w, http.StatusInternalServerError,
+			fmt.Sprintf("Failed to get pipeline catalog: %v", err))
+		return
+	}
+
+	respondJson(app.lc, w, catalog)
 }
 
 func (app *CameraManagementApp) startPipelineRoute(w http.ResponseWriter, req *http.Request) {
@@ -219,11 +607,31 @@ func (app *CameraManagementApp) startPipelineRoute(w http.ResponseWriter, req *h
 	}
 
 	if app.isPipelineRunning(deviceName) {
-		respondError(app.lc, w, http.StatusBadRequest, fmt.Sprintf("pipeline already running for camera: %s", deviceName))
+		respondErrorCode(app.lc, w, http.StatusBadRequest, PipelineAlreadyRunning,
+			fmt.Sprintf("pipeline already running for camera: %s", deviceName), nil)
 		return
 	}
 
 	if err := app.startPipeline(deviceName, sr); err != nil {
+		if validationErr, ok := asUSBStreamingValidationError(err); ok {
+			var details map[string]interface{}
+			if validationErr.Suggestion != nil {
+				details = map[string]interface{}{"suggestion": validationErr.Suggestion}
+			}
+			respondErrorCode(app.lc, w, http.StatusBadRequest, UnsupportedStreamingMode,
+				fmt.Sprintf("Failed to start pipeline: %v", err), details)
+			return
+		}
+		if errors.Is(err, errUnknownPipeline) {
+			respondErrorCode(app.lc, w, http.StatusBadRequest, UnknownPipeline,
+				fmt.Sprintf("Failed to start pipeline: %v", err), nil)
+			return
+		}
+		if errors.Is(err, errMasksNotSupported) {
+			respondErrorCode(app.lc, w, http.StatusBadRequest, MasksNotSupported,
+				fmt.Sprintf("Failed to start pipeline: %v", err), nil)
+			return
+		}
 		respondError(app.lc, w, http.StatusInternalServerError, fmt.Sprintf("Failed to start pipeline: %v", err))
 		return
 	}
@@ -245,6 +653,22 @@ func (app *CameraManagementApp) pipelineStatusRoute(w http.ResponseWriter, req *
 	respondJson(app.lc, w, res)
 }
 
+func (app *CameraManagementApp) pipelineInfoStatusRoute(w http.ResponseWriter, req *http.Request) {
+	rv := mux.Vars(req)
+	deviceName := rv["name"]
+	res, found, err := app.getPipelineInfoStatus(deviceName)
+	if err != nil {
+		respondError(app.lc, w, http.StatusInternalServerError,
+			fmt.Sprintf("failed to get pipeline status: %v", err))
+		return
+	}
+	if !found {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	respondJson(app.lc, w, res)
+}
+
 func (app *CameraManagementApp) allPipelineStatusesRoute(w http.ResponseWriter, _ *http.Request) {
 	res, err := app.getAllPipelineStatuses()
 	if err != nil {
@@ -264,23 +688,26 @@ func (app *CameraManagementApp) stopPipelineRoute(w http.ResponseWriter, req *ht
 	deviceName := rv["name"]
 	id := rv["id"]
 
+	// resolve the adapter first, so that an unsupported device is rejected before its pipeline is stopped
+	dev, err := app.getDeviceByName(deviceName)
+	if err != nil {
+		respondError(app.lc, w, http.StatusBadRequest,
+			fmt.Sprintf("failed to query device %s: %v", deviceName, err))
+		return
+	}
+	adapter, err := app.getCameraAdapter(dev)
+	if err != nil {
+		respondError(app.lc, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	defer func() {
-		dev, err := app.getDeviceByName(deviceName)
-		if err != nil {
-			respondError(app.lc, w, http.StatusBadRequest,
-				fmt.Sprintf("failed to query device %s: %v", deviceName, err))
+		// stop streaming after shutting off the pipeline (only does something for cameras such as usb cameras)
+		if err := adapter.StopStreaming(dev); err != nil {
+			respondError(app.lc, w, http.StatusInternalServerError,
+				fmt.Sprintf("failed to stop streaming camera %s: %v", deviceName, err))
 			return
 		}
-
-		// if the device is a usb device, stop streaming after shutting off the pipeline
-		if dev.ServiceName == app.config.AppCustom.USBDeviceServiceName {
-			_, err := app.stopStreaming(deviceName)
-			if err != nil {
-				respondError(app.lc, w, http.StatusInternalServerError,
-					fmt.Sprintf("failed to stop streaming usb camera %s: %v", deviceName, err))
-				return
-			}
-		}
 	}()
 
 	if err := app.stopPipeline(deviceName, id); err != nil {
@@ -296,13 +723,9 @@ func (app *CameraManagementApp) gotoPresetRoute(w http.ResponseWriter, req *http
 	profileToken := rv["profile"]
 	preset := rv["preset"]
 
-	var res dtosCommon.BaseResponse
-	var err error
-
-	res, err = app.gotoPreset(deviceName, profileToken, preset)
+	res, err := app.ptz.gotoPreset(req.Context(), deviceName, req.Header.Get(PTZOperatorHeader), profileToken, preset)
 	if err != nil {
-		respondError(app.lc, w, http.StatusInternalServerError,
-			fmt.Sprintf("Failed to do gotoPreset: %v", err))
+		app.respondPTZError(w, "Failed to do gotoPreset", err)
 		return
 	}
 	respondJson(app.lc, w, res)
@@ -314,9 +737,6 @@ func (app *CameraManagementApp) ptzRoute(w http.ResponseWriter, req *http.Reques
 	profileToken := rv["profile"]
 	action := rv["action"]
 
-	var res dtosCommon.BaseResponse
-	var err error
-
 	ptzRange, err := app.getPTZRange(deviceName)
 	if err != nil {
 		respondError(app.lc, w, http.StatusInternalServerError,
@@ -331,46 +751,173 @@ func (app *CameraManagementApp) ptzRoute(w http.ResponseWriter, req *http.Reques
 	zoomIn := zoomOffset * ptzRange.ZRange
 	zoomOut := -zoomIn
 
+	var x, y, zoom float64
 	switch action {
 	case "left":
-		res, err = app.doPTZ(deviceName, profileToken, left, 0, 0)
+		x = left
 	case "right":
-		res, err = app.doPTZ(deviceName, profileToken, right, 0, 0)
+		x = right
 
 	case "up":
-		res, err = app.doPTZ(deviceName, profileToken, 0, up, 0)
+		y = up
 	case "up-left":
-		res, err = app.doPTZ(deviceName, profileToken, left, up, 0)
+		x, y = left, up
 	case "up-right":
-		res, err = app.doPTZ(deviceName, profileToken, right, up, 0)
+		x, y = right, up
 
 	case "down":
-		res, err = app.doPTZ(deviceName, profileToken, 0, down, 0)
+		y = down
 	case "down-left":
-		res, err = app.doPTZ(deviceName, profileToken, left, down, 0)
+		x, y = left, down
 	case "down-right":
-		res, err = app.doPTZ(deviceName, profileToken, right, down, 0)
+		x, y = right, down
 
 	case "zoom-in":
-		res, err = app.doPTZ(deviceName, profileToken, 0, 0, zoomIn)
+		zoom = zoomIn
 	case "zoom-out":
-		res, err = app.doPTZ(deviceName, profileToken, 0, 0, zoomOut)
+		zoom = zoomOut
 
 	default:
-		err = fmt.Errorf("unknown ptz action: %s", action)
+		respondError(app.lc, w, http.StatusBadRequest, fmt.Sprintf("unknown ptz action: %s", action))
+		return
 	}
 
+	res, err := app.ptz.move(req.Context(), deviceName, req.Header.Get(PTZOperatorHeader), profileToken, x, y, zoom)
 	if err != nil {
-		respondError(app.lc, w, http.StatusInternalServerError,
-			fmt.Sprintf("Failed to do ptz: %v", err))
+		app.respondPTZError(w, "Failed to do ptz", err)
+		return
+	}
+	respondJson(app.lc, w, res)
+}
+
+// respondPTZError responds with the status and code matching an error returned by the ptzController.
+func (app *CameraManagementApp) respondPTZError(w http.ResponseWriter, msg string, err error) {
+	var lockedErr *PTZLockedError
+	switch {
+	case errors.As(err, &lockedErr):
+		respondErrorCode(app.lc, w, http.StatusConflict, CameraLocked, fmt.Sprintf("%s: %v", msg, err),
+			map[string]interface{}{"owner": lockedErr.Lock.Owner, "expires": lockedErr.Lock.Expires})
+	case errors.Is(err, errPTZQueueFull):
+		respondErrorCode(app.lc, w, http.StatusTooManyRequests, PTZQueueFull, fmt.Sprintf("%s: %v", msg, err), nil)
+	default:
+		respondError(app.lc, w, http.StatusInternalServerError, fmt.Sprintf("%s: %v", msg, err))
+	}
+}
+
+func (app *CameraManagementApp) getPTZLockRoute(w http.ResponseWriter, req *http.Request) {
+	deviceName := mux.Vars(req)["name"]
+
+	lock := app.ptz.getLock(deviceName)
+	if lock == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	respondJson(app.lc, w, lock)
+}
+
+func (app *CameraManagementApp) acquirePTZLockRoute(w http.ResponseWriter, req *http.Request) {
+	deviceName := mux.Vars(req)["name"]
+
+	var lockReq PTZLockRequest
+	if !extractJSONBody(app.lc, w, req, &lockReq) {
 		return
 	}
-	_, err = w.Write([]byte(res.Message))
+	if lockReq.Owner == "" {
+		respondError(app.lc, w, http.StatusBadRequest, "the owner of the lock is required")
+		return
+	}
+
+	lock, err := app.ptz.acquireLock(deviceName, lockReq)
 	if err != nil {
+		var lockedErr *PTZLockedError
+		if errors.As(err, &lockedErr) {
+			app.respondPTZError(w, "Failed to lock PTZ", err)
+		} else {
+			respondError(app.lc, w, http.StatusBadRequest, fmt.Sprintf("Failed to lock PTZ: %v", err))
+		}
+		return
+	}
+	app.lc.Infof("PTZ of camera %s locked by %s until %s", deviceName, lock.Owner, lock.Expires.Format(time.RFC3339))
+	respondJson(app.lc, w, lock)
+}
+
+func (app *CameraManagementApp) releasePTZLockRoute(w http.ResponseWriter, req *http.Request) {
+	deviceName := mux.Vars(req)["name"]
+	force := req.URL.Query().Get("force") == "true"
+
+	if err := app.ptz.releaseLock(deviceName, req.Header.Get(PTZOperatorHeader), force); err != nil {
+		app.respondPTZError(w, "Failed to unlock PTZ", err)
+		return
+	}
+	app.lc.Infof("PTZ of camera %s unlocked", deviceName)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *CameraManagementApp) triggerDiscoveryRoute(w http.ResponseWriter, req *http.Request) {
+	services, err := app.discoveryServicesFor(req.URL.Query().Get("service"))
+	if err != nil {
+		respondError(app.lc, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	for _, service := range services {
+		if err = app.triggerDiscovery(req.Context(), service); err != nil {
+			respondError(app.lc, w, http.StatusInternalServerError, fmt.Sprintf("Failed to trigger discovery: %v", err))
+			return
+		}
+		app.lc.Infof("Triggered discovery on device service %s", service)
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusAccepted)
+	if err = json.NewEncoder(w).Encode(DiscoveryResponse{Services: services}); err != nil {
 		app.lc.Error(err.Error())
 	}
 }
 
+func (app *CameraManagementApp) getProvisionalDevicesRoute(w http.ResponseWriter, req *http.Request) {
+	devices, err := app.getProvisionalDevices(req.Context())
+	if err != nil {
+		respondError(app.lc, w, http.StatusInternalServerError,
+			fmt.Sprintf("Failed to get provisional devices: %v", err))
+		return
+	}
+	respondJson(app.lc, w, devices)
+}
+
+func (app *CameraManagementApp) approveDeviceRoute(w http.ResponseWriter, req *http.Request) {
+	deviceName := mux.Vars(req)["name"]
+
+	var approveReq ApproveDeviceRequest
+	if req.ContentLength != 0 {
+		if !extractJSONBody(app.lc, w, req, &approveReq) {
+			return
+		}
+	}
+	if (approveReq.PipelineName == "") != (approveReq.PipelineVersion == "") {
+		respondError(app.lc, w, http.StatusBadRequest, "both pipelineName and pipelineVersion must be set")
+		return
+	}
+
+	res, err := app.approveDevice(req.Context(), deviceName, approveReq)
+	if err != nil {
+		var edgexErr edgexErrors.EdgeX
+		switch {
+		case errors.Is(err, errNotProvisional):
+			respondErrorCode(app.lc, w, http.StatusConflict, NotProvisional,
+				fmt.Sprintf("Failed to approve device: %v", err), nil)
+		case errors.Is(err, errUnknownProfile):
+			respondError(app.lc, w, http.StatusBadRequest, fmt.Sprintf("Failed to approve device: %v", err))
+		case errors.As(err, &edgexErr) && edgexErr.Code() == http.StatusNotFound:
+			respondError(app.lc, w, http.StatusNotFound, fmt.Sprintf("Failed to approve device: %v", err))
+		default:
+			respondError(app.lc, w, http.StatusInternalServerError, fmt.Sprintf("Failed to approve device: %v", err))
+		}
+		return
+	}
+	respondJson(app.lc, w, res)
+}
+
 func (app *CameraManagementApp) getPTZRange(deviceName string) (PTZRange, error) {
 	app.ptzRangeMutex.Lock()
 	defer app.ptzRangeMutex.Unlock()
@@ -0,0 +1,81 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"fmt"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
+	"github.com/pkg/errors"
+)
+
+const (
+	// rtspProtocol is the name of the device protocol holding the stream information of a generic RTSP camera
+	rtspProtocol = "RTSP"
+	// rtspStreamUriKey is the protocol property holding the RTSP stream uri
+	rtspStreamUriKey = "StreamUri"
+)
+
+// rtspAdapter handles generic cameras which only provide an RTSP stream. The stream uri is taken from the
+// StreamUri property of the device's RTSP protocol, for example:
+//
+//	protocols:
+//	  RTSP:
+//	    StreamUri: rtsp://192.168.1.10:554/stream1
+type rtspAdapter struct {
+	app *CameraManagementApp
+}
+
+func (a *rtspAdapter) Type() CameraType {
+	return RTSP
+}
+
+func (a *rtspAdapter) ServiceName() string {
+	return a.app.appConfig().RTSPDeviceServiceName
+}
+
+func (a *rtspAdapter) Supports(device dtos.Device) bool {
+	_, ok := device.Protocols[rtspProtocol]
+	return ok
+}
+
+func (a *rtspAdapter) StreamUri(device dtos.Device, _ StartPipelineRequest) (string, error) {
+	protocol, ok := device.Protocols[rtspProtocol]
+	if !ok {
+		return "", errors.Errorf("device %s is missing the %s protocol", device.Name, rtspProtocol)
+	}
+	value, ok := protocol[rtspStreamUriKey]
+	if !ok {
+		return "", errors.Errorf("device %s is missing the %s protocol property %s", device.Name, rtspProtocol, rtspStreamUriKey)
+	}
+	uri := fmt.Sprintf("%v", value)
+	if uri == "" {
+		return "", errors.Errorf("device %s has an empty %s protocol property %s", device.Name, rtspProtocol, rtspStreamUriKey)
+	}
+	return uri, nil
+}
+
+func (a *rtspAdapter) Features(_ dtos.Device) (CameraFeatures, error) {
+	return CameraFeatures{CameraType: RTSP}, nil
+}
+
+func (a *rtspAdapter) StartStreaming(_ dtos.Device, _ StartPipelineRequest) error {
+	// rtsp cameras are always streaming
+	return nil
+}
+
+func (a *rtspAdapter) StopStreaming(_ dtos.Device) error {
+	return nil
+}
+
+func (a *rtspAdapter) DefaultStreamConfig(_ dtos.Device, _ *StartPipelineRequest) error {
+	// nothing to configure, everything is taken from the device protocol
+	return nil
+}
+
+func (a *rtspAdapter) SecretName() string {
+	return rtspCameraAuth
+}
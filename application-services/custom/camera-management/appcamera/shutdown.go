@@ -0,0 +1,197 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ShutdownPolicy determines what happens to the camera pipelines when the service stops
+type ShutdownPolicy string
+
+const (
+	// LeaveRunning leaves the pipelines running in EVAM, so they are picked up again when the service restarts
+	LeaveRunning ShutdownPolicy = "leave-running"
+	// StopPipelines stops the pipelines started by this service. The pipelines which were already running in
+	// EVAM when the service started, or when it was elected leader, are left running.
+	StopPipelines ShutdownPolicy = "stop-pipelines"
+	// StopPipelinesAndStreaming stops the pipelines started by this service, and the streaming of cameras
+	// which had to be told to start streaming, such as usb cameras
+	StopPipelinesAndStreaming ShutdownPolicy = "stop-pipelines-and-streaming"
+
+	defaultShutdownTimeout = 10 * time.Second
+)
+
+// ShutdownCameraReport describes what was done for a single camera during shutdown
+type ShutdownCameraReport struct {
+	Camera           string `json:"camera"`
+	PipelineId       string `json:"pipelineId"`
+	PipelineStopped  bool   `json:"pipelineStopped"`
+	StreamingStopped bool   `json:"streamingStopped"`
+	Error            string `json:"error,omitempty"`
+}
+
+// ShutdownReport describes what was done during shutdown
+type ShutdownReport struct {
+	Policy   ShutdownPolicy         `json:"policy"`
+	Cameras  []ShutdownCameraReport `json:"cameras"`
+	TimedOut bool                   `json:"timedOut"`
+	Duration string                 `json:"duration"`
+}
+
+func parseShutdownPolicy(policy string) (ShutdownPolicy, error) {
+	switch ShutdownPolicy(policy) {
+	case "":
+		return LeaveRunning, nil
+	case LeaveRunning, StopPipelines, StopPipelinesAndStreaming:
+		return ShutdownPolicy(policy), nil
+	default:
+		return "", errors.Errorf("invalid ShutdownPolicy '%s', must be one of %s, %s or %s",
+			policy, LeaveRunning, StopPipelines, StopPipelinesAndStreaming)
+	}
+}
+
+func parseShutdownTimeout(timeout string) (time.Duration, error) {
+	if timeout == "" {
+		return defaultShutdownTimeout, nil
+	}
+	d, err := time.ParseDuration(timeout)
+	if err != nil || d <= 0 {
+		return 0, errors.Errorf("invalid ShutdownTimeout '%s'", timeout)
+	}
+	return d, nil
+}
+
+// shutdown applies the configured shutdown policy to the pipelines of all cameras, and reports what it did.
+// Cameras are handled concurrently, and any camera not done by the configured timeout is reported as such.
+func (app *CameraManagementApp) shutdown() ShutdownReport {
+	start := time.Now()
+	// the configuration was validated at startup
+	policy, _ := parseShutdownPolicy(app.appConfig().ShutdownPolicy)
+	timeout, _ := parseShutdownTimeout(app.appConfig().ShutdownTimeout)
+
+	if !app.isLeader() {
+		// the pipelines are taken over by the next leader
+		policy = LeaveRunning
+	}
+
+	report := ShutdownReport{Policy: policy, Cameras: []ShutdownCameraReport{}}
+	if policy == LeaveRunning {
+		report.Duration = time.Since(start).String()
+		return report
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	app.pipelinesMutex.RLock()
+	pipelines := make(map[string]PipelineInfo, len(app.pipelinesMap))
+	for camera, info := range app.pipelinesMap {
+		if info.startedByApp() {
+			pipelines[camera] = info
+		} else {
+			app.lc.Debugf("Leaving the pipeline %s of camera %s running, as it was not started by this service", info.Id, camera)
+		}
+	}
+	app.pipelinesMutex.RUnlock()
+
+	results := make(chan ShutdownCameraReport, len(pipelines))
+	wg := sync.WaitGroup{}
+	for camera, info := range pipelines {
+		wg.Add(1)
+		go func(camera string, info PipelineInfo) {
+			defer wg.Done()
+			results <- app.shutdownCamera(ctx, policy, camera, info)
+		}(camera, info)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		report.TimedOut = true
+	}
+
+	// collect the results of the cameras which completed in time
+	reported := make(map[string]bool)
+	for len(results) > 0 {
+		r := <-results
+		reported[r.Camera] = true
+		report.Cameras = append(report.Cameras, r)
+	}
+	for camera, info := range pipelines {
+		if !reported[camera] {
+			report.Cameras = append(report.Cameras, ShutdownCameraReport{
+				Camera:     camera,
+				PipelineId: info.Id,
+				Error:      "shutdown timeout exceeded",
+			})
+		}
+	}
+	sort.Slice(report.Cameras, func(i, j int) bool {
+		return report.Cameras[i].Camera < report.Cameras[j].Camera
+	})
+
+	report.Duration = time.Since(start).String()
+	return report
+}
+
+func (app *CameraManagementApp) shutdownCamera(ctx context.Context, policy ShutdownPolicy, camera string, info PipelineInfo) ShutdownCameraReport {
+	r := ShutdownCameraReport{Camera: camera, PipelineId: info.Id}
+
+	var res interface{}
+	if err := issueDeleteRequest(ctx, &res, app.appConfig().EvamBaseUrl, path.Join("/pipelines", info.Id)); err != nil {
+		r.Error = errors.Wrap(err, "DELETE request to stop EVAM pipeline failed").Error()
+		return r
+	}
+	if current, found := app.getPipelineInfo(camera); found && current.Id == info.Id {
+		app.deletePipelineInfo(camera)
+	}
+	r.PipelineStopped = true
+
+	if policy != StopPipelinesAndStreaming {
+		return r
+	}
+
+	device, adapter, err := app.getDeviceAdapter(camera)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	if err = adapter.StopStreaming(device); err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	r.StreamingStopped = true
+	return r
+}
+
+// logShutdownReport logs the shutdown report as json, so it can easily be parsed from the logs
+func (app *CameraManagementApp) logShutdownReport(report ShutdownReport) {
+	b, err := json.Marshal(report)
+	if err != nil {
+		app.lc.Errorf("Failed to marshal shutdown report: %v", err)
+		return
+	}
+	if report.TimedOut {
+		timeout, _ := parseShutdownTimeout(app.appConfig().ShutdownTimeout)
+		app.lc.Warnf("Shutdown did not complete within %s: %s", timeout, string(b))
+		return
+	}
+	app.lc.Infof("Shutdown completed: %s", string(b))
+}
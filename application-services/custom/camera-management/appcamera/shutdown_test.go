@@ -0,0 +1,100 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/clients/logger"
+	loggerMocks "github.com/edgexfoundry/go-mod-core-contracts/v3/clients/logger/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestApp returns an app with the custom configuration, which is only suitable for the code paths
+// which do not use the application service
+func newTestApp(cfg CustomConfig) *CameraManagementApp {
+	return &CameraManagementApp{
+		lc:           logger.NewMockClient(),
+		config:       &ServiceConfig{AppCustom: cfg},
+		pipelinesMap: make(map[string]PipelineInfo),
+		ptzRangeMap:  make(map[string]PTZRange),
+	}
+}
+
+// fakeEvam records the ids of the pipelines deleted through its api
+type fakeEvam struct {
+	mutex   sync.Mutex
+	deleted []string
+}
+
+func (e *fakeEvam) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodDelete {
+		e.mutex.Lock()
+		e.deleted = append(e.deleted, strings.TrimPrefix(r.URL.Path, "/pipelines/"))
+		e.mutex.Unlock()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`{}`))
+}
+
+func TestShutdownOnlyStopsStartedPipelines(t *testing.T) {
+	evam := &fakeEvam{}
+	server := httptest.NewServer(evam)
+	defer server.Close()
+
+	app := newTestApp(CustomConfig{EvamBaseUrl: server.URL, ShutdownPolicy: string(StopPipelines)})
+	app.pipelinesMap["started"] = PipelineInfo{Id: "1", request: &StartPipelineRequest{PipelineName: "object_detection"}}
+	app.pipelinesMap["adopted"] = PipelineInfo{Id: "2"}
+
+	report := app.shutdown()
+
+	require.Len(t, report.Cameras, 1)
+	assert.Equal(t, "started", report.Cameras[0].Camera)
+	assert.True(t, report.Cameras[0].PipelineStopped)
+	assert.Equal(t, []string{"1"}, evam.deleted)
+	assert.False(t, app.isPipelineRunning("started"))
+	assert.True(t, app.isPipelineRunning("adopted"))
+}
+
+func TestShutdownLeaveRunning(t *testing.T) {
+	evam := &fakeEvam{}
+	server := httptest.NewServer(evam)
+	defer server.Close()
+
+	app := newTestApp(CustomConfig{EvamBaseUrl: server.URL, ShutdownPolicy: string(LeaveRunning)})
+	app.pipelinesMap["started"] = PipelineInfo{Id: "1", request: &StartPipelineRequest{PipelineName: "object_detection"}}
+
+	report := app.shutdown()
+
+	assert.Equal(t, LeaveRunning, report.Policy)
+	assert.Empty(t, report.Cameras)
+	assert.Empty(t, evam.deleted)
+}
+
+func TestLogShutdownReportTimeout(t *testing.T) {
+	lc := &loggerMocks.LoggingClient{}
+	lc.On("Warnf", "Shutdown did not complete within %s: %s", defaultShutdownTimeout, mock.Anything).Return()
+
+	// the default timeout is logged when the ShutdownTimeout is not set
+	app := newTestApp(CustomConfig{})
+	app.lc = lc
+	app.logShutdownReport(ShutdownReport{Policy: StopPipelines, TimedOut: true})
+	lc.AssertExpectations(t)
+
+	lc = &loggerMocks.LoggingClient{}
+	lc.On("Warnf", "Shutdown did not complete within %s: %s", 3*time.Second, mock.Anything).Return()
+	app = newTestApp(CustomConfig{ShutdownTimeout: "3s"})
+	app.lc = lc
+	app.logShutdownReport(ShutdownReport{Policy: StopPipelines, TimedOut: true})
+	lc.AssertExpectations(t)
+}
@@ -0,0 +1,450 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// frameZone is the name of the implicit zone covering the whole frame of every camera
+	frameZone = "frame"
+
+	// trackTimeout is how long the last position of a tracked object is remembered for line-crossing detection
+	trackTimeout = 5 * time.Second
+)
+
+// CountingZone is a named polygon region of a camera's view in which objects are counted.
+type CountingZone struct {
+	Name   string  `json:"name"`
+	Points []Point `json:"points"`
+}
+
+// Validate checks that the zone describes a valid polygon.
+func (z CountingZone) Validate() error {
+	if z.Name == "" {
+		return errors.New("zone name is required")
+	}
+	if z.Name == frameZone {
+		return errors.Errorf("zone name %s is reserved", frameZone)
+	}
+	if err := validatePolygon(z.Points); err != nil {
+		return errors.Wrapf(err, "invalid zone %s", z.Name)
+	}
+	return nil
+}
+
+// CountingLine is a named line of a camera's view for which tracked objects crossing it are counted.
+// Objects crossing over to the right-hand side of the line, when facing from Start towards End in the frame,
+// are counted as in, the others are counted as out.
+type CountingLine struct {
+	Name  string `json:"name"`
+	Start Point  `json:"start"`
+	End   Point  `json:"end"`
+}
+
+// Validate checks that the line is within the frame.
+func (l CountingLine) Validate() error {
+	if l.Name == "" {
+		return errors.New("line name is required")
+	}
+	if l.Start == l.End {
+		return errors.Errorf("line %s start and end must be different points", l.Name)
+	}
+	if err := l.Start.validate(); err != nil {
+		return errors.Wrapf(err, "invalid line %s", l.Name)
+	}
+	if err := l.End.validate(); err != nil {
+		return errors.Wrapf(err, "invalid line %s", l.Name)
+	}
+	return nil
+}
+
+// StatisticsBucket holds the detection statistics of a single label within a zone or line of a camera,
+// for the time period starting at Start and ending before End.
+type StatisticsBucket struct {
+	Camera string    `json:"camera"`
+	Zone   string    `json:"zone"`
+	Label  string    `json:"label"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	// Samples is the number of inference events which were counted
+	Samples int `json:"samples"`
+	// Total is the sum of the objects counted in each sample
+	Total int `json:"total"`
+	// Average is the average number of objects over all samples
+	Average float64 `json:"average"`
+	// Peak is the highest number of objects counted in a single sample
+	Peak int `json:"peak"`
+	// In is the number of tracked objects which crossed a line inwards
+	In int `json:"in"`
+	// Out is the number of tracked objects which crossed a line outwards
+	Out int `json:"out"`
+}
+
+func (b *StatisticsBucket) addSample(count int) {
+	b.Samples++
+	b.Total += count
+	if count > b.Peak {
+		b.Peak = count
+	}
+	b.Average = float64(b.Total) / float64(b.Samples)
+}
+
+func (b *StatisticsBucket) merge(other *StatisticsBucket) {
+	b.Samples += other.Samples
+	b.Total += other.Total
+	if other.Peak > b.Peak {
+		b.Peak = other.Peak
+	}
+	if b.Samples > 0 {
+		b.Average = float64(b.Total) / float64(b.Samples)
+	}
+	b.In += other.In
+	b.Out += other.Out
+}
+
+type statisticsKey struct {
+	camera string
+	zone   string
+	label  string
+	start  int64
+}
+
+// statisticsData is the content of the statistics store file.
+type statisticsData struct {
+	Zones   map[string][]CountingZone `json:"zones"`
+	Lines   map[string][]CountingLine `json:"lines"`
+	Buckets []*StatisticsBucket       `json:"buckets"`
+}
+
+type trackedObject struct {
+	position Point
+	lastSeen time.Time
+}
+
+// statisticsAggregator consumes inference events and keeps time-bucketed statistics for each camera,
+// zone and label. The zones, lines and buckets are persisted to a json file.
+type statisticsAggregator struct {
+	filename   string
+	bucketSize time.Duration
+	retention  time.Duration
+
+	mutex   sync.Mutex
+	zones   map[string][]CountingZone
+	lines   map[string][]CountingLine
+	buckets map[statisticsKey]*StatisticsBucket
+	// labels holds all the labels seen for each camera, so that samples with none of those objects are counted as well
+	labels map[string]map[string]bool
+	// tracks holds the last position of each tracked object of each camera
+	tracks map[string]map[int]trackedObject
+	dirty  bool
+}
+
+func newStatisticsAggregator(filename string, bucketSize, retention time.Duration) *statisticsAggregator {
+	return &statisticsAggregator{
+		filename:   filename,
+		bucketSize: bucketSize,
+		retention:  retention,
+		zones:      make(map[string][]CountingZone),
+		lines:      make(map[string][]CountingLine),
+		buckets:    make(map[statisticsKey]*StatisticsBucket),
+		labels:     make(map[string]map[string]bool),
+		tracks:     make(map[string]map[int]trackedObject),
+	}
+}
+
+// load reads the persisted statistics from disk. A missing file is not an error.
+func (s *statisticsAggregator) load() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data := statisticsData{}
+	if err := readJSONFile(s.filename, &data); err != nil {
+		return err
+	}
+	if data.Zones != nil {
+		s.zones = data.Zones
+	}
+	if data.Lines != nil {
+		s.lines = data.Lines
+	}
+	for _, b := range data.Buckets {
+		s.buckets[statisticsKey{b.Camera, b.Zone, b.Label, b.Start.Unix()}] = b
+		s.addLabel(b.Camera, b.Label)
+	}
+	return nil
+}
+
+// save writes the statistics to disk. The caller must hold the lock.
+func (s *statisticsAggregator) save() error {
+	data := statisticsData{
+		Zones:   s.zones,
+		Lines:   s.lines,
+		Buckets: make([]*StatisticsBucket, 0, len(s.buckets)),
+	}
+	for _, b := range s.buckets {
+		data.Buckets = append(data.Buckets, b)
+	}
+	if err := writeJSONFile(s.filename, data); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+// addLabel records that the label was seen for the camera. The caller must hold the lock.
+func (s *statisticsAggregator) addLabel(camera string, label string) {
+	if label == "" {
+		return
+	}
+	if s.labels[camera] == nil {
+		s.labels[camera] = make(map[string]bool)
+	}
+	s.labels[camera][label] = true
+}
+
+// bucket returns the bucket for the specified time, creating it if needed. The caller must hold the lock.
+func (s *statisticsAggregator) bucket(camera, zone, label string, t time.Time) *StatisticsBucket {
+	start := t.Truncate(s.bucketSize)
+	key := statisticsKey{camera, zone, label, start.Unix()}
+	b, found := s.buckets[key]
+	if !found {
+		b = &StatisticsBucket{
+			Camera: camera,
+			Zone:   zone,
+			Label:  label,
+			Start:  start,
+			End:    start.Add(s.bucketSize),
+		}
+		s.buckets[key] = b
+	}
+	return b
+}
+
+// process updates the statistics of the camera with the objects of an inference event received at the specified time.
+func (s *statisticsAggregator) process(camera string, event InferenceEvent, now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, obj := range event.Objects {
+		s.addLabel(camera, obj.Detection.Label)
+	}
+
+	zones := append([]CountingZone{{Name: frameZone}}, s.zones[camera]...)
+	for _, zone := range zones {
+		counts := make(map[string]int)
+		for _, obj := range event.Objects {
+			if zone.Name == frameZone || polygonContains(zone.Points, obj.position()) {
+				counts[obj.Detection.Label]++
+			}
+		}
+		for label := range s.labels[camera] {
+			s.bucket(camera, zone.Name, label, now).addSample(counts[label])
+		}
+	}
+
+	s.processLineCrossings(camera, event, now)
+	s.dirty = true
+}
+
+// processLineCrossings counts the tracked objects which crossed a line since their last known position.
+// The caller must hold the lock.
+func (s *statisticsAggregator) processLineCrossings(camera string, event InferenceEvent, now time.Time) {
+	tracks := s.tracks[camera]
+	if tracks == nil {
+		tracks = make(map[int]trackedObject)
+		s.tracks[camera] = tracks
+	}
+
+	for _, obj := range event.Objects {
+		// objects are only tracked when the pipeline assigns them an id
+		if obj.Id == 0 {
+			continue
+		}
+		current := obj.position()
+		if previous, found := tracks[obj.Id]; found && now.Sub(previous.lastSeen) <= trackTimeout {
+			for _, line := range s.lines[camera] {
+				if !segmentsIntersect(previous.position, current, line.Start, line.End) {
+					continue
+				}
+				b := s.bucket(camera, line.Name, obj.Detection.Label, now)
+				if cross(line.Start, line.End, current) > 0 {
+					b.In++
+				} else {
+					b.Out++
+				}
+			}
+		}
+		tracks[obj.Id] = trackedObject{position: current, lastSeen: now}
+	}
+
+	for id, track := range tracks {
+		if now.Sub(track.lastSeen) > trackTimeout {
+			delete(tracks, id)
+		}
+	}
+}
+
+// query returns the statistics of the camera between start and end, aggregated into buckets of the specified size.
+// Empty zone and label match all zones and labels.
+func (s *statisticsAggregator) query(camera, zone, label string, start, end time.Time, bucketSize time.Duration) ([]StatisticsBucket, error) {
+	if bucketSize < s.bucketSize || bucketSize%s.bucketSize != 0 {
+		return nil, errors.Errorf("bucket size must be a multiple of %v", s.bucketSize)
+	}
+	if !end.After(start) {
+		return nil, errors.New("end must be after start")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	results := make(map[statisticsKey]*StatisticsBucket)
+	for _, b := range s.buckets {
+		if b.Camera != camera || (zone != "" && b.Zone != zone) || (label != "" && b.Label != label) ||
+			b.Start.Before(start) || !b.Start.Before(end) {
+			continue
+		}
+		bucketStart := b.Start.Truncate(bucketSize)
+		key := statisticsKey{b.Camera, b.Zone, b.Label, bucketStart.Unix()}
+		result, found := results[key]
+		if !found {
+			result = &StatisticsBucket{
+				Camera: b.Camera,
+				Zone:   b.Zone,
+				Label:  b.Label,
+				Start:  bucketStart,
+				End:    bucketStart.Add(bucketSize),
+			}
+			results[key] = result
+		}
+		result.merge(b)
+	}
+
+	buckets := make([]StatisticsBucket, 0, len(results))
+	for _, b := range results {
+		buckets = append(buckets, *b)
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		if !buckets[i].Start.Equal(buckets[j].Start) {
+			return buckets[i].Start.Before(buckets[j].Start)
+		}
+		if buckets[i].Zone != buckets[j].Zone {
+			return buckets[i].Zone < buckets[j].Zone
+		}
+		return buckets[i].Label < buckets[j].Label
+	})
+	return buckets, nil
+}
+
+// cameras returns the names of all cameras with statistics.
+func (s *statisticsAggregator) cameras() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var cameras []string
+	for camera := range s.labels {
+		cameras = append(cameras, camera)
+	}
+	sort.Strings(cameras)
+	return cameras
+}
+
+// prune removes the buckets older than the retention period, and persists the statistics if they changed.
+func (s *statisticsAggregator) prune(now time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	cutoff := now.Add(-s.retention)
+	for key, b := range s.buckets {
+		if b.End.Before(cutoff) {
+			delete(s.buckets, key)
+			s.dirty = true
+		}
+	}
+	if !s.dirty {
+		return nil
+	}
+	return s.save()
+}
+
+func (s *statisticsAggregator) getZones(camera string) []CountingZone {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	zones := make([]CountingZone, len(s.zones[camera]))
+	copy(zones, s.zones[camera])
+	return zones
+}
+
+func (s *statisticsAggregator) putZone(camera string, zone CountingZone) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	zones := s.zones[camera]
+	for i := range zones {
+		if zones[i].Name == zone.Name {
+			zones[i] = zone
+			return s.save()
+		}
+	}
+	s.zones[camera] = append(zones, zone)
+	return s.save()
+}
+
+func (s *statisticsAggregator) deleteZone(camera string, name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	zones := s.zones[camera]
+	for i := range zones {
+		if zones[i].Name == name {
+			s.zones[camera] = append(zones[:i], zones[i+1:]...)
+			return s.save()
+		}
+	}
+	return errors.Errorf("zone %s not found for camera %s", name, camera)
+}
+
+func (s *statisticsAggregator) getLines(camera string) []CountingLine {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	lines := make([]CountingLine, len(s.lines[camera]))
+	copy(lines, s.lines[camera])
+	return lines
+}
+
+func (s *statisticsAggregator) putLine(camera string, line CountingLine) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	lines := s.lines[camera]
+	for i := range lines {
+		if lines[i].Name == line.Name {
+			lines[i] = line
+			return s.save()
+		}
+	}
+	s.lines[camera] = append(lines, line)
+	return s.save()
+}
+
+func (s *statisticsAggregator) deleteLine(camera string, name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	lines := s.lines[camera]
+	for i := range lines {
+		if lines[i].Name == name {
+			s.lines[camera] = append(lines[:i], lines[i+1:]...)
+			return s.save()
+		}
+	}
+	return errors.Errorf("line %s not found for camera %s", name, camera)
+}
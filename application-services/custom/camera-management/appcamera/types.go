@@ -43,11 +43,81 @@ type StreamingStatusResponse struct {
 	OutputVideoQuality string `json:"OutputVideoQuality"`
 }
 
+type USBImageFormatsResponse struct {
+	ImageFormats []USBImageFormat `json:"ImageFormats"`
+}
+type USBImageFormat struct {
+	BufType     int            `json:"BufType"`
+	Description string         `json:"Description"`
+	Flags       int            `json:"Flags"`
+	FrameSizes  []USBFrameSize `json:"FrameSizes"`
+	Index       int            `json:"Index"`
+	MbusCode    int            `json:"MbusCode"`
+	PixelFormat string         `json:"PixelFormat"`
+}
+type USBFrameSize struct {
+	FrameRates  []USBFrameRate   `json:"FrameRates"`
+	Index       int              `json:"Index"`
+	PixelFormat int64            `json:"PixelFormat"`
+	Size        USBFrameSizeSize `json:"Size"`
+	Type        int              `json:"Type"`
+}
+type USBFrameRate struct {
+	Denominator int `json:"Denominator"`
+	Numerator   int `json:"Numerator"`
+}
+type USBFrameSizeSize struct {
+	MaxHeight  int `json:"MaxHeight"`
+	MaxWidth   int `json:"MaxWidth"`
+	MinHeight  int `json:"MinHeight"`
+	MinWidth   int `json:"MinWidth"`
+	StepHeight int `json:"StepHeight"`
+	StepWidth  int `json:"StepWidth"`
+}
+
+// ErrorCode is the machine readable code of an ErrorResponse
+type ErrorCode string
+
+const (
+	// InvalidRequest is returned when the request does not match the OpenAPI specification, or is otherwise invalid
+	InvalidRequest ErrorCode = "InvalidRequest"
+	// UnsupportedStreamingMode is returned when a usb camera does not support the requested streaming mode
+	UnsupportedStreamingMode ErrorCode = "UnsupportedStreamingMode"
+	// PipelineAlreadyRunning is returned when starting a pipeline for a camera which already has one
+	PipelineAlreadyRunning ErrorCode = "PipelineAlreadyRunning"
+	// NotFound is returned when the resource does not exist
+	NotFound ErrorCode = "NotFound"
+	// NotLeader is returned when a request which is only served by the leader is sent to another instance
+	NotLeader ErrorCode = "NotLeader"
+	// CameraLocked is returned when the PTZ of the camera is locked by another operator
+	CameraLocked ErrorCode = "CameraLocked"
+	// PTZQueueFull is returned when too many PTZ commands are already queued for the camera
+	PTZQueueFull ErrorCode = "PTZQueueFull"
+	// NotProvisional is returned when approving a device which is not waiting to be approved
+	NotProvisional ErrorCode = "NotProvisional"
+	// UnknownPipeline is returned when starting a pipeline which is not in the catalog of EVAM
+	UnknownPipeline ErrorCode = "UnknownPipeline"
+	// MasksNotSupported is returned when starting a pipeline which cannot blur the privacy masks of the camera
+	MasksNotSupported ErrorCode = "MasksNotSupported"
+	// InternalError is returned when the request failed, usually because a camera, device service or EVAM failed
+	InternalError ErrorCode = "InternalError"
+)
+
+// ErrorResponse is the body of the error responses of all the routes
+type ErrorResponse struct {
+	ApiVersion string                 `json:"apiVersion"`
+	StatusCode int                    `json:"statusCode"`
+	Code       ErrorCode              `json:"code"`
+	Message    string                 `json:"message"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+}
+
 type CameraType string
 
 const (
 	USB     CameraType = "USB"
 	Onvif   CameraType = "Onvif"
+	RTSP    CameraType = "RTSP"
 	Unknown CameraType = "Unknown"
 )
 
@@ -58,21 +128,35 @@ type CameraFeatures struct {
 }
 
 type PipelineRequest struct {
-	Source      Source      `json:"source"`
-	Destination Destination `json:"destination"`
+	Source      Source                 `json:"source"`
+	Destination Destination            `json:"destination"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+	Tags        map[string]interface{} `json:"tags,omitempty"`
 }
 type Source struct {
 	URI  string `json:"uri"`
 	Type string `json:"type"`
 }
 type Metadata struct {
-	Type  string `json:"type"`
-	Host  string `json:"host"`
-	Topic string `json:"topic"`
+	Type     string       `json:"type"`
+	Host     string       `json:"host"`
+	Topic    string       `json:"topic"`
+	ClientId string       `json:"mqtt-client-id,omitempty"`
+	Username string       `json:"username,omitempty"`
+	Password string       `json:"password,omitempty"`
+	Tls      *MetadataTls `json:"tls,omitempty"`
+}
+
+// MetadataTls holds the paths of the certificates used by EVAM to connect to the metadata broker
+type MetadataTls struct {
+	CaCert     string `json:"ca_cert"`
+	ClientCert string `json:"client_cert,omitempty"`
+	ClientKey  string `json:"client_key,omitempty"`
 }
 type Frame struct {
-	Type string `json:"type"`
-	Path string `json:"path"`
+	Type   string `json:"type"`
+	Path   string `json:"path,omitempty"`
+	PeerId string `json:"peer-id,omitempty"`
 }
 type Destination struct {
 	Metadata Metadata `json:"metadata"`
@@ -116,6 +200,7 @@ type PipelineInformationResponse struct {
 				Class               string `json:"class"`
 				EncodeQuality       int    `json:"encode-quality"`
 				Path                string `json:"path"`
+				PeerId              string `json:"peer-id"`
 				SyncWithDestination bool   `json:"sync-with-destination"`
 				SyncWithSource      bool   `json:"sync-with-source"`
 				Type                string `json:"type"`
@@ -141,3 +226,51 @@ type PipelineInformationResponse struct {
 	} `json:"request"`
 	Type string `json:"type"`
 }
+
+type BoundingBox struct {
+	XMax float64 `json:"x_max"`
+	XMin float64 `json:"x_min"`
+	YMax float64 `json:"y_max"`
+	YMin float64 `json:"y_min"`
+}
+
+type Detection struct {
+	BoundingBox BoundingBox `json:"bounding_box"`
+	Confidence  float64     `json:"confidence"`
+	Label       string      `json:"label"`
+	LabelId     int         `json:"label_id"`
+}
+
+type InferenceObject struct {
+	Detection Detection `json:"detection"`
+	// Id is only set when the pipeline tracks the objects
+	Id      int    `json:"id,omitempty"`
+	H       int    `json:"h"`
+	RoiType string `json:"roi_type"`
+	W       int    `json:"w"`
+	X       int    `json:"x"`
+	Y       int    `json:"y"`
+}
+
+// position returns the bottom centre of the object's bounding box, which is where a person or vehicle
+// touches the ground.
+func (o InferenceObject) position() Point {
+	box := o.Detection.BoundingBox
+	return Point{
+		X: (box.XMin + box.XMax) / 2,
+		Y: box.YMax,
+	}
+}
+
+type Resolution struct {
+	Height int `json:"height"`
+	Width  int `json:"width"`
+}
+
+type InferenceEvent struct {
+	Objects    []InferenceObject      `json:"objects"`
+	Resolution Resolution             `json:"resolution"`
+	Source     string                 `json:"source"`
+	Tags       map[string]interface{} `json:"tags,omitempty"`
+	Timestamp  int64                  `json:"timestamp"`
+}
@@ -0,0 +1,402 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// frame size types reported by V4L2, any other type is a discrete frame size
+const (
+	frameSizeTypeContinuous = 2
+	frameSizeTypeStepwise   = 3
+)
+
+var imageSizeRegex = regexp.MustCompile(`^\s*(\d+)\s*[xX*]\s*(\d+)\s*$`)
+
+// USBImageSize is a discrete image size supported by a usb camera
+type USBImageSize struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// Fps are the frame rates the camera advertises for the size, which are unknown when empty
+	Fps []float64 `json:"fps,omitempty"`
+}
+
+// fpsTolerance is how far a requested frame rate may be from an advertised one, as frame rates such as
+// 30000/1001 are usually requested rounded
+const fpsTolerance = 0.01
+
+func (s USBImageSize) String() string {
+	return fmt.Sprintf("%dx%d", s.Width, s.Height)
+}
+
+// USBImageSizeRange is a range of image sizes supported by a usb camera
+type USBImageSizeRange struct {
+	MinWidth   int `json:"minWidth"`
+	MaxWidth   int `json:"maxWidth"`
+	StepWidth  int `json:"stepWidth"`
+	MinHeight  int `json:"minHeight"`
+	MaxHeight  int `json:"maxHeight"`
+	StepHeight int `json:"stepHeight"`
+}
+
+// USBStreamingMode lists the image sizes supported by a usb camera for a pixel format
+type USBStreamingMode struct {
+	PixelFormat string              `json:"pixelFormat"`
+	Description string              `json:"description"`
+	Sizes       []USBImageSize      `json:"sizes,omitempty"`
+	Ranges      []USBImageSizeRange `json:"ranges,omitempty"`
+}
+
+func (s USBImageSize) sameSize(other USBImageSize) bool {
+	return s.Width == other.Width && s.Height == other.Height
+}
+
+// supportsFps returns true if the frame rate is advertised for the size, or if its frame rates are unknown.
+func (s USBImageSize) supportsFps(fps float64) bool {
+	if len(s.Fps) == 0 {
+		return true
+	}
+	for _, f := range s.Fps {
+		if math.Abs(f-fps) <= fpsTolerance {
+			return true
+		}
+	}
+	return false
+}
+
+// supportsSize returns true if the image size is one of the discrete sizes, or is within one of the ranges.
+func (m USBStreamingMode) supportsSize(size USBImageSize) bool {
+	for _, s := range m.Sizes {
+		if s.sameSize(size) {
+			return true
+		}
+	}
+	for _, r := range m.Ranges {
+		if inRange(size.Width, r.MinWidth, r.MaxWidth, r.StepWidth) &&
+			inRange(size.Height, r.MinHeight, r.MaxHeight, r.StepHeight) {
+			return true
+		}
+	}
+	return false
+}
+
+// supportsFps returns true if the frame rate is supported for the image size, or for any size when size is nil.
+// The frame rates of the size ranges are unknown, so any frame rate is assumed to be supported by them.
+func (m USBStreamingMode) supportsFps(size *USBImageSize, fps float64) bool {
+	for _, s := range m.Sizes {
+		if (size == nil || s.sameSize(*size)) && s.supportsFps(fps) {
+			return true
+		}
+	}
+	for _, r := range m.Ranges {
+		if size == nil || (inRange(size.Width, r.MinWidth, r.MaxWidth, r.StepWidth) &&
+			inRange(size.Height, r.MinHeight, r.MaxHeight, r.StepHeight)) {
+			return true
+		}
+	}
+	return false
+}
+
+// closestSize returns the supported size which is the closest to the requested size.
+func (m USBStreamingMode) closestSize(size USBImageSize) (USBImageSize, bool) {
+	var closest USBImageSize
+	found := false
+	bestDistance := math.MaxFloat64
+	consider := func(candidate USBImageSize) {
+		distance := math.Hypot(float64(candidate.Width-size.Width), float64(candidate.Height-size.Height))
+		if distance < bestDistance {
+			bestDistance = distance
+			closest = candidate
+			found = true
+		}
+	}
+	for _, s := range m.Sizes {
+		consider(s)
+	}
+	for _, r := range m.Ranges {
+		consider(USBImageSize{
+			Width:  clampToStep(size.Width, r.MinWidth, r.MaxWidth, r.StepWidth),
+			Height: clampToStep(size.Height, r.MinHeight, r.MaxHeight, r.StepHeight),
+		})
+	}
+	return closest, found
+}
+
+func inRange(value, min, max, step int) bool {
+	if value < min || value > max {
+		return false
+	}
+	return step <= 1 || (value-min)%step == 0
+}
+
+func clampToStep(value, min, max, step int) int {
+	if value <= min {
+		return min
+	}
+	if value >= max {
+		return max
+	}
+	if step <= 1 {
+		return value
+	}
+	return min + int(math.Round(float64(value-min)/float64(step)))*step
+}
+
+// USBStreamingValidationError is returned when a usb start streaming request is not supported by the camera
+type USBStreamingValidationError struct {
+	Reason string
+	// Suggestion is the closest supported request, if any
+	Suggestion *USBStartStreamingRequest
+}
+
+func (e *USBStreamingValidationError) Error() string {
+	if e.Suggestion == nil {
+		return e.Reason
+	}
+	if e.Suggestion.InputFps != "" {
+		return fmt.Sprintf("%s, closest supported mode is InputPixelFormat=%s InputImageSize=%s InputFps=%s",
+			e.Reason, e.Suggestion.InputPixelFormat, e.Suggestion.InputImageSize, e.Suggestion.InputFps)
+	}
+	return fmt.Sprintf("%s, closest supported mode is InputPixelFormat=%s InputImageSize=%s",
+		e.Reason, e.Suggestion.InputPixelFormat, e.Suggestion.InputImageSize)
+}
+
+// getUSBStreamingModes returns the pixel formats and image sizes supported by a usb camera.
+func (app *CameraManagementApp) getUSBStreamingModes(deviceName string) ([]USBStreamingMode, error) {
+	resp := USBImageFormatsResponse{}
+	if err := app.issueGetCommandForResponse(context.Background(), deviceName, usbImageFormatsCommand, &resp); err != nil {
+		return nil, err
+	}
+
+	modes := make([]USBStreamingMode, 0, len(resp.ImageFormats))
+	for _, format := range resp.ImageFormats {
+		mode := USBStreamingMode{
+			PixelFormat: format.PixelFormat,
+			Description: format.Description,
+		}
+		for _, fs := range format.FrameSizes {
+			switch fs.Type {
+			case frameSizeTypeContinuous, frameSizeTypeStepwise:
+				mode.Ranges = append(mode.Ranges, USBImageSizeRange{
+					MinWidth:   fs.Size.MinWidth,
+					MaxWidth:   fs.Size.MaxWidth,
+					StepWidth:  fs.Size.StepWidth,
+					MinHeight:  fs.Size.MinHeight,
+					MaxHeight:  fs.Size.MaxHeight,
+					StepHeight: fs.Size.StepHeight,
+				})
+			default:
+				size := USBImageSize{Width: fs.Size.MaxWidth, Height: fs.Size.MaxHeight}
+				for _, rate := range fs.FrameRates {
+					if rate.Numerator > 0 && rate.Denominator > 0 {
+						size.Fps = append(size.Fps, float64(rate.Numerator)/float64(rate.Denominator))
+					}
+				}
+				mode.Sizes = append(mode.Sizes, size)
+			}
+		}
+		modes = append(modes, mode)
+	}
+	return modes, nil
+}
+
+// validateUSBStartStreamingRequest checks the request against the modes supported by the camera, and returns
+// a normalized copy of it. Any unsupported combination results in a USBStreamingValidationError suggesting
+// the closest supported mode.
+func validateUSBStartStreamingRequest(modes []USBStreamingMode, req USBStartStreamingRequest) (USBStartStreamingRequest, error) {
+	var err error
+	if req.InputFps, err = normalizeFps("InputFps", req.InputFps); err != nil {
+		return req, err
+	}
+	if req.OutputFps, err = normalizeFps("OutputFps", req.OutputFps); err != nil {
+		return req, err
+	}
+	if req.OutputImageSize != "" {
+		size, err := parseImageSize("OutputImageSize", req.OutputImageSize)
+		if err != nil {
+			return req, err
+		}
+		req.OutputImageSize = size.String()
+	}
+
+	if len(modes) == 0 || (req.InputPixelFormat == "" && req.InputImageSize == "" && req.InputFps == "") {
+		// let the device service pick its defaults
+		return req, nil
+	}
+
+	var size *USBImageSize
+	if req.InputImageSize != "" {
+		s, err := parseImageSize("InputImageSize", req.InputImageSize)
+		if err != nil {
+			return req, err
+		}
+		size = &s
+		req.InputImageSize = s.String()
+	}
+
+	// find the requested pixel format, if any
+	candidates := modes
+	if req.InputPixelFormat != "" {
+		candidates = nil
+		for _, m := range modes {
+			if strings.EqualFold(m.PixelFormat, req.InputPixelFormat) {
+				candidates = []USBStreamingMode{m}
+				req.InputPixelFormat = m.PixelFormat
+				break
+			}
+		}
+		if candidates == nil {
+			return req, &USBStreamingValidationError{
+				Reason:     fmt.Sprintf("unsupported InputPixelFormat '%s'", req.InputPixelFormat),
+				Suggestion: suggestUSBMode(modes, size),
+			}
+		}
+	}
+
+	if size != nil {
+		var supported []USBStreamingMode
+		for _, m := range candidates {
+			if m.supportsSize(*size) {
+				supported = append(supported, m)
+			}
+		}
+		if len(supported) == 0 {
+			return req, &USBStreamingValidationError{
+				Reason:     fmt.Sprintf("unsupported InputImageSize '%s' for InputPixelFormat '%s'", req.InputImageSize, req.InputPixelFormat),
+				Suggestion: suggestUSBMode(candidates, size),
+			}
+		}
+		candidates = supported
+	}
+
+	if req.InputFps != "" {
+		// the fps was normalized above
+		fps, _ := strconv.ParseFloat(req.InputFps, 64)
+		var supported []USBStreamingMode
+		for _, m := range candidates {
+			if m.supportsFps(size, fps) {
+				supported = append(supported, m)
+			}
+		}
+		if len(supported) == 0 {
+			return req, &USBStreamingValidationError{
+				Reason: fmt.Sprintf("unsupported InputFps '%s' for InputPixelFormat '%s' and InputImageSize '%s'",
+					req.InputFps, req.InputPixelFormat, req.InputImageSize),
+				Suggestion: suggestUSBFps(candidates, size, fps),
+			}
+		}
+		candidates = supported
+	}
+
+	if req.InputPixelFormat == "" && size != nil {
+		req.InputPixelFormat = candidates[0].PixelFormat
+	}
+	return req, nil
+}
+
+// suggestUSBFps returns the advertised frame rate which is the closest to the requested one, for the
+// requested image size or for any size when size is nil.
+func suggestUSBFps(modes []USBStreamingMode, size *USBImageSize, fps float64) *USBStartStreamingRequest {
+	var suggestion *USBStartStreamingRequest
+	bestDistance := math.MaxFloat64
+	for _, m := range modes {
+		for _, s := range m.Sizes {
+			if size != nil && !s.sameSize(*size) {
+				continue
+			}
+			for _, f := range s.Fps {
+				if distance := math.Abs(f - fps); distance < bestDistance {
+					bestDistance = distance
+					suggestion = &USBStartStreamingRequest{
+						InputPixelFormat: m.PixelFormat,
+						InputImageSize:   s.String(),
+						// rounded, as fractional frame rates such as 30000/1001 are accepted within the tolerance
+						InputFps: strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64),
+					}
+				}
+			}
+		}
+	}
+	return suggestion
+}
+
+// suggestUSBMode returns the mode which has the closest image size to the requested one, or the first
+// size of the first mode if no size was requested.
+func suggestUSBMode(modes []USBStreamingMode, size *USBImageSize) *USBStartStreamingRequest {
+	target := USBImageSize{}
+	if size != nil {
+		target = *size
+	}
+	var suggestion *USBStartStreamingRequest
+	bestDistance := math.MaxFloat64
+	for _, m := range modes {
+		closest, found := m.closestSize(target)
+		if !found {
+			continue
+		}
+		distance := math.Hypot(float64(closest.Width-target.Width), float64(closest.Height-target.Height))
+		if size == nil || distance < bestDistance {
+			bestDistance = distance
+			suggestion = &USBStartStreamingRequest{
+				InputPixelFormat: m.PixelFormat,
+				InputImageSize:   closest.String(),
+			}
+			if size == nil {
+				break
+			}
+		}
+	}
+	return suggestion
+}
+
+func parseImageSize(field string, value string) (USBImageSize, error) {
+	match := imageSizeRegex.FindStringSubmatch(value)
+	if match == nil {
+		return USBImageSize{}, &USBStreamingValidationError{
+			Reason: fmt.Sprintf("invalid %s '%s', must be in the form <width>x<height>", field, value),
+		}
+	}
+	// the regex guarantees these are numbers
+	width, _ := strconv.Atoi(match[1])
+	height, _ := strconv.Atoi(match[2])
+	if width == 0 || height == 0 {
+		return USBImageSize{}, &USBStreamingValidationError{
+			Reason: fmt.Sprintf("invalid %s '%s', width and height must be greater than zero", field, value),
+		}
+	}
+	return USBImageSize{Width: width, Height: height}, nil
+}
+
+func normalizeFps(field string, value string) (string, error) {
+	if value == "" {
+		return value, nil
+	}
+	fps, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || fps <= 0 || math.IsInf(fps, 0) || math.IsNaN(fps) {
+		return value, &USBStreamingValidationError{
+			Reason: fmt.Sprintf("invalid %s '%s', must be a positive number", field, value),
+		}
+	}
+	return strconv.FormatFloat(fps, 'f', -1, 64), nil
+}
+
+// asUSBStreamingValidationError returns the USBStreamingValidationError if the error is caused by an
+// unsupported start streaming request
+func asUSBStreamingValidationError(err error) (*USBStreamingValidationError, bool) {
+	var validationErr *USBStreamingValidationError
+	if errors.As(err, &validationErr) {
+		return validationErr, true
+	}
+	return nil, false
+}
@@ -0,0 +1,129 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testUSBModes = []USBStreamingMode{
+	{
+		PixelFormat: "YUYV",
+		Sizes: []USBImageSize{
+			{Width: 640, Height: 480, Fps: []float64{30, 15}},
+			{Width: 1280, Height: 720, Fps: []float64{10, 5}},
+		},
+	},
+	{
+		PixelFormat: "MJPG",
+		Sizes: []USBImageSize{
+			{Width: 640, Height: 480, Fps: []float64{60, 30000.0 / 1001}},
+			{Width: 1920, Height: 1080, Fps: []float64{30}},
+			// the frame rates of this size are unknown
+			{Width: 320, Height: 240},
+		},
+	},
+	{
+		PixelFormat: "GREY",
+		Ranges: []USBImageSizeRange{
+			{MinWidth: 160, MaxWidth: 800, StepWidth: 16, MinHeight: 120, MaxHeight: 600, StepHeight: 8},
+		},
+	},
+}
+
+func TestValidateUSBStartStreamingRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		modes      []USBStreamingMode
+		request    USBStartStreamingRequest
+		expected   USBStartStreamingRequest
+		suggestion *USBStartStreamingRequest
+		invalid    bool
+	}{
+		{"empty request", testUSBModes, USBStartStreamingRequest{}, USBStartStreamingRequest{}, nil, false},
+		{"no modes", nil, USBStartStreamingRequest{InputImageSize: "1x1", InputFps: "1000"},
+			USBStartStreamingRequest{InputImageSize: "1x1", InputFps: "1000"}, nil, false},
+		{"format is case insensitive", testUSBModes, USBStartStreamingRequest{InputPixelFormat: "mjpg"},
+			USBStartStreamingRequest{InputPixelFormat: "MJPG"}, nil, false},
+		{"size picks first format", testUSBModes, USBStartStreamingRequest{InputImageSize: "640 X 480"},
+			USBStartStreamingRequest{InputPixelFormat: "YUYV", InputImageSize: "640x480"}, nil, false},
+		{"size and fps pick matching format", testUSBModes, USBStartStreamingRequest{InputImageSize: "640x480", InputFps: "60"},
+			USBStartStreamingRequest{InputPixelFormat: "MJPG", InputImageSize: "640x480", InputFps: "60"}, nil, false},
+		{"fractional fps within tolerance", testUSBModes,
+			USBStartStreamingRequest{InputPixelFormat: "MJPG", InputImageSize: "640x480", InputFps: "29.97"},
+			USBStartStreamingRequest{InputPixelFormat: "MJPG", InputImageSize: "640x480", InputFps: "29.97"}, nil, false},
+		{"fps is normalized", testUSBModes, USBStartStreamingRequest{InputPixelFormat: "YUYV", InputImageSize: "640x480", InputFps: " 15.0 "},
+			USBStartStreamingRequest{InputPixelFormat: "YUYV", InputImageSize: "640x480", InputFps: "15"}, nil, false},
+		{"fps only", testUSBModes, USBStartStreamingRequest{InputFps: "10"},
+			USBStartStreamingRequest{InputFps: "10"}, nil, false},
+		{"unknown frame rates accept any fps", testUSBModes,
+			USBStartStreamingRequest{InputPixelFormat: "MJPG", InputImageSize: "320x240", InputFps: "25"},
+			USBStartStreamingRequest{InputPixelFormat: "MJPG", InputImageSize: "320x240", InputFps: "25"}, nil, false},
+		{"range size on step", testUSBModes, USBStartStreamingRequest{InputPixelFormat: "GREY", InputImageSize: "176x128", InputFps: "90"},
+			USBStartStreamingRequest{InputPixelFormat: "GREY", InputImageSize: "176x128", InputFps: "90"}, nil, false},
+		{"range size off step", testUSBModes, USBStartStreamingRequest{InputPixelFormat: "GREY", InputImageSize: "170x128"},
+			USBStartStreamingRequest{}, &USBStartStreamingRequest{InputPixelFormat: "GREY", InputImageSize: "176x128"}, true},
+		{"unknown format", testUSBModes, USBStartStreamingRequest{InputPixelFormat: "H264", InputImageSize: "1280x720"},
+			USBStartStreamingRequest{}, &USBStartStreamingRequest{InputPixelFormat: "YUYV", InputImageSize: "1280x720"}, true},
+		{"unsupported size", testUSBModes, USBStartStreamingRequest{InputPixelFormat: "YUYV", InputImageSize: "1300x700"},
+			USBStartStreamingRequest{}, &USBStartStreamingRequest{InputPixelFormat: "YUYV", InputImageSize: "1280x720"}, true},
+		{"fps not advertised for size", testUSBModes, USBStartStreamingRequest{InputPixelFormat: "YUYV", InputImageSize: "1280x720", InputFps: "30"},
+			USBStartStreamingRequest{}, &USBStartStreamingRequest{InputPixelFormat: "YUYV", InputImageSize: "1280x720", InputFps: "10"}, true},
+		{"fps not advertised for any size of format", testUSBModes, USBStartStreamingRequest{InputPixelFormat: "YUYV", InputFps: "59"},
+			USBStartStreamingRequest{}, &USBStartStreamingRequest{InputPixelFormat: "YUYV", InputImageSize: "640x480", InputFps: "30"}, true},
+		{"fps suggestion is rounded", testUSBModes, USBStartStreamingRequest{InputPixelFormat: "MJPG", InputImageSize: "640x480", InputFps: "29"},
+			USBStartStreamingRequest{}, &USBStartStreamingRequest{InputPixelFormat: "MJPG", InputImageSize: "640x480", InputFps: "29.97"}, true},
+		{"invalid fps", testUSBModes, USBStartStreamingRequest{InputFps: "-1"}, USBStartStreamingRequest{}, nil, true},
+		{"invalid size", testUSBModes, USBStartStreamingRequest{InputImageSize: "640"}, USBStartStreamingRequest{}, nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := validateUSBStartStreamingRequest(test.modes, test.request)
+			if test.invalid {
+				require.Error(t, err)
+				validationErr, ok := asUSBStreamingValidationError(err)
+				require.True(t, ok)
+				assert.Equal(t, test.suggestion, validationErr.Suggestion)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestUSBStreamingModeSupportsFps(t *testing.T) {
+	yuyv, mjpg, grey := testUSBModes[0], testUSBModes[1], testUSBModes[2]
+	vga := &USBImageSize{Width: 640, Height: 480}
+	hd := &USBImageSize{Width: 1280, Height: 720}
+
+	tests := []struct {
+		name     string
+		mode     USBStreamingMode
+		size     *USBImageSize
+		fps      float64
+		expected bool
+	}{
+		{"advertised", yuyv, vga, 30, true},
+		{"advertised for another size", yuyv, hd, 30, false},
+		{"any size", yuyv, nil, 10, true},
+		{"no size advertises it", yuyv, nil, 60, false},
+		{"within tolerance", mjpg, vga, 29.97, true},
+		{"outside tolerance", mjpg, vga, 29.9, false},
+		{"size not supported", mjpg, hd, 30, false},
+		{"unknown frame rates", mjpg, &USBImageSize{Width: 320, Height: 240}, 7, true},
+		{"range", grey, &USBImageSize{Width: 800, Height: 600}, 120, true},
+		{"outside range", grey, &USBImageSize{Width: 801, Height: 600}, 120, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.mode.supportsFps(test.size, test.fps))
+		})
+	}
+}
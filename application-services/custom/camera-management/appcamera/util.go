@@ -12,6 +12,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"os"
+	"strings"
 
 	"github.com/edgexfoundry/go-mod-core-contracts/v3/clients/http/utils"
 	"github.com/edgexfoundry/go-mod-core-contracts/v3/clients/logger"
@@ -78,6 +81,28 @@ func issuePostRequest(ctx context.Context, res interface{}, baseUrl string, reqP
 	return utils.PostRequest(ctx, &res, baseUrl, reqPath, jsonValue, common.ContentTypeJSON, nil)
 }
 
+// redactedCredential replaces the credentials in the redacted error messages
+const redactedCredential = "<redacted>"
+
+// redactCredentials removes the credentials from the error message, including their url encoded forms.
+func redactCredentials(err error, credentials []string) error {
+	msg := err.Error()
+	for _, credential := range credentials {
+		if credential == "" {
+			continue
+		}
+		msg = strings.NewReplacer(
+			credential, redactedCredential,
+			url.PathEscape(credential), redactedCredential,
+			url.QueryEscape(credential), redactedCredential,
+		).Replace(msg)
+	}
+	if msg == err.Error() {
+		return err
+	}
+	return errors.New(msg)
+}
+
 func issueGetRequest(ctx context.Context, res interface{}, baseUrl string, requestPath string) (err error) {
 	return utils.GetRequest(ctx, &res, baseUrl, requestPath, nil, nil)
 }
@@ -86,16 +111,49 @@ func issueDeleteRequest(ctx context.Context, res interface{}, baseUrl string, re
 	return utils.DeleteRequest(ctx, &res, baseUrl, requestPath, nil)
 }
 
+// respondError writes an ErrorResponse with the default code of the status code.
 func respondError(lc logger.LoggingClient, w http.ResponseWriter, statusCode int, errStr string) {
+	respondErrorCode(lc, w, statusCode, defaultErrorCode(statusCode), errStr, nil)
+}
+
+// respondErrorCode writes an ErrorResponse, details are optional.
+func respondErrorCode(lc logger.LoggingClient, w http.ResponseWriter, statusCode int, code ErrorCode, errStr string,
+	details map[string]interface{}) {
+
 	lc.Error(errStr)
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	b, err := json.Marshal(ErrorResponse{
+		ApiVersion: common.ApiVersion,
+		StatusCode: statusCode,
+		Code:       code,
+		Message:    errStr,
+		Details:    details,
+	})
+	if err != nil {
+		lc.Errorf("failed to marshal error response: %v", err)
+		b = []byte(errStr)
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.Header().Set("X-Content-Type-Options", "nosniff")
 	w.WriteHeader(statusCode)
-	if _, writeErr := w.Write([]byte(errStr)); writeErr != nil {
+	if _, writeErr := w.Write(b); writeErr != nil {
 		lc.Error(writeErr.Error())
 	}
 }
 
+func defaultErrorCode(statusCode int) ErrorCode {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return InvalidRequest
+	case http.StatusNotFound:
+		return NotFound
+	case http.StatusServiceUnavailable:
+		return NotLeader
+	default:
+		return InternalError
+	}
+}
+
 func respondJson(lc logger.LoggingClient, w http.ResponseWriter, val interface{}) {
 	lc.Debugf("response: %+v\n", val)
 	b, err := json.Marshal(val)
@@ -147,3 +205,35 @@ func (app *CameraManagementApp) getDeviceByName(deviceName string) (dtos.Device,
 	}
 	return resp.Device, nil
 }
+
+// readJSONFile unmarshalls the contents of the json file into v. A missing file is not an error, and leaves v untouched.
+func readJSONFile(filename string, v interface{}) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "failed to read file %s", filename)
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return errors.Wrapf(err, "failed to parse json file %s", filename)
+	}
+	return nil
+}
+
+// writeJSONFile replaces the contents of the file with the json representation of v. It writes to a
+// temporary file first, so that a failure does not corrupt the existing file.
+func writeJSONFile(filename string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal %T to json", v)
+	}
+	tmp := filename + ".tmp"
+	if err = os.WriteFile(tmp, data, 0600); err != nil {
+		return errors.Wrapf(err, "failed to write file %s", tmp)
+	}
+	if err = os.Rename(tmp, filename); err != nil {
+		return errors.Wrapf(err, "failed to replace file %s", filename)
+	}
+	return nil
+}
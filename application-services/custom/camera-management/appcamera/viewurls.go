@@ -0,0 +1,133 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// rtspOutput makes EVAM publish the inference results through its RTSP server
+	rtspOutput = "rtsp"
+	// webrtcOutput makes EVAM publish the inference results through its WebRTC signaling server
+	webrtcOutput = "webrtc"
+
+	defaultRtspPort = 8555
+
+	hostPlaceholder   = "{host}"
+	cameraPlaceholder = "{camera}"
+)
+
+// ViewUrls are the urls the inference results of a pipeline can be viewed at. Only the urls supported
+// by the configured output are set.
+type ViewUrls struct {
+	Rtsp   string `json:"rtsp,omitempty"`
+	WebRTC string `json:"webrtc,omitempty"`
+	HLS    string `json:"hls,omitempty"`
+}
+
+func validateStreamOutput(output StreamOutputConfig) error {
+	switch output.Protocol {
+	case "", rtspOutput, webrtcOutput:
+	default:
+		return errors.Errorf("invalid StreamOutput Protocol '%s', must be one of %s or %s",
+			output.Protocol, rtspOutput, webrtcOutput)
+	}
+	if output.RtspPort < 0 || output.RtspPort > 65535 {
+		return errors.Errorf("invalid StreamOutput RtspPort %d", output.RtspPort)
+	}
+	for name, template := range map[string]string{"WebRTCUrl": output.WebRTCUrl, "HLSUrl": output.HLSUrl} {
+		if template == "" {
+			continue
+		}
+		if _, err := url.Parse(expandViewUrl(template, "localhost", "camera")); err != nil {
+			return errors.Wrapf(err, "invalid StreamOutput %s '%s'", name, template)
+		}
+	}
+	return nil
+}
+
+// streamOutputProtocol returns the frame destination type requested from EVAM.
+func (app *CameraManagementApp) streamOutputProtocol() string {
+	if protocol := app.appConfig().StreamOutput.Protocol; protocol != "" {
+		return protocol
+	}
+	return rtspOutput
+}
+
+// streamOutputFrame returns the frame destination of the pipeline request for the specified camera.
+func (app *CameraManagementApp) streamOutputFrame(deviceName string) Frame {
+	if app.streamOutputProtocol() == webrtcOutput {
+		return Frame{Type: webrtcOutput, PeerId: deviceName}
+	}
+	return Frame{Type: rtspOutput, Path: deviceName}
+}
+
+// streamOutputHost returns the host clients use to view the output streams, which defaults to the host of EVAM.
+// It is empty when neither is set.
+func (app *CameraManagementApp) streamOutputHost() string {
+	if host := app.appConfig().StreamOutput.Host; host != "" {
+		return host
+	}
+	if u, err := url.Parse(app.appConfig().EvamBaseUrl); err == nil && u.Hostname() != "" {
+		return u.Hostname()
+	}
+	return ""
+}
+
+// getViewUrls returns the urls the inference results of the specified camera can be viewed at. The urls
+// which need the host are left empty when it is unknown, as the clients are usually not on the same host.
+func (app *CameraManagementApp) getViewUrls(deviceName string) ViewUrls {
+	output := app.appConfig().StreamOutput
+	host := app.streamOutputHost()
+
+	urls := ViewUrls{}
+	if host == "" {
+		app.lc.Warnf("Unable to determine the view urls host of camera %s, set StreamOutput Host", deviceName)
+		output.WebRTCUrl = withoutHostPlaceholder(output.WebRTCUrl)
+		output.HLSUrl = withoutHostPlaceholder(output.HLSUrl)
+	} else if app.streamOutputProtocol() == rtspOutput {
+		port := output.RtspPort
+		if port == 0 {
+			port = defaultRtspPort
+		}
+		urls.Rtsp = fmt.Sprintf("rtsp://%s/%s", joinHostPort(host, port), url.PathEscape(deviceName))
+	}
+	if output.WebRTCUrl != "" {
+		urls.WebRTC = expandViewUrl(output.WebRTCUrl, host, deviceName)
+	}
+	if output.HLSUrl != "" {
+		urls.HLS = expandViewUrl(output.HLSUrl, host, deviceName)
+	}
+	return urls
+}
+
+// withoutHostPlaceholder returns the template, or an empty string if it needs the host.
+func withoutHostPlaceholder(template string) string {
+	if strings.Contains(template, hostPlaceholder) {
+		return ""
+	}
+	return template
+}
+
+func expandViewUrl(template string, host string, deviceName string) string {
+	return strings.NewReplacer(
+		hostPlaceholder, host,
+		cameraPlaceholder, url.PathEscape(deviceName),
+	).Replace(template)
+}
+
+func joinHostPort(host string, port int) string {
+	// ipv6 addresses must be wrapped in brackets
+	if strings.Contains(host, ":") && !strings.HasPrefix(host, "[") {
+		host = "[" + host + "]"
+	}
+	return fmt.Sprintf("%s:%d", host, port)
+}
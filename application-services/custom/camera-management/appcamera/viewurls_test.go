@@ -0,0 +1,41 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package appcamera
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetViewUrls(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   CustomConfig
+		expected ViewUrls
+	}{
+		{"host of evam", CustomConfig{EvamBaseUrl: "http://evam:8080"},
+			ViewUrls{Rtsp: "rtsp://evam:8555/my%20camera"}},
+		{"configured host and port", CustomConfig{EvamBaseUrl: "http://evam:8080",
+			StreamOutput: StreamOutputConfig{Host: "192.168.1.10", RtspPort: 9000}},
+			ViewUrls{Rtsp: "rtsp://192.168.1.10:9000/my%20camera"}},
+		{"ipv6 host", CustomConfig{StreamOutput: StreamOutputConfig{Host: "fe80::1"}},
+			ViewUrls{Rtsp: "rtsp://[fe80::1]:8555/my%20camera"}},
+		{"webrtc and hls", CustomConfig{EvamBaseUrl: "http://evam:8080", StreamOutput: StreamOutputConfig{
+			Protocol: webrtcOutput, WebRTCUrl: "http://{host}:8082/?destination_peer_id={camera}", HLSUrl: "http://{host}/hls/{camera}.m3u8"}},
+			ViewUrls{WebRTC: "http://evam:8082/?destination_peer_id=my%20camera", HLS: "http://evam/hls/my%20camera.m3u8"}},
+		{"unknown host", CustomConfig{EvamBaseUrl: "/relative"}, ViewUrls{}},
+		{"unknown host keeps urls without placeholder", CustomConfig{StreamOutput: StreamOutputConfig{
+			WebRTCUrl: "http://viewer.example.com/{camera}", HLSUrl: "http://{host}/hls/{camera}.m3u8"}},
+			ViewUrls{WebRTC: "http://viewer.example.com/my%20camera"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := newTestApp(test.config)
+			assert.Equal(t, test.expected, app.getViewUrls("my camera"))
+		})
+	}
+}
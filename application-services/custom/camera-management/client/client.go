@@ -0,0 +1,305 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package client is a typed client of the camera management app service routes, sharing the
+// request and response types of the app service.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/IOTechSystems/onvif/media"
+	"github.com/IOTechSystems/onvif/ptz"
+	"github.com/edgexfoundry/edgex-examples/application-services/custom/camera-management/appcamera"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
+	dtosCommon "github.com/edgexfoundry/go-mod-core-contracts/v3/dtos/common"
+	"github.com/pkg/errors"
+)
+
+// DefaultBaseUrl is the url of the app service when running locally
+const DefaultBaseUrl = "http://localhost:59750"
+
+// Error is returned when the app service responds with an error
+type Error struct {
+	appcamera.ErrorResponse
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Client calls the routes of the camera management app service
+type Client struct {
+	baseUrl    string
+	httpClient *http.Client
+	operator   string
+}
+
+// NewClient creates a client of the app service at baseUrl. The default http client is used if
+// httpClient is nil.
+func NewClient(baseUrl string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		baseUrl:    strings.TrimSuffix(baseUrl, "/"),
+		httpClient: httpClient,
+	}
+}
+
+// SetOperator sets the operator sending the PTZ commands, which is the owner of the PTZ locks taken
+// by this client.
+func (c *Client) SetOperator(operator string) {
+	c.operator = operator
+}
+
+// Cameras returns the cameras of all the supported device services.
+func (c *Client) Cameras(ctx context.Context) ([]dtos.Device, error) {
+	var res []dtos.Device
+	_, err := c.do(ctx, http.MethodGet, nil, &res, "cameras")
+	return res, err
+}
+
+// Inventory returns everything known about all the cameras. The app service cache is bypassed if refresh is true.
+func (c *Client) Inventory(ctx context.Context, refresh bool) ([]appcamera.CameraInventoryItem, error) {
+	var res []appcamera.CameraInventoryItem
+	_, err := c.doWithQuery(ctx, http.MethodGet, refreshQuery(refresh), nil, &res, "inventory")
+	return res, err
+}
+
+// CameraInventory returns everything known about a camera. The app service cache is bypassed if refresh is true.
+func (c *Client) CameraInventory(ctx context.Context, camera string, refresh bool) (appcamera.CameraInventoryItem, error) {
+	var res appcamera.CameraInventoryItem
+	_, err := c.doWithQuery(ctx, http.MethodGet, refreshQuery(refresh), nil, &res, "cameras", camera, "inventory")
+	return res, err
+}
+
+func refreshQuery(refresh bool) url.Values {
+	if !refresh {
+		return nil
+	}
+	return url.Values{"refresh": []string{"true"}}
+}
+
+// CameraFeatures returns the capabilities of a camera.
+func (c *Client) CameraFeatures(ctx context.Context, camera string) (appcamera.CameraFeatures, error) {
+	var res appcamera.CameraFeatures
+	_, err := c.do(ctx, http.MethodGet, nil, &res, "cameras", camera, "features")
+	return res, err
+}
+
+// Profiles returns the media profiles of an Onvif camera.
+func (c *Client) Profiles(ctx context.Context, camera string) (media.GetProfilesResponse, error) {
+	var res media.GetProfilesResponse
+	_, err := c.do(ctx, http.MethodGet, nil, &res, "cameras", camera, "profiles")
+	return res, err
+}
+
+// StreamingModes returns the pixel formats and image sizes supported by a USB camera.
+func (c *Client) StreamingModes(ctx context.Context, camera string) ([]appcamera.USBStreamingMode, error) {
+	var res []appcamera.USBStreamingMode
+	_, err := c.do(ctx, http.MethodGet, nil, &res, "cameras", camera, "streamingmodes")
+	return res, err
+}
+
+// PTZ moves an Onvif camera relatively to its current position, action is one of left, right, up, up-left,
+// up-right, down, down-left, down-right, zoom-in or zoom-out.
+func (c *Client) PTZ(ctx context.Context, camera string, profile string, action string) (dtosCommon.BaseResponse, error) {
+	var res dtosCommon.BaseResponse
+	_, err := c.do(ctx, http.MethodPost, nil, &res, "cameras", camera, "profiles", profile, "ptz", action)
+	return res, err
+}
+
+// Presets returns the PTZ presets of an Onvif camera profile.
+func (c *Client) Presets(ctx context.Context, camera string, profile string) (ptz.GetPresetsResponse, error) {
+	var res ptz.GetPresetsResponse
+	_, err := c.do(ctx, http.MethodGet, nil, &res, "cameras", camera, "profiles", profile, "presets")
+	return res, err
+}
+
+// GotoPreset moves an Onvif camera to a PTZ preset.
+func (c *Client) GotoPreset(ctx context.Context, camera string, profile string, preset string) (dtosCommon.BaseResponse, error) {
+	var res dtosCommon.BaseResponse
+	_, err := c.do(ctx, http.MethodPost, nil, &res, "cameras", camera, "profiles", profile, "presets", preset)
+	return res, err
+}
+
+// PTZLock returns the PTZ lock of a camera, or nil if it is not locked.
+func (c *Client) PTZLock(ctx context.Context, camera string) (*appcamera.PTZLock, error) {
+	var res appcamera.PTZLock
+	found, err := c.do(ctx, http.MethodGet, nil, &res, "cameras", camera, "ptz", "lock")
+	if err != nil || !found {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// LockPTZ locks the PTZ of a camera for the operator, or renews the lock. The default duration of the
+// app service is used if duration is empty.
+func (c *Client) LockPTZ(ctx context.Context, camera string, duration string) (appcamera.PTZLock, error) {
+	var res appcamera.PTZLock
+	req := appcamera.PTZLockRequest{Owner: c.operator, Duration: duration}
+	_, err := c.do(ctx, http.MethodPut, req, &res, "cameras", camera, "ptz", "lock")
+	return res, err
+}
+
+// UnlockPTZ releases the PTZ lock of a camera held by the operator, or any lock if force is true.
+func (c *Client) UnlockPTZ(ctx context.Context, camera string, force bool) error {
+	var query url.Values
+	if force {
+		query = url.Values{"force": []string{"true"}}
+	}
+	_, err := c.doWithQuery(ctx, http.MethodDelete, query, nil, nil, "cameras", camera, "ptz", "lock")
+	return err
+}
+
+// Discover triggers the discovery of new cameras on the device service, or on all the device services
+// supporting discovery if service is empty.
+func (c *Client) Discover(ctx context.Context, service string) (appcamera.DiscoveryResponse, error) {
+	var res appcamera.DiscoveryResponse
+	var query url.Values
+	if service != "" {
+		query = url.Values{"service": []string{service}}
+	}
+	_, err := c.doWithQuery(ctx, http.MethodPost, query, nil, &res, "discovery")
+	return res, err
+}
+
+// ProvisionalDevices returns the discovered cameras waiting to be approved.
+func (c *Client) ProvisionalDevices(ctx context.Context) ([]dtos.Device, error) {
+	var res []dtos.Device
+	_, err := c.do(ctx, http.MethodGet, nil, &res, "discovery", "devices")
+	return res, err
+}
+
+// ApproveDevice approves a discovered camera, which starts its pipeline.
+func (c *Client) ApproveDevice(ctx context.Context, camera string, req appcamera.ApproveDeviceRequest) (appcamera.ApproveDeviceResponse, error) {
+	var res appcamera.ApproveDeviceResponse
+	_, err := c.do(ctx, http.MethodPost, req, &res, "discovery", "devices", camera, "approve")
+	return res, err
+}
+
+// PipelineTemplates returns the pipelines which can be started for a camera.
+func (c *Client) PipelineTemplates(ctx context.Context) ([]appcamera.CatalogPipeline, error) {
+	var res []appcamera.CatalogPipeline
+	_, err := c.do(ctx, http.MethodGet, nil, &res, "pipelines")
+	return res, err
+}
+
+// Catalog returns the pipelines and models available in EVAM. The app service cache is bypassed if refresh is true.
+func (c *Client) Catalog(ctx context.Context, refresh bool) (appcamera.PipelineCatalog, error) {
+	var res appcamera.PipelineCatalog
+	_, err := c.doWithQuery(ctx, http.MethodGet, refreshQuery(refresh), nil, &res, "catalog")
+	return res, err
+}
+
+// StartPipeline starts a pipeline for a camera.
+func (c *Client) StartPipeline(ctx context.Context, camera string, req appcamera.StartPipelineRequest) error {
+	_, err := c.do(ctx, http.MethodPost, req, nil, "cameras", camera, "pipeline", "start")
+	return err
+}
+
+// StopPipeline stops the pipeline of a camera.
+func (c *Client) StopPipeline(ctx context.Context, camera string, id string) error {
+	_, err := c.do(ctx, http.MethodPost, nil, nil, "cameras", camera, "pipeline", "stop", id)
+	return err
+}
+
+// Pipeline returns the pipeline of a camera along with its status, or nil if no pipeline is running.
+func (c *Client) Pipeline(ctx context.Context, camera string) (*appcamera.PipelineInfoStatus, error) {
+	var res appcamera.PipelineInfoStatus
+	found, err := c.do(ctx, http.MethodGet, nil, &res, "cameras", camera, "pipeline")
+	if err != nil || !found {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Pipelines returns the pipelines of all cameras along with their status, keyed by camera name.
+func (c *Client) Pipelines(ctx context.Context) (map[string]appcamera.PipelineInfoStatus, error) {
+	res := make(map[string]appcamera.PipelineInfoStatus)
+	_, err := c.do(ctx, http.MethodGet, nil, &res, "pipelines", "status", "all")
+	return res, err
+}
+
+// do sends the request to the route made of the escaped path segments, and decodes the response into res
+// if it is not nil. It returns false if the response has no content.
+func (c *Client) do(ctx context.Context, method string, body interface{}, res interface{}, segments ...string) (bool, error) {
+	return c.doWithQuery(ctx, method, nil, body, res, segments...)
+}
+
+// doWithQuery is do with query parameters.
+func (c *Client) doWithQuery(ctx context.Context, method string, query url.Values, body interface{}, res interface{},
+	segments ...string) (bool, error) {
+
+	escaped := make([]string, len(segments))
+	for i, segment := range segments {
+		escaped[i] = url.PathEscape(segment)
+	}
+	reqUrl := c.baseUrl + common.ApiBase + "/" + strings.Join(escaped, "/")
+	if len(query) > 0 {
+		reqUrl += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to marshal %T to json", body)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, reqUrl, reader)
+	if err != nil {
+		return false, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", common.ContentTypeJSON)
+	}
+	if c.operator != "" {
+		req.Header.Set(appcamera.PTZOperatorHeader, c.operator)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to read response of %s %s", method, reqUrl)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &Error{}
+		if err = json.Unmarshal(data, &apiErr.ErrorResponse); err != nil || apiErr.Code == "" {
+			// not an error of the app service, for example from a proxy
+			apiErr.StatusCode = resp.StatusCode
+			apiErr.Code = appcamera.InternalError
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return false, apiErr
+	}
+
+	if resp.StatusCode == http.StatusNoContent || len(data) == 0 {
+		return false, nil
+	}
+	if res != nil {
+		if err = json.Unmarshal(data, res); err != nil {
+			return false, errors.Wrapf(err, "failed to parse response of %s %s", method, reqUrl)
+		}
+	}
+	return true, nil
+}
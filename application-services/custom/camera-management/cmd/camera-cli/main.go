@@ -0,0 +1,666 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// camera-cli is a command-line client of the camera management app service, for scripting camera operations.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/edgexfoundry/edgex-examples/application-services/custom/camera-management/appcamera"
+	"github.com/edgexfoundry/edgex-examples/application-services/custom/camera-management/client"
+	"github.com/pkg/errors"
+)
+
+const (
+	urlEnvVar      = "CAMERA_MANAGEMENT_URL"
+	operatorEnvVar = "CAMERA_OPERATOR"
+
+	usage = `Usage: camera-cli [flags] <command> [command flags] [arguments]
+
+Commands:
+  cameras                          List the cameras
+  inventory [camera]               Show the features, device information, pipeline and health of the cameras
+  discover                         Trigger the discovery of new cameras
+  provisional                      List the discovered cameras waiting to be approved
+  approve <camera>                 Approve a discovered camera and start its pipeline
+  features <camera>                Show the capabilities of a camera
+  profiles <camera>                List the media profiles of an Onvif camera
+  modes <camera>                   List the streaming modes of a USB camera
+  templates                        List the pipeline templates
+  models                           List the models available in EVAM and their inference devices
+  start <camera> <name/version>    Start a pipeline from a template
+  stop <camera>                    Stop the pipeline of a camera
+  status [camera]                  Show the pipeline of a camera, or of all cameras
+  ptz <camera> <action>            Move an Onvif camera: left, right, up, up-left, up-right,
+                                   down, down-left, down-right, zoom-in or zoom-out
+  presets <camera>                 List the PTZ presets of an Onvif camera
+  goto <camera> <preset>           Move an Onvif camera to a PTZ preset
+  lock <camera>                    Take exclusive control of the PTZ of a camera, or renew it
+  unlock <camera>                  Release the PTZ lock of a camera
+  snapshot <camera> <file>         Save a frame of the pipeline output (requires ffmpeg)
+
+Run 'camera-cli <command> -h' for the flags of a command.
+
+Flags:
+`
+)
+
+// cli holds the global flags shared by all commands
+type cli struct {
+	client  *client.Client
+	output  string
+	timeout time.Duration
+}
+
+type command func(ctx context.Context, c *cli, args []string) error
+
+var commands = map[string]command{
+	"cameras":     listCameras,
+	"inventory":   showInventory,
+	"discover":    discoverCameras,
+	"provisional": listProvisional,
+	"approve":     approveCamera,
+	"features":    showFeatures,
+	"profiles":    listProfiles,
+	"modes":       listStreamingModes,
+	"templates":   listTemplates,
+	"models":      listModels,
+	"start":       startPipeline,
+	"stop":        stopPipeline,
+	"status":      showStatus,
+	"ptz":         movePTZ,
+	"presets":     listPresets,
+	"goto":        gotoPreset,
+	"lock":        lockPTZ,
+	"unlock":      unlockPTZ,
+	"snapshot":    saveSnapshot,
+}
+
+func main() {
+	baseUrl := os.Getenv(urlEnvVar)
+	if baseUrl == "" {
+		baseUrl = client.DefaultBaseUrl
+	}
+	operator := os.Getenv(operatorEnvVar)
+	if operator == "" {
+		operator = os.Getenv("USER")
+	}
+
+	c := &cli{}
+	flags := flag.NewFlagSet("camera-cli", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&baseUrl, "url", baseUrl, "Url of the app service, also set by "+urlEnvVar)
+	flags.StringVar(&c.output, "o", tableOutput, "Output format: table or json")
+	flags.DurationVar(&c.timeout, "timeout", 30*time.Second, "Timeout of each request")
+	flags.StringVar(&operator, "operator", operator,
+		"Operator sending the PTZ commands and owning the PTZ locks, also set by "+operatorEnvVar)
+	_ = flags.Parse(os.Args[1:])
+
+	if c.output != tableOutput && c.output != jsonOutput {
+		fmt.Fprintf(os.Stderr, "invalid output format '%s'\n", c.output)
+		os.Exit(2)
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	cmd, found := commands[flags.Arg(0)]
+	if !found {
+		fmt.Fprintf(os.Stderr, "unknown command '%s'\n", flags.Arg(0))
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	c.client = client.NewClient(baseUrl, nil)
+	c.client.SetOperator(operator)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := cmd(ctx, c, flags.Args()[1:]); err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		stop()
+		os.Exit(1)
+	}
+}
+
+// parseArgs parses the command flags, and checks the number of remaining arguments.
+func parseArgs(flags *flag.FlagSet, args []string, minArgs int, maxArgs int, argsUsage string) ([]string, error) {
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: camera-cli %s [flags] %s\n", flags.Name(), argsUsage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() < minArgs || flags.NArg() > maxArgs {
+		flags.Usage()
+		return nil, errors.Errorf("%s expects %s", flags.Name(), argsUsage)
+	}
+	return flags.Args(), nil
+}
+
+// withTimeout returns the context of a single request.
+func (c *cli) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// defaultProfile returns the profile if set, otherwise the first media profile of the Onvif camera.
+func (c *cli) defaultProfile(ctx context.Context, camera string, profile string) (string, error) {
+	if profile != "" {
+		return profile, nil
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	profiles, err := c.client.Profiles(ctx, camera)
+	if err != nil {
+		return "", err
+	}
+	if len(profiles.Profiles) == 0 {
+		return "", errors.Errorf("camera %s has no media profiles", camera)
+	}
+	return string(profiles.Profiles[0].Token), nil
+}
+
+func listCameras(ctx context.Context, c *cli, args []string) error {
+	if _, err := parseArgs(flag.NewFlagSet("cameras", flag.ExitOnError), args, 0, 0, ""); err != nil {
+		return err
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	cameras, err := c.client.Cameras(ctx)
+	if err != nil {
+		return err
+	}
+	return c.print(cameras, func(t *table) {
+		t.row("NAME", "SERVICE", "PROFILE", "STATE")
+		for _, camera := range cameras {
+			t.row(camera.Name, camera.ServiceName, camera.ProfileName, camera.OperatingState)
+		}
+	})
+}
+
+func showInventory(ctx context.Context, c *cli, args []string) error {
+	flags := flag.NewFlagSet("inventory", flag.ExitOnError)
+	refresh := flags.Bool("refresh", false, "Read the cameras from the device services instead of the cache")
+	args, err := parseArgs(flags, args, 0, 1, "[camera]")
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	var items []appcamera.CameraInventoryItem
+	var res interface{}
+	if len(args) == 1 {
+		item, err := c.client.CameraInventory(ctx, args[0], *refresh)
+		if err != nil {
+			return err
+		}
+		items, res = []appcamera.CameraInventoryItem{item}, item
+	} else {
+		if items, err = c.client.Inventory(ctx, *refresh); err != nil {
+			return err
+		}
+		res = items
+	}
+	return c.print(res, func(t *table) {
+		t.row("NAME", "TYPE", "MODEL", "FIRMWARE", "PIPELINE", "HEALTH")
+		for _, item := range items {
+			model, firmware, pipeline := "", "", ""
+			if info := item.DeviceInformation; info != nil {
+				model = strings.TrimSpace(info.Manufacturer + " " + info.Model)
+				firmware = info.FirmwareVersion
+			}
+			if item.Pipeline != nil {
+				pipeline = item.Pipeline.Info.Name + "/" + item.Pipeline.Info.Version
+			}
+			t.row(item.Device.Name, item.Features.CameraType, model, firmware, pipeline, item.Health.Status)
+		}
+	})
+}
+
+func discoverCameras(ctx context.Context, c *cli, args []string) error {
+	flags := flag.NewFlagSet("discover", flag.ExitOnError)
+	service := flags.String("service", "", "Device service, defaults to all the device services supporting discovery")
+	if _, err := parseArgs(flags, args, 0, 0, ""); err != nil {
+		return err
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	res, err := c.client.Discover(ctx, *service)
+	if err != nil {
+		return err
+	}
+	return c.print(res, func(t *table) {
+		t.row("DISCOVERING")
+		for _, name := range res.Services {
+			t.row(name)
+		}
+	})
+}
+
+func listProvisional(ctx context.Context, c *cli, args []string) error {
+	if _, err := parseArgs(flag.NewFlagSet("provisional", flag.ExitOnError), args, 0, 0, ""); err != nil {
+		return err
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	devices, err := c.client.ProvisionalDevices(ctx)
+	if err != nil {
+		return err
+	}
+	return c.print(devices, func(t *table) {
+		t.row("NAME", "SERVICE", "PROFILE", "LABELS")
+		for _, device := range devices {
+			t.row(device.Name, device.ServiceName, device.ProfileName, strings.Join(device.Labels, ","))
+		}
+	})
+}
+
+func approveCamera(ctx context.Context, c *cli, args []string) error {
+	flags := flag.NewFlagSet("approve", flag.ExitOnError)
+	req := appcamera.ApproveDeviceRequest{}
+	flags.StringVar(&req.ProfileName, "profile", "", "Device profile, defaults to the current profile")
+	labels := flags.String("labels", "", "Comma separated labels, replacing the current labels")
+	template := flags.String("pipeline", "", "Pipeline template name/version, defaults to the template of the profile")
+	args, err := parseArgs(flags, args, 1, 1, "<camera>")
+	if err != nil {
+		return err
+	}
+	if *labels != "" {
+		req.Labels = strings.Split(*labels, ",")
+	}
+	if *template != "" {
+		var found bool
+		req.PipelineName, req.PipelineVersion, found = strings.Cut(*template, "/")
+		if !found || req.PipelineName == "" || req.PipelineVersion == "" {
+			return errors.Errorf("invalid template '%s', expected name/version", *template)
+		}
+	}
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	res, err := c.client.ApproveDevice(ctx, args[0], req)
+	if err != nil {
+		return err
+	}
+	return c.print(res, func(t *table) {
+		t.row("NAME", "PROFILE", "PIPELINE", "ERROR")
+		pipeline := ""
+		if res.Pipeline != nil {
+			pipeline = res.Pipeline.Name + "/" + res.Pipeline.Version
+		}
+		t.row(res.Device.Name, res.Device.ProfileName, pipeline, res.PipelineError)
+	})
+}
+
+func showFeatures(ctx context.Context, c *cli, args []string) error {
+	args, err := parseArgs(flag.NewFlagSet("features", flag.ExitOnError), args, 1, 1, "<camera>")
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	features, err := c.client.CameraFeatures(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return c.print(features, func(t *table) {
+		t.row("TYPE", "PTZ", "ZOOM")
+		t.row(string(features.CameraType), features.PTZ, features.Zoom)
+	})
+}
+
+func listProfiles(ctx context.Context, c *cli, args []string) error {
+	args, err := parseArgs(flag.NewFlagSet("profiles", flag.ExitOnError), args, 1, 1, "<camera>")
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	profiles, err := c.client.Profiles(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return c.print(profiles, func(t *table) {
+		t.row("TOKEN", "NAME", "ENCODING", "RESOLUTION")
+		for _, profile := range profiles.Profiles {
+			encoding, resolution := "", ""
+			// the onvif fields are all optional
+			if encoder := profile.VideoEncoderConfiguration; encoder != nil {
+				if encoder.Encoding != nil {
+					encoding = string(*encoder.Encoding)
+				}
+				if r := encoder.Resolution; r != nil && r.Width != nil && r.Height != nil {
+					resolution = fmt.Sprintf("%dx%d", *r.Width, *r.Height)
+				}
+			}
+			t.row(string(profile.Token), string(profile.Name), encoding, resolution)
+		}
+	})
+}
+
+func listStreamingModes(ctx context.Context, c *cli, args []string) error {
+	args, err := parseArgs(flag.NewFlagSet("modes", flag.ExitOnError), args, 1, 1, "<camera>")
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	modes, err := c.client.StreamingModes(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return c.print(modes, func(t *table) {
+		t.row("PIXEL FORMAT", "DESCRIPTION", "SIZES")
+		for _, mode := range modes {
+			sizes := make([]string, 0, len(mode.Sizes)+len(mode.Ranges))
+			for _, size := range mode.Sizes {
+				sizes = append(sizes, size.String())
+			}
+			for _, r := range mode.Ranges {
+				sizes = append(sizes, fmt.Sprintf("%dx%d-%dx%d", r.MinWidth, r.MinHeight, r.MaxWidth, r.MaxHeight))
+			}
+			t.row(mode.PixelFormat, mode.Description, strings.Join(sizes, " "))
+		}
+	})
+}
+
+func listTemplates(ctx context.Context, c *cli, args []string) error {
+	if _, err := parseArgs(flag.NewFlagSet("templates", flag.ExitOnError), args, 0, 0, ""); err != nil {
+		return err
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	templates, err := c.client.PipelineTemplates(ctx)
+	if err != nil {
+		return err
+	}
+	return c.print(templates, func(t *table) {
+		t.row("TEMPLATE", "TYPE", "DESCRIPTION")
+		for _, template := range templates {
+			t.row(template.Name+"/"+template.Version, template.Type, template.Description)
+		}
+	})
+}
+
+func listModels(ctx context.Context, c *cli, args []string) error {
+	flags := flag.NewFlagSet("models", flag.ExitOnError)
+	refresh := flags.Bool("refresh", false, "Read the models from EVAM instead of the cache")
+	if _, err := parseArgs(flags, args, 0, 0, ""); err != nil {
+		return err
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	catalog, err := c.client.Catalog(ctx, *refresh)
+	if err != nil {
+		return err
+	}
+	return c.print(catalog.Models, func(t *table) {
+		t.row("MODEL", "TYPE", "PRECISIONS", "DEVICES")
+		for _, model := range catalog.Models {
+			t.row(model.Name+"/"+model.Version, model.Type, strings.Join(model.Precisions, ","),
+				strings.Join(model.Devices, ","))
+		}
+	})
+}
+
+func startPipeline(ctx context.Context, c *cli, args []string) error {
+	flags := flag.NewFlagSet("start", flag.ExitOnError)
+	profile := flags.String("profile", "", "Media profile of an Onvif camera, defaults to the first profile")
+	usb := appcamera.USBStartStreamingRequest{}
+	flags.StringVar(&usb.InputPixelFormat, "pixel-format", "", "Input pixel format of a USB camera")
+	flags.StringVar(&usb.InputImageSize, "image-size", "", "Input image size of a USB camera, such as 640x480")
+	flags.StringVar(&usb.InputFps, "fps", "", "Input frame rate of a USB camera")
+	flags.StringVar(&usb.OutputVideoQuality, "quality", "", "Output video quality of a USB camera")
+	args, err := parseArgs(flags, args, 2, 2, "<camera> <name/version>")
+	if err != nil {
+		return err
+	}
+	camera := args[0]
+	name, version, found := strings.Cut(args[1], "/")
+	if !found || name == "" || version == "" {
+		return errors.Errorf("invalid template '%s', expected name/version", args[1])
+	}
+
+	reqCtx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	features, err := c.client.CameraFeatures(reqCtx, camera)
+	if err != nil {
+		return err
+	}
+
+	req := appcamera.StartPipelineRequest{
+		PipelineName:    name,
+		PipelineVersion: version,
+	}
+	switch features.CameraType {
+	case appcamera.Onvif:
+		token, err := c.defaultProfile(ctx, camera, *profile)
+		if err != nil {
+			return err
+		}
+		req.Onvif = &appcamera.OnvifPipelineConfig{ProfileToken: token}
+	case appcamera.USB:
+		req.USB = &usb
+	}
+
+	reqCtx, cancel = c.withTimeout(ctx)
+	defer cancel()
+	if err = c.client.StartPipeline(reqCtx, camera, req); err != nil {
+		return err
+	}
+	return printPipeline(ctx, c, camera)
+}
+
+func stopPipeline(ctx context.Context, c *cli, args []string) error {
+	args, err := parseArgs(flag.NewFlagSet("stop", flag.ExitOnError), args, 1, 1, "<camera>")
+	if err != nil {
+		return err
+	}
+	camera := args[0]
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	pipeline, err := c.client.Pipeline(ctx, camera)
+	if err != nil {
+		return err
+	}
+	if pipeline == nil {
+		return errors.Errorf("no pipeline is running for camera %s", camera)
+	}
+	return c.client.StopPipeline(ctx, camera, pipeline.Info.Id)
+}
+
+func showStatus(ctx context.Context, c *cli, args []string) error {
+	flags := flag.NewFlagSet("status", flag.ExitOnError)
+	watch := flags.Duration("watch", 0, "Keep showing the status at this interval, such as 5s")
+	args, err := parseArgs(flags, args, 0, 1, "[camera]")
+	if err != nil {
+		return err
+	}
+
+	show := func() error {
+		if len(args) == 1 {
+			return printPipeline(ctx, c, args[0])
+		}
+		return printPipelines(ctx, c)
+	}
+	if *watch <= 0 {
+		return show()
+	}
+
+	ticker := time.NewTicker(*watch)
+	defer ticker.Stop()
+	for {
+		if c.output == tableOutput {
+			fmt.Printf("\n%s\n", time.Now().Format(time.RFC3339))
+		}
+		if err = show(); err != nil {
+			// keep watching, the app service or EVAM may be restarting
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func printPipeline(ctx context.Context, c *cli, camera string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	pipeline, err := c.client.Pipeline(ctx, camera)
+	if err != nil {
+		return err
+	}
+	pipelines := map[string]appcamera.PipelineInfoStatus{}
+	if pipeline != nil {
+		pipelines[camera] = *pipeline
+	}
+	return c.printPipelineStatuses(pipelines)
+}
+
+func printPipelines(ctx context.Context, c *cli) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	pipelines, err := c.client.Pipelines(ctx)
+	if err != nil {
+		return err
+	}
+	return c.printPipelineStatuses(pipelines)
+}
+
+func movePTZ(ctx context.Context, c *cli, args []string) error {
+	flags := flag.NewFlagSet("ptz", flag.ExitOnError)
+	profile := flags.String("profile", "", "Media profile, defaults to the first profile")
+	args, err := parseArgs(flags, args, 2, 2, "<camera> <action>")
+	if err != nil {
+		return err
+	}
+	token, err := c.defaultProfile(ctx, args[0], *profile)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	_, err = c.client.PTZ(ctx, args[0], token, args[1])
+	return err
+}
+
+func listPresets(ctx context.Context, c *cli, args []string) error {
+	flags := flag.NewFlagSet("presets", flag.ExitOnError)
+	profile := flags.String("profile", "", "Media profile, defaults to the first profile")
+	args, err := parseArgs(flags, args, 1, 1, "<camera>")
+	if err != nil {
+		return err
+	}
+	token, err := c.defaultProfile(ctx, args[0], *profile)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	presets, err := c.client.Presets(ctx, args[0], token)
+	if err != nil {
+		return err
+	}
+	return c.print(presets, func(t *table) {
+		t.row("TOKEN", "NAME")
+		for _, preset := range presets.Preset {
+			t.row(string(preset.Token), string(preset.Name))
+		}
+	})
+}
+
+func gotoPreset(ctx context.Context, c *cli, args []string) error {
+	flags := flag.NewFlagSet("goto", flag.ExitOnError)
+	profile := flags.String("profile", "", "Media profile, defaults to the first profile")
+	args, err := parseArgs(flags, args, 2, 2, "<camera> <preset>")
+	if err != nil {
+		return err
+	}
+	token, err := c.defaultProfile(ctx, args[0], *profile)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	_, err = c.client.GotoPreset(ctx, args[0], token, args[1])
+	return err
+}
+
+func lockPTZ(ctx context.Context, c *cli, args []string) error {
+	flags := flag.NewFlagSet("lock", flag.ExitOnError)
+	duration := flags.String("duration", "", "Duration of the lock, defaults to the app service's default")
+	args, err := parseArgs(flags, args, 1, 1, "<camera>")
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	lock, err := c.client.LockPTZ(ctx, args[0], *duration)
+	if err != nil {
+		return err
+	}
+	return c.print(lock, func(t *table) {
+		t.row("OWNER", "EXPIRES")
+		t.row(lock.Owner, lock.Expires.Local().Format(time.RFC3339))
+	})
+}
+
+func unlockPTZ(ctx context.Context, c *cli, args []string) error {
+	flags := flag.NewFlagSet("unlock", flag.ExitOnError)
+	force := flags.Bool("force", false, "Release the lock even if it is owned by another operator")
+	args, err := parseArgs(flags, args, 1, 1, "<camera>")
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.client.UnlockPTZ(ctx, args[0], *force)
+}
+
+// saveSnapshot saves a frame of the rtsp output of the camera's pipeline, which includes the inference results.
+func saveSnapshot(ctx context.Context, c *cli, args []string) error {
+	args, err := parseArgs(flag.NewFlagSet("snapshot", flag.ExitOnError), args, 2, 2, "<camera> <file>")
+	if err != nil {
+		return err
+	}
+	camera, file := args[0], args[1]
+
+	reqCtx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	pipeline, err := c.client.Pipeline(reqCtx, camera)
+	if err != nil {
+		return err
+	}
+	if pipeline == nil || pipeline.Info.ViewUrls.Rtsp == "" {
+		return errors.Errorf("camera %s has no pipeline publishing an rtsp stream", camera)
+	}
+
+	ctx, cancel = c.withTimeout(ctx)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-y", "-loglevel", "error", "-rtsp_transport", "tcp",
+		"-i", pipeline.Info.ViewUrls.Rtsp, "-frames:v", "1", file)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err = cmd.Run(); err != nil {
+		return errors.Wrapf(err, "failed to capture a frame of %s with ffmpeg", pipeline.Info.ViewUrls.Rtsp)
+	}
+	return nil
+}
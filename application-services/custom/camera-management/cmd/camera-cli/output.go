@@ -0,0 +1,79 @@
+//
+// Copyright (C) 2023 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/edgexfoundry/edgex-examples/application-services/custom/camera-management/appcamera"
+)
+
+const (
+	tableOutput = "table"
+	jsonOutput  = "json"
+)
+
+// table writes tab separated rows as aligned columns
+type table struct {
+	w *tabwriter.Writer
+}
+
+func (t *table) row(values ...interface{}) {
+	columns := make([]string, len(values))
+	for i, v := range values {
+		columns[i] = fmt.Sprint(v)
+	}
+	fmt.Fprintln(t.w, strings.Join(columns, "\t"))
+}
+
+// print writes v as json, or as a table using printTable, depending on the output format.
+func (c *cli) print(v interface{}, printTable func(t *table)) error {
+	if c.output == jsonOutput {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	t := &table{w: tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)}
+	printTable(t)
+	return t.w.Flush()
+}
+
+func (c *cli) printPipelineStatuses(pipelines map[string]appcamera.PipelineInfoStatus) error {
+	cameras := make([]string, 0, len(pipelines))
+	for camera := range pipelines {
+		cameras = append(cameras, camera)
+	}
+	sort.Strings(cameras)
+
+	return c.print(pipelines, func(t *table) {
+		t.row("CAMERA", "PIPELINE", "ID", "STATE", "FPS", "VIEW")
+		for _, camera := range cameras {
+			pipeline := pipelines[camera]
+			state, fps := "", ""
+			// the status is passed through from EVAM
+			if status, ok := pipeline.Status.(map[string]interface{}); ok {
+				state = fmt.Sprint(status["state"])
+				if avgFps, ok := status["avg_fps"].(float64); ok {
+					fps = fmt.Sprintf("%.1f", avgFps)
+				}
+			}
+			view := pipeline.Info.ViewUrls.Rtsp
+			if view == "" {
+				view = pipeline.Info.ViewUrls.WebRTC
+			}
+			t.row(camera, pipeline.Info.Name+"/"+pipeline.Info.Version, pipeline.Info.Id, state, fps, view)
+		}
+	})
+}
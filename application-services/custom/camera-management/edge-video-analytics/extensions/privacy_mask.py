@@ -0,0 +1,30 @@
+#
+# Copyright (C) 2023 Intel Corporation
+#
+# SPDX-License-Identifier: Apache-2.0
+#
+
+import cv2
+import numpy as np
+
+
+class PrivacyMask:
+    """Blurs the privacy mask polygons of every frame. The points of the masks are relative to the frame size."""
+
+    def __init__(self, masks=None, kernel_size=51):
+        self.masks = masks or []
+        # the gaussian kernel size must be odd
+        self.kernel_size = kernel_size | 1
+
+    def process_frame(self, frame):
+        if not self.masks:
+            return True
+        with frame.data() as mat:
+            height, width = mat.shape[:2]
+            region = np.zeros((height, width), dtype=np.uint8)
+            for mask in self.masks:
+                points = np.array([[p["x"] * width, p["y"] * height] for p in mask["points"]], dtype=np.int32)
+                cv2.fillPoly(region, [points], 255)
+            blurred = cv2.GaussianBlur(mat, (self.kernel_size, self.kernel_size), 0)
+            mat[region == 255] = blurred[region == 255]
+        return True
@@ -26,7 +26,7 @@ import (
 )
 
 const (
-	serviceKey = "app-camera-management"
+	serviceKey = appcamera.ServiceKey
 )
 
 func main() {
@@ -4,6 +4,8 @@
 import { HttpHeaders } from "@angular/common/http";
 
 export const ApiLogIgnoreHeader: string = 'X-CameraApp-Ignore';
+// PtzOperatorHeader identifies the operator sending a PTZ command, which is checked against the PTZ lock of the camera
+export const PtzOperatorHeader: string = 'X-PTZ-Operator';
 export const JsonHeaders = {
   headers: new HttpHeaders({
     'Content-Type': 'application/json; charset=utf-8',
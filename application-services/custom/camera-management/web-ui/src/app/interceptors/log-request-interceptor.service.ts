@@ -41,7 +41,9 @@ export class LogRequestInterceptor implements HttpInterceptor {
     }, error => {
       if (error instanceof HttpErrorResponse) {
         item.response = error as HttpErrorResponse;
-        this.snackbar.open(`${error.status} ${error.statusText}\n${error.error.substring(0, 60)}...`, '', {
+        // the app service returns errors as json, but other errors may be plain text
+        const message: string = typeof error.error === 'string' ? error.error : (error.error?.message ?? '');
+        this.snackbar.open(`${error.status} ${error.statusText}\n${message.substring(0, 60)}...`, '', {
           duration: 2500,
           panelClass: ['mat-toolbar', 'mat-warn', 'error-snackbar'],
         });
@@ -29,4 +29,11 @@ export class PtzComponent implements OnInit, OnDestroy {
   isZoomDisabled(): boolean {
     return this.data.cameraFeatures === undefined || this.data.cameraFeatures.Zoom === false;
   }
+
+  setOperator(event: Event) {
+    const operator = (event.target as HTMLInputElement).value;
+    if (operator.trim() !== '') {
+      this.data.setPtzOperator(operator);
+    }
+  }
 }
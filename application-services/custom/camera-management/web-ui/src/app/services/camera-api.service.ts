@@ -13,8 +13,8 @@ import {
   GetProfilesResponse
 } from "./camera-api.types";
 import { DataService } from "./data.service";
-import { Pipeline, PipelineInfoStatus, PipelineStatus, StartPipelineRequest, USBConfig } from "./pipeline-api.types";
-import { ApiLogIgnoreHeader, JsonHeaders } from "../constants";
+import { Pipeline, PipelineCatalog, PipelineInfoStatus, PipelineStatus, StartPipelineRequest, USBConfig } from "./pipeline-api.types";
+import { ApiLogIgnoreHeader, JsonHeaders, PtzOperatorHeader } from "../constants";
 
 @Injectable({
   providedIn: 'root',
@@ -144,7 +144,8 @@ export class CameraApiService {
 
   gotoPreset(cameraName: string, profileToken: string, presetToken: string) {
     return this.httpClient.post<any>(
-      this.makePresetUrl(cameraName, profileToken, presetToken), '')
+      this.makePresetUrl(cameraName, profileToken, presetToken), '',
+      {headers: new HttpHeaders({[PtzOperatorHeader]: this.data.ptzOperator})})
       .subscribe();
   }
 
@@ -231,20 +232,17 @@ export class CameraApiService {
     });
   }
 
+  // updatePipelinesList reads the catalog of the app service, so that only the pipelines available in EVAM are offered
   updatePipelinesList() {
     this.data.pipelines = undefined;
-    this.httpClient.get<Pipeline[]>(environment.appServiceBaseUrl + "/pipelines")
+    this.httpClient.get<PipelineCatalog>(environment.appServiceBaseUrl + "/catalog")
       .subscribe({
         next: data => {
-          this.data.pipelines = data.filter(p => {
-            if (this.shouldShowPipeline(p)) {
-              if (`${p.name}/${p.version}` === environment.defaultPipelineId) {
-                this.data.selectedPipeline = environment.defaultPipelineId;
-              }
-              return true;
-            }
-            return false;
-          });
+          this.data.pipelines = data.pipelines.filter(p => this.shouldShowPipeline(p));
+          const ids = this.data.pipelines.map(p => `${p.name}/${p.version}`);
+          if (!ids.includes(this.data.selectedPipeline)) {
+            this.data.selectedPipeline = ids.includes(environment.defaultPipelineId) ? environment.defaultPipelineId : undefined;
+          }
         }, error: _ => {
           this.data.pipelines = undefined;
         }
@@ -8,6 +8,7 @@ import { Pipeline, PipelineInfoStatus, PipelineStatus, USBConfig } from './pipel
 
 const onvifServiceName = 'device-onvif-camera';
 const usbServiceName = 'device-usb-camera';
+const ptzOperatorStorageKey = 'ptzOperator';
 
 /**
  * Represents a page that users can navigate to, either via
@@ -60,6 +61,10 @@ export class DataService {
 
   public apiLog: APILogItem[];
 
+  // ptzOperator identifies this browser in the PTZ commands, so that it can move the cameras it locked.
+  // It is kept across page reloads, so that the locks are not lost.
+  public ptzOperator: string;
+
   // pages is a list of all tabs that are navigable by the user, accessible
   // via the routing module and also by clicking tabs
   public pages: Page[] = [
@@ -74,6 +79,14 @@ export class DataService {
   constructor() {
     this.apiLog = new Array<APILogItem>();
     this.pipelineMap = new Map<string, PipelineInfoStatus>();
+    this.ptzOperator = localStorage.getItem(ptzOperatorStorageKey)
+      || `web-ui-${Math.random().toString(36).substring(2, 10)}`;
+    localStorage.setItem(ptzOperatorStorageKey, this.ptzOperator);
+  }
+
+  setPtzOperator(operator: string) {
+    this.ptzOperator = operator.trim();
+    localStorage.setItem(ptzOperatorStorageKey, this.ptzOperator);
   }
 
   cameraIsOnvif(): boolean {
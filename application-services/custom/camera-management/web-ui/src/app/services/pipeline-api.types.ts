@@ -20,6 +20,14 @@ export interface PipelineInfo {
   version: string;
   // profile is the ProfileToken for the specific stream
   profile: string;
+  // viewUrls are the urls the inference results can be viewed at
+  viewUrls?: ViewUrls;
+}
+
+export interface ViewUrls {
+  rtsp?: string;
+  webrtc?: string;
+  hls?: string;
 }
 
 export interface PipelineInfoStatus {
@@ -28,11 +36,39 @@ export interface PipelineInfoStatus {
   status: PipelineStatus;
 }
 
+export interface PipelineParameter {
+  type?: string;
+  description?: string;
+  default?: any;
+  enum?: any[];
+  minimum?: number;
+  maximum?: number;
+}
+
 export interface Pipeline {
   description: string;
   name: string;
   type: string;
   version: string;
+  parameters?: { [name: string]: PipelineParameter };
+}
+
+export interface Model {
+  description: string;
+  name: string;
+  type: string;
+  version: string;
+  // precisions are the precisions the model is available in, such as FP16 or FP32
+  precisions: string[];
+  // devices are the inference devices supporting at least one of the precisions
+  devices: string[];
+}
+
+// PipelineCatalog lists the pipelines and models available in EVAM
+export interface PipelineCatalog {
+  pipelines: Pipeline[];
+  models: Model[];
+  updatedAt: string;
 }
 
 export interface OnvifConfig {
@@ -2,10 +2,11 @@
 // SPDX-License-Identifier: Apache-2.0
 
 import { Injectable } from '@angular/core';
-import { HttpClient } from '@angular/common/http';
+import { HttpClient, HttpHeaders } from '@angular/common/http';
 
 import { DataService } from './data.service';
 import { environment } from '../../environments/environment';
+import { PtzOperatorHeader } from '../constants';
 
 @Injectable({
   providedIn: 'root',
@@ -15,12 +16,32 @@ export class PtzService {
     return `${environment.appServiceBaseUrl}/cameras/${cameraName}/profiles/${profileToken}/ptz${actionPath}`
   }
 
+  makeLockUrl(cameraName: string) {
+    return `${environment.appServiceBaseUrl}/cameras/${cameraName}/ptz/lock`
+  }
+
   constructor(private httpClient: HttpClient, public data: DataService) {
   }
 
+  // operatorHeaders identify the operator, so that the commands are accepted while the operator holds the PTZ lock
+  operatorHeaders(): HttpHeaders {
+    return new HttpHeaders({[PtzOperatorHeader]: this.data.ptzOperator});
+  }
+
   post(cameraName: string, profileToken: string, actionPath: string) {
     let url = this.makePtzUrl(cameraName, profileToken, actionPath);
-    return this.httpClient.post<any>(url, '')
+    return this.httpClient.post<any>(url, '', {headers: this.operatorHeaders()})
+      .subscribe();
+  }
+
+  // lock takes exclusive PTZ control of the camera for the default lock duration, or renews the lock
+  lock(cameraName: string) {
+    return this.httpClient.put<any>(this.makeLockUrl(cameraName), {owner: this.data.ptzOperator})
+      .subscribe();
+  }
+
+  unlock(cameraName: string) {
+    return this.httpClient.delete<any>(this.makeLockUrl(cameraName), {headers: this.operatorHeaders()})
       .subscribe();
   }
 
@@ -0,0 +1,128 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap/messaging"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
+)
+
+// Keys of the NatsTrigger secret, which are all optional. The username, password and certificate keys are
+// the ones used by the EdgeX MessageBus secrets.
+const (
+	secretUsernameKey   = messaging.SecretUsernameKey
+	secretPasswordKey   = messaging.SecretPasswordKey
+	secretTokenKey      = "token"
+	secretNKeySeedKey   = "nkeyseed"
+	secretJWTKey        = "jwt"
+	secretCACertKey     = messaging.SecretCACert
+	secretClientCertKey = messaging.SecretClientCert
+	secretClientKeyKey  = messaging.SecretClientKey
+)
+
+// secretOptions returns the connection options built from the secret and the TLS settings.
+func (t *rpcTrigger) secretOptions() ([]nats.Option, error) {
+	cfg := t.cfg
+	secret := map[string]string{}
+	if cfg.SecretName != "" {
+		var err error
+		if secret, err = t.secretProvider.GetSecret(cfg.SecretName); err != nil {
+			return nil, fmt.Errorf("failed to get the %s secret: %w", cfg.SecretName, err)
+		}
+	}
+
+	opts, err := authOptions(secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s secret: %w", cfg.SecretName, err)
+	}
+
+	if cfg.UseTLS || secret[secretCACertKey] != "" || secret[secretClientCertKey] != "" || secret[secretClientKeyKey] != "" {
+		tlsConfig, err := tlsConfigFor(secret, cfg.InsecureSkipVerify)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s secret: %w", cfg.SecretName, err)
+		}
+		opts = append(opts, nats.Secure(tlsConfig))
+	}
+	return opts, nil
+}
+
+// authOptions returns the authentication option matching the keys of the secret: a user JWT with its NKey seed,
+// an NKey seed, a token, or a username and password.
+func authOptions(secret map[string]string) ([]nats.Option, error) {
+	seed := secret[secretNKeySeedKey]
+	switch {
+	case secret[secretJWTKey] != "":
+		if seed == "" {
+			return nil, fmt.Errorf("%s must be set along with %s", secretNKeySeedKey, secretJWTKey)
+		}
+		return []nats.Option{nats.UserJWTAndSeed(secret[secretJWTKey], seed)}, nil
+
+	case seed != "":
+		user, err := nkeys.FromSeed([]byte(seed))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", secretNKeySeedKey, err)
+		}
+		publicKey, err := user.PublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", secretNKeySeedKey, err)
+		}
+		return []nats.Option{nats.Nkey(publicKey, user.Sign)}, nil
+
+	case secret[secretTokenKey] != "":
+		return []nats.Option{nats.Token(secret[secretTokenKey])}, nil
+
+	case secret[secretUsernameKey] != "":
+		return []nats.Option{nats.UserInfo(secret[secretUsernameKey], secret[secretPasswordKey])}, nil
+	}
+	return nil, nil
+}
+
+// tlsConfigFor builds the TLS configuration from the PEM encoded certificates of the secret. The system
+// certificates are used when there is no CA certificate.
+func tlsConfigFor(secret map[string]string, insecureSkipVerify bool) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: insecureSkipVerify, // nolint:gosec // only when configured, for testing
+	}
+
+	if caCert := secret[secretCACertKey]; caCert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(caCert)) {
+			return nil, fmt.Errorf("%s is not a PEM encoded certificate", secretCACertKey)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	clientCert, clientKey := secret[secretClientCertKey], secret[secretClientKeyKey]
+	if (clientCert == "") != (clientKey == "") {
+		return nil, errors.New("both " + secretClientCertKey + " and " + secretClientKeyKey + " must be set")
+	}
+	if clientCert != "" {
+		cert, err := tls.X509KeyPair([]byte(clientCert), []byte(clientKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
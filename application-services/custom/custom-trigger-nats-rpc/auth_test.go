@@ -0,0 +1,311 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecretName = "nats"
+
+// fakeSecretProvider holds the secrets in memory and records the secret updated callbacks.
+type fakeSecretProvider struct {
+	mutex     sync.Mutex
+	secrets   map[string]map[string]string
+	callbacks map[string]func(string)
+}
+
+func newFakeSecretProvider(secret map[string]string) *fakeSecretProvider {
+	return &fakeSecretProvider{
+		secrets:   map[string]map[string]string{testSecretName: secret},
+		callbacks: map[string]func(string){},
+	}
+}
+
+func (p *fakeSecretProvider) StoreSecret(secretName string, secrets map[string]string) error {
+	p.mutex.Lock()
+	p.secrets[secretName] = secrets
+	callback := p.callbacks[secretName]
+	p.mutex.Unlock()
+	if callback != nil {
+		callback(secretName)
+	}
+	return nil
+}
+
+func (p *fakeSecretProvider) GetSecret(secretName string, _ ...string) (map[string]string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	secret, ok := p.secrets[secretName]
+	if !ok {
+		return nil, errors.New("secret not found")
+	}
+	return secret, nil
+}
+
+func (p *fakeSecretProvider) SecretsLastUpdated() time.Time {
+	return time.Now()
+}
+
+func (p *fakeSecretProvider) ListSecretNames() ([]string, error) {
+	return nil, nil
+}
+
+func (p *fakeSecretProvider) HasSecret(secretName string) (bool, error) {
+	_, err := p.GetSecret(secretName)
+	return err == nil, nil
+}
+
+func (p *fakeSecretProvider) RegisterSecretUpdatedCallback(secretName string, callback func(secretName string)) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.callbacks[secretName] = callback
+	return nil
+}
+
+func (p *fakeSecretProvider) DeregisterSecretUpdatedCallback(secretName string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	delete(p.callbacks, secretName)
+}
+
+func secretConfig(s *server.Server) NatsTriggerConfig {
+	return NatsTriggerConfig{
+		Servers:    []string{s.ClientURL()},
+		Subjects:   []string{"rpc.*"},
+		SecretName: testSecretName,
+	}
+}
+
+// requestHello sends a request with the given connection options and checks the reply of the pipeline.
+func requestHello(t *testing.T, s *server.Server, opts ...nats.Option) {
+	nc, err := nats.Connect(s.ClientURL(), opts...)
+	require.NoError(t, err)
+	defer nc.Close()
+	reply, err := nc.Request("rpc.test", []byte("hello"), testTimeout)
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO", string(reply.Data))
+}
+
+func withUsers(users ...*server.User) func(*server.Options) {
+	return func(o *server.Options) {
+		o.Users = users
+	}
+}
+
+func TestSecretUserPassword(t *testing.T) {
+	s := runServer(t, withUsers(&server.User{Username: "alice", Password: "secret"}))
+	secrets := newFakeSecretProvider(map[string]string{secretUsernameKey: "alice", secretPasswordKey: "secret"})
+	_, deferred := startTriggerWithSecrets(t, secretConfig(s), &receiver{}, secrets)
+	defer deferred()
+
+	requestHello(t, s, nats.UserInfo("alice", "secret"))
+}
+
+func TestSecretToken(t *testing.T) {
+	s := runServer(t, func(o *server.Options) { o.Authorization = "s3cr3t" })
+	secrets := newFakeSecretProvider(map[string]string{secretTokenKey: "s3cr3t"})
+	_, deferred := startTriggerWithSecrets(t, secretConfig(s), &receiver{}, secrets)
+	defer deferred()
+
+	requestHello(t, s, nats.Token("s3cr3t"))
+}
+
+func TestSecretNKey(t *testing.T) {
+	user, err := nkeys.CreateUser()
+	require.NoError(t, err)
+	publicKey, err := user.PublicKey()
+	require.NoError(t, err)
+	seed, err := user.Seed()
+	require.NoError(t, err)
+
+	s := runServer(t, func(o *server.Options) { o.Nkeys = []*server.NkeyUser{{Nkey: publicKey}} })
+	secrets := newFakeSecretProvider(map[string]string{secretNKeySeedKey: string(seed)})
+	_, deferred := startTriggerWithSecrets(t, secretConfig(s), &receiver{}, secrets)
+	defer deferred()
+
+	requestHello(t, s, nats.Nkey(publicKey, user.Sign))
+}
+
+func TestSecretTLS(t *testing.T) {
+	certPEM, keyPEM := selfSignedCert(t)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	s := runServer(t, func(o *server.Options) {
+		o.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+		o.TLSTimeout = 2
+	})
+
+	// the server certificate is rejected without the CA certificate
+	r := &receiver{}
+	trigger := &rpcTrigger{secretProvider: newFakeSecretProvider(map[string]string{})}
+	trigger.cfg, err = parseTriggerConfig(NatsTriggerConfig{Servers: []string{s.ClientURL()}, SecretName: testSecretName, UseTLS: true})
+	require.NoError(t, err)
+	_, err = trigger.connect()
+	require.Error(t, err)
+
+	secrets := newFakeSecretProvider(map[string]string{secretCACertKey: string(certPEM)})
+	_, deferred := startTriggerWithSecrets(t, secretConfig(s), r, secrets)
+	defer deferred()
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(certPEM)
+	requestHello(t, s, nats.Secure(&tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}))
+}
+
+func TestSecretUpdatedReconnects(t *testing.T) {
+	s := runServer(t, withUsers(
+		&server.User{Username: "alice", Password: "secret"},
+		&server.User{Username: "bob", Password: "secret"},
+	))
+	secrets := newFakeSecretProvider(map[string]string{secretUsernameKey: "alice", secretPasswordKey: "secret"})
+	trigger, deferred := startTriggerWithSecrets(t, secretConfig(s), &receiver{}, secrets)
+	defer deferred()
+	previous := trigger.conn
+
+	require.NoError(t, secrets.StoreSecret(testSecretName, map[string]string{secretUsernameKey: "bob", secretPasswordKey: "secret"}))
+
+	trigger.mutex.Lock()
+	assert.NotSame(t, previous, trigger.conn)
+	assert.True(t, previous.IsClosed())
+	trigger.mutex.Unlock()
+	requestHello(t, s, nats.UserInfo("alice", "secret"))
+}
+
+func TestSecretUpdatedInvalidKeepsConnection(t *testing.T) {
+	s := runServer(t, withUsers(&server.User{Username: "alice", Password: "secret"}))
+	secrets := newFakeSecretProvider(map[string]string{secretUsernameKey: "alice", secretPasswordKey: "secret"})
+	trigger, deferred := startTriggerWithSecrets(t, secretConfig(s), &receiver{}, secrets)
+	defer deferred()
+	previous := trigger.conn
+
+	require.NoError(t, secrets.StoreSecret(testSecretName, map[string]string{secretUsernameKey: "alice", secretPasswordKey: "wrong"}))
+
+	trigger.mutex.Lock()
+	assert.Same(t, previous, trigger.conn)
+	trigger.mutex.Unlock()
+	requestHello(t, s, nats.UserInfo("alice", "secret"))
+}
+
+func TestSecretUpdatedSubscribeFailureKeepsConnection(t *testing.T) {
+	// bob connects to an account without JetStream, so the durable consumer cannot be subscribed to
+	conf := filepath.Join(t.TempDir(), "server.conf")
+	require.NoError(t, os.WriteFile(conf, []byte(fmt.Sprintf(`
+jetstream: {store_dir: %q}
+accounts: {
+  JS: {jetstream: enabled, users: [{user: alice, password: secret}]}
+  NOJS: {users: [{user: bob, password: secret}]}
+}`, t.TempDir())), 0600))
+	s := runServer(t, func(o *server.Options) {
+		fileOpts, err := server.ProcessConfigFile(conf)
+		require.NoError(t, err)
+		o.Accounts, o.Users, o.StoreDir = fileOpts.Accounts, fileOpts.Users, fileOpts.StoreDir
+	})
+
+	r := &receiver{}
+	secrets := newFakeSecretProvider(map[string]string{secretUsernameKey: "alice", secretPasswordKey: "secret"})
+	cfg := jetStreamConfig(s, 5)
+	cfg.SecretName = testSecretName
+	trigger, deferred := startTriggerWithSecrets(t, cfg, r, secrets)
+	defer deferred()
+	previous := trigger.conn
+
+	require.NoError(t, secrets.StoreSecret(testSecretName, map[string]string{secretUsernameKey: "bob", secretPasswordKey: "secret"}))
+
+	trigger.mutex.Lock()
+	assert.Same(t, previous, trigger.conn)
+	assert.False(t, previous.IsClosed())
+	trigger.mutex.Unlock()
+
+	// the durable consumer is subscribed to again on the current connection
+	nc, err := nats.Connect(s.ClientURL(), nats.UserInfo("alice", "secret"))
+	require.NoError(t, err)
+	defer nc.Close()
+	js, err := nc.JetStream()
+	require.NoError(t, err)
+	_, err = js.Publish("rpc.test", []byte("hello"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(r.received()) == 1 }, testTimeout, 10*time.Millisecond)
+}
+
+func TestSecretOptionsInvalid(t *testing.T) {
+	certPEM, keyPEM := selfSignedCert(t)
+	tests := []struct {
+		name   string
+		secret map[string]string
+	}{
+		{"jwt without seed", map[string]string{secretJWTKey: "jwt"}},
+		{"invalid seed", map[string]string{secretNKeySeedKey: "seed"}},
+		{"invalid ca cert", map[string]string{secretCACertKey: "cert"}},
+		{"client cert without key", map[string]string{secretClientCertKey: string(certPEM)}},
+		{"client key without cert", map[string]string{secretClientKeyKey: string(keyPEM)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			trigger := &rpcTrigger{
+				secretProvider: newFakeSecretProvider(test.secret),
+				cfg:            triggerSettings{NatsTriggerConfig: NatsTriggerConfig{SecretName: testSecretName}},
+			}
+			_, err := trigger.secretOptions()
+			require.Error(t, err)
+		})
+	}
+}
+
+// selfSignedCert returns a PEM encoded certificate and key for 127.0.0.1.
+func selfSignedCert(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "nats"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
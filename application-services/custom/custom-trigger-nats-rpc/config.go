@@ -0,0 +1,143 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"custom-trigger/dispatch"
+)
+
+const (
+	natsTriggerSection = "NatsTrigger"
+
+	defaultSubject        = "rpc.*"
+	defaultConnectTimeout = 5 * time.Second
+	defaultAckWait        = 30 * time.Second
+)
+
+// NatsTriggerConfig holds the settings of the NATS trigger, loaded from the NatsTrigger section
+type NatsTriggerConfig struct {
+	// Servers are the urls of the NATS servers, defaults to nats://127.0.0.1:4222
+	Servers []string
+	// Subjects are the subjects subscribed to, which may contain wildcards
+	Subjects []string
+	// QueueGroup balances the messages between the instances of the service, when set
+	QueueGroup string
+	// CredentialsFile is the path of a NATS user credentials file, when the server requires authentication
+	CredentialsFile string
+	// SecretName is the secret holding the credentials and certificates, the connection is rebuilt when it is updated
+	SecretName string
+	// UseTLS connects with TLS, which is also used when the secret holds a CA certificate
+	UseTLS bool
+	// InsecureSkipVerify disables the verification of the server certificate, for testing only
+	InsecureSkipVerify bool
+	ConnectTimeout     string
+	JetStream          JetStreamConfig
+	// Dispatch bounds the number of messages processed concurrently
+	Dispatch dispatch.Config
+}
+
+// JetStreamConfig holds the settings of the JetStream mode, in which messages are kept by a stream
+// until the pipeline processed them, even while the service is down
+type JetStreamConfig struct {
+	Enabled bool
+	// Stream is the stream capturing the Subjects, it is created if it does not exist
+	Stream string
+	// Durable is the name of the durable consumer. With several Subjects, the subject is appended to it.
+	Durable string
+	// AckWait is how long the pipeline may take before the message is delivered again
+	AckWait string
+	// MaxDeliver is the maximum number of deliveries of a message, 0 or -1 for no limit
+	MaxDeliver int
+	// NakDelay delays the redelivery of a message the pipeline failed to process, 0 redelivers it immediately
+	NakDelay string
+}
+
+// natsServiceConfig wraps the NatsTrigger section so that it can be loaded by the ConfigLoader of the trigger
+type natsServiceConfig struct {
+	NatsTrigger NatsTriggerConfig
+}
+
+// UpdateFromRaw updates the configuration from raw data received from the Configuration Provider.
+func (c *natsServiceConfig) UpdateFromRaw(rawConfig interface{}) bool {
+	configuration, ok := rawConfig.(*natsServiceConfig)
+	if !ok {
+		return false
+	}
+
+	*c = *configuration
+
+	return true
+}
+
+// triggerSettings are the parsed NatsTriggerConfig
+type triggerSettings struct {
+	NatsTriggerConfig
+	connectTimeout time.Duration
+	ackWait        time.Duration
+	nakDelay       time.Duration
+}
+
+func parseDuration(name string, value string, defaultValue time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s '%s'", name, value)
+	}
+	return d, nil
+}
+
+// parseTriggerConfig applies the defaults and validates the configuration.
+func parseTriggerConfig(cfg NatsTriggerConfig) (triggerSettings, error) {
+	s := triggerSettings{NatsTriggerConfig: cfg}
+	if len(s.Servers) == 0 {
+		s.Servers = []string{nats.DefaultURL}
+	}
+	if len(s.Subjects) == 0 {
+		s.Subjects = []string{defaultSubject}
+	}
+
+	var err error
+	if s.connectTimeout, err = parseDuration("ConnectTimeout", cfg.ConnectTimeout, defaultConnectTimeout); err != nil {
+		return s, err
+	}
+
+	js := cfg.JetStream
+	if !js.Enabled {
+		return s, nil
+	}
+	if js.Stream == "" {
+		return s, errors.New("JetStream Stream must be set")
+	}
+	if js.Durable == "" {
+		return s, errors.New("JetStream Durable must be set")
+	}
+	if s.ackWait, err = parseDuration("JetStream AckWait", js.AckWait, defaultAckWait); err != nil {
+		return s, err
+	}
+	if s.nakDelay, err = parseDuration("JetStream NakDelay", js.NakDelay, 0); err != nil {
+		return s, err
+	}
+	return s, nil
+}
@@ -0,0 +1,89 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+)
+
+// Headers of the messages, which are the ones used by the EdgeX NATS MessageBus
+const (
+	contentTypeHeader   = common.ContentType
+	correlationIDHeader = common.CorrelationHeader
+	requestIDHeader     = "RequestId"
+	apiVersionHeader    = "ApiVersion"
+	// errorCodeHeader is set to 1 in the replies when the pipeline failed, along with the error as payload
+	errorCodeHeader = "ErrorCode"
+)
+
+// topicReplacer formats NATS subjects into EdgeX topics, so that the topics of the functions pipelines
+// are matched with the / separator and the + and # wildcards
+var topicReplacer = strings.NewReplacer(".", "/", "*", "+", ">", "#")
+
+// envelopeFromMsg maps the subject and headers of the NATS message to the envelope passed to the pipelines.
+func envelopeFromMsg(msg *nats.Msg) types.MessageEnvelope {
+	env := types.MessageEnvelope{
+		ReceivedTopic: topicReplacer.Replace(msg.Subject),
+		CorrelationID: msg.Header.Get(correlationIDHeader),
+		RequestID:     msg.Header.Get(requestIDHeader),
+		ContentType:   msg.Header.Get(contentTypeHeader),
+		Payload:       msg.Data,
+	}
+	env.ApiVersion = msg.Header.Get(apiVersionHeader)
+	if env.ApiVersion == "" {
+		env.ApiVersion = common.ApiVersion
+	}
+	if env.CorrelationID == "" {
+		env.CorrelationID = uuid.NewString()
+	}
+	if env.ContentType == "" {
+		env.ContentType = common.ContentTypeJSON
+	}
+	return env
+}
+
+// replyMsg builds the reply to a request, which carries the response data of the pipeline, or the error
+// if the pipeline failed.
+func replyMsg(env types.MessageEnvelope, data []byte, contentType string, err error) *nats.Msg {
+	reply := &nats.Msg{
+		Header: nats.Header{},
+		Data:   data,
+	}
+	reply.Header.Set(correlationIDHeader, env.CorrelationID)
+	if env.RequestID != "" {
+		reply.Header.Set(requestIDHeader, env.RequestID)
+	}
+	reply.Header.Set(apiVersionHeader, common.ApiVersion)
+
+	if err != nil {
+		reply.Header.Set(errorCodeHeader, "1")
+		reply.Header.Set(contentTypeHeader, common.ContentTypeText)
+		reply.Data = []byte(err.Error())
+		return reply
+	}
+	reply.Header.Set(errorCodeHeader, strconv.Itoa(0))
+	if contentType != "" {
+		reply.Header.Set(contentTypeHeader, contentType)
+	}
+	return reply
+}
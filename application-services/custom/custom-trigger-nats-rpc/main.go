@@ -17,50 +17,20 @@
 package main
 
 import (
-	"context"
 	"fmt"
 	"os"
-	"sync"
 	"time"
 
 	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg"
 	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
 	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/util"
-	"github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap"
-	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
-	"github.com/nats-io/nats.go"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
 )
 
 const (
 	serviceKey = "app-custom-trigger-nats-rpc"
 )
 
-type rpcTrigger struct {
-	tc interfaces.TriggerConfig
-}
-
-func (t *rpcTrigger) Initialize(_ *sync.WaitGroup, ctx context.Context, _ <-chan interfaces.BackgroundMessage) (bootstrap.Deferred, error) {
-	ct, err := nats.Connect("demo.nats.io")
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to start connect to NATS server %w", err)
-	}
-
-	sub, err := ct.Subscribe("rpc.*", func(msg *nats.Msg) {
-		env := types.MessageEnvelope{
-			Payload: msg.Data,
-		}
-		t.tc.MessageReceived(t.tc.ContextBuilder(env), env, func(ctx interfaces.AppFunctionContext, pipeline *interfaces.FunctionPipeline) error {
-			return msg.Respond([]byte(fmt.Sprintf("got %s (from %s at %v)", string(msg.Data), msg.Subject, time.Now().UTC())))
-		})
-	})
-
-	return func() {
-		_ = sub.Drain()
-		ct.Close()
-	}, nil
-}
-
 func main() {
 	// turn off secure mode for examples. Not recommended for production
 	_ = os.Setenv("EDGEX_SECURITY_SECRET_STORE", "false")
@@ -73,7 +43,9 @@ func main() {
 
 	service.RegisterCustomTriggerFactory("custom-rpc", func(config interfaces.TriggerConfig) (interfaces.Trigger, error) {
 		return &rpcTrigger{
-			tc: config,
+			tc:             config,
+			secretProvider: service.SecretProvider(),
+			metricsManager: service.MetricsManager(),
 		}, nil
 	})
 
@@ -114,5 +86,10 @@ func printToConsole(appContext interfaces.AppFunctionContext, data interface{})
 
 	os.Stdout.WriteString(fmt.Sprintf("'%s' received %s ago\n>", string(input), wait.String()))
 
+	// the response data is the reply to NATS requests
+	topic, _ := appContext.GetValue(interfaces.RECEIVEDTOPIC)
+	appContext.SetResponseContentType(common.ContentTypeText)
+	appContext.SetResponseData([]byte(fmt.Sprintf("got %s (from %s at %v)", string(input), topic, time.Now().UTC())))
+
 	return false, nil
 }
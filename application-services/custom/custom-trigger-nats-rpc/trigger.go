@@ -0,0 +1,441 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+	"github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap"
+	bootstrapInterfaces "github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap/interfaces"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+	"github.com/nats-io/nats.go"
+
+	"custom-trigger/dispatch"
+)
+
+// drainPollInterval is the interval at which close checks whether the subscriptions are drained
+const drainPollInterval = 10 * time.Millisecond
+
+type rpcTrigger struct {
+	tc interfaces.TriggerConfig
+	// secretProvider reads the credentials and certificates of the NatsTrigger SecretName
+	secretProvider bootstrapInterfaces.SecretProvider
+	metricsManager bootstrapInterfaces.MetricsManager
+	cfg            triggerSettings
+	// dispatcher runs the pipelines, outside of the callback goroutines of the subscriptions
+	dispatcher *dispatch.Dispatcher
+
+	// mutex protects the connection, which is replaced when the secret is updated
+	mutex sync.Mutex
+	conn  *nats.Conn
+	subs  []*nats.Subscription
+}
+
+func (t *rpcTrigger) Initialize(_ *sync.WaitGroup, _ context.Context, _ <-chan interfaces.BackgroundMessage) (bootstrap.Deferred, error) {
+	sc := &natsServiceConfig{}
+	if err := t.tc.ConfigLoader(sc, natsTriggerSection); err != nil {
+		return nil, fmt.Errorf("failed to load the %s configuration: %w", natsTriggerSection, err)
+	}
+	cfg, err := parseTriggerConfig(sc.NatsTrigger)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s configuration: %w", natsTriggerSection, err)
+	}
+	t.cfg = cfg
+
+	if t.dispatcher, err = dispatch.New(cfg.Dispatch); err != nil {
+		return nil, fmt.Errorf("invalid %s Dispatch configuration: %w", natsTriggerSection, err)
+	}
+	if err = t.dispatcher.RegisterMetrics(t.metricsManager, map[string]string{"trigger": "custom-rpc"}); err != nil {
+		t.dispatcher.Stop()
+		return nil, fmt.Errorf("failed to register the dispatch metrics: %w", err)
+	}
+
+	conn, err := t.connect()
+	if err == nil {
+		t.conn = conn
+		t.subs, err = t.subscribeAll(conn)
+	}
+	if err != nil {
+		t.close()
+		return nil, err
+	}
+
+	if cfg.SecretName != "" {
+		if err = t.secretProvider.RegisterSecretUpdatedCallback(cfg.SecretName, t.onSecretUpdated); err != nil {
+			t.close()
+			return nil, fmt.Errorf("failed to watch the %s secret: %w", cfg.SecretName, err)
+		}
+	}
+
+	return func() {
+		if cfg.SecretName != "" {
+			t.secretProvider.DeregisterSecretUpdatedCallback(cfg.SecretName)
+		}
+		t.close()
+	}, nil
+}
+
+// connect connects to the NATS servers with the credentials and certificates of the secret.
+func (t *rpcTrigger) connect() (*nats.Conn, error) {
+	cfg := t.cfg
+	opts := []nats.Option{
+		nats.Name(serviceKey),
+		nats.Timeout(cfg.connectTimeout),
+		// keep reconnecting, JetStream messages received meanwhile are delivered once reconnected
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				t.tc.Logger.Warnf("disconnected from NATS: %s", err.Error())
+			}
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			t.tc.Logger.Infof("reconnected to NATS server %s", nc.ConnectedUrl())
+		}),
+	}
+	if cfg.CredentialsFile != "" {
+		opts = append(opts, nats.UserCredentials(cfg.CredentialsFile))
+	}
+	authOpts, err := t.secretOptions()
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, authOpts...)
+
+	conn, err := nats.Connect(strings.Join(cfg.Servers, ","), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS server %w", err)
+	}
+	return conn, nil
+}
+
+// subscribeAll subscribes to the subjects on the connection.
+func (t *rpcTrigger) subscribeAll(conn *nats.Conn) ([]*nats.Subscription, error) {
+	var subs []*nats.Subscription
+	var err error
+	if t.cfg.JetStream.Enabled {
+		subs, err = t.subscribeJetStream(conn, t.cfg)
+	} else {
+		subs, err = t.subscribe(conn, t.cfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.tc.Logger.Infof("NATS trigger subscribed to %s on %s", strings.Join(t.cfg.Subjects, ", "), conn.ConnectedUrl())
+	return subs, nil
+}
+
+// onSecretUpdated rebuilds the connection with the updated secret. The current connection is kept
+// if the updated secret is rejected by the server or the new connection fails to subscribe.
+func (t *rpcTrigger) onSecretUpdated(secretName string) {
+	t.tc.Logger.Infof("secret %s updated, reconnecting to NATS", secretName)
+	conn, err := t.connect()
+	if err != nil {
+		t.tc.Logger.Errorf("failed to reconnect to NATS with the updated %s secret, keeping the current connection: %s",
+			secretName, err.Error())
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	subs, err := t.subscribeAll(conn)
+	if err != nil && t.cfg.JetStream.Enabled && t.cfg.QueueGroup == "" {
+		// a durable consumer without a deliver group accepts a single subscription, so the previous
+		// subscriptions are removed first and subscribed again if the new connection still fails
+		unsubscribeAll(t.subs)
+		if subs, err = t.subscribeAll(conn); err != nil {
+			var resubscribeErr error
+			if t.subs, resubscribeErr = t.subscribeAll(t.conn); resubscribeErr != nil {
+				t.tc.Logger.Errorf("failed to subscribe again on the current NATS connection: %s", resubscribeErr.Error())
+			}
+		}
+	}
+	if err != nil {
+		conn.Close()
+		t.tc.Logger.Errorf("failed to subscribe with the updated %s secret, keeping the current connection: %s",
+			secretName, err.Error())
+		return
+	}
+
+	// Messages which were being processed on the previous connection are delivered again by JetStream, as they
+	// cannot be acknowledged.
+	unsubscribeAll(t.subs)
+	_ = t.conn.Flush()
+	t.conn.Close()
+	t.conn, t.subs = conn, subs
+}
+
+// close drains the subscriptions, waits for the queued messages to be processed and closes the connection.
+func (t *rpcTrigger) close() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, sub := range t.subs {
+		_ = sub.Drain()
+	}
+	// the pending messages are submitted to the dispatcher once drained, they are bounded by the ConnectTimeout
+	// as a drained subscription only reports it is closed
+	deadline := time.Now().Add(t.cfg.connectTimeout)
+	for _, sub := range t.subs {
+		for sub.IsValid() && time.Now().Before(deadline) {
+			time.Sleep(drainPollInterval)
+		}
+	}
+	// the dispatcher processes the queued messages before the connection replying to them is closed
+	t.dispatcher.Stop()
+	t.dispatcher.UnregisterMetrics(t.metricsManager)
+	if t.conn != nil {
+		t.conn.Close()
+	}
+}
+
+// subscribe receives the messages published on the subjects while the service is running, replying to requests
+// with the response data of the pipeline.
+func (t *rpcTrigger) subscribe(ct *nats.Conn, cfg triggerSettings) ([]*nats.Subscription, error) {
+	handler := func(msg *nats.Msg) {
+		env := envelopeFromMsg(msg)
+
+		var responseHandler interfaces.PipelineResponseHandler
+		// only the first response is received by the requester, when several pipelines match the subject
+		var respondOnce sync.Once
+		if msg.Reply != "" {
+			responseHandler = func(ctx interfaces.AppFunctionContext, _ *interfaces.FunctionPipeline) error {
+				var err error
+				respondOnce.Do(func() {
+					err = msg.RespondMsg(replyMsg(env, ctx.ResponseData(), ctx.ResponseContentType(), nil))
+				})
+				return err
+			}
+		}
+		replyError := func(err error) {
+			if msg.Reply == "" {
+				return
+			}
+			respondOnce.Do(func() {
+				if err = msg.RespondMsg(replyMsg(env, nil, "", err)); err != nil {
+					t.tc.Logger.Errorf("failed to reply to message on %s (%s): %s", msg.Subject, env.CorrelationID, err.Error())
+				}
+			})
+		}
+
+		t.dispatch(env, func() {
+			err := t.tc.MessageReceived(t.buildContext(env), env, responseHandler)
+			if err != nil {
+				t.tc.Logger.Errorf("failed to process message on %s (%s): %s", msg.Subject, env.CorrelationID, err.Error())
+				replyError(err)
+			}
+		}, replyError)
+	}
+
+	subs := make([]*nats.Subscription, 0, len(cfg.Subjects))
+	for _, subject := range cfg.Subjects {
+		var sub *nats.Subscription
+		var err error
+		if cfg.QueueGroup != "" {
+			sub, err = ct.QueueSubscribe(subject, cfg.QueueGroup, handler)
+		} else {
+			sub, err = ct.Subscribe(subject, handler)
+		}
+		if err != nil {
+			unsubscribeAll(subs)
+			return nil, fmt.Errorf("failed to subscribe to %s: %w", subject, err)
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
+
+// subscribeJetStream receives the messages of the stream through durable consumers, which resume where they
+// stopped when the service restarts. Messages are acknowledged once the pipeline processed them, and delivered
+// again when it failed, up to MaxDeliver times. Messages matching no pipeline are terminated.
+func (t *rpcTrigger) subscribeJetStream(ct *nats.Conn, cfg triggerSettings) ([]*nats.Subscription, error) {
+	js, err := ct.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get JetStream context: %w", err)
+	}
+	jsCfg := cfg.JetStream
+
+	if _, err = js.StreamInfo(jsCfg.Stream); errors.Is(err, nats.ErrStreamNotFound) {
+		t.tc.Logger.Infof("creating JetStream stream %s for %s", jsCfg.Stream, strings.Join(cfg.Subjects, ", "))
+		_, err = js.AddStream(&nats.StreamConfig{
+			Name:     jsCfg.Stream,
+			Subjects: cfg.Subjects,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get JetStream stream %s: %w", jsCfg.Stream, err)
+	}
+
+	nak := func(msg *nats.Msg) {
+		if cfg.nakDelay > 0 {
+			_ = msg.NakWithDelay(cfg.nakDelay)
+		} else {
+			_ = msg.Nak()
+		}
+	}
+
+	handler := func(msg *nats.Msg) {
+		env := envelopeFromMsg(msg)
+		// the AckWait only bounds the processing, so it is reset while the message waits for a worker
+		stopProgress := keepInProgress(msg, cfg.ackWait)
+		t.dispatch(env, func() {
+			stopProgress()
+			// the reply subject of JetStream messages is used to acknowledge them, so there is no response,
+			// and the response handler counts the pipelines which processed the message
+			var processed atomic.Int32
+			err := t.tc.MessageReceived(t.buildContext(env), env,
+				func(interfaces.AppFunctionContext, *interfaces.FunctionPipeline) error {
+					processed.Add(1)
+					return nil
+				})
+			if err == nil && processed.Load() == 0 {
+				// delivering it again would not find a pipeline either
+				t.tc.Logger.Warnf("no pipeline matches the message on %s, dropping it", msg.Subject)
+				_ = msg.Term()
+				return
+			}
+			if err == nil {
+				if err = msg.Ack(); err != nil {
+					t.tc.Logger.Errorf("failed to acknowledge message on %s: %s", msg.Subject, err.Error())
+				}
+				return
+			}
+
+			meta, metaErr := msg.Metadata()
+			if metaErr == nil && jsCfg.MaxDeliver > 0 && meta.NumDelivered >= uint64(jsCfg.MaxDeliver) {
+				t.tc.Logger.Errorf("failed to process message %d on %s after %d deliveries, dropping it: %s",
+					meta.Sequence.Stream, msg.Subject, meta.NumDelivered, err.Error())
+				_ = msg.Term()
+				return
+			}
+			t.tc.Logger.Errorf("failed to process message on %s, it will be delivered again: %s", msg.Subject, err.Error())
+			nak(msg)
+		}, func(error) {
+			stopProgress()
+			nak(msg)
+		})
+	}
+
+	subs := make([]*nats.Subscription, 0, len(cfg.Subjects))
+	for _, subject := range cfg.Subjects {
+		durable := jsCfg.Durable
+		if len(cfg.Subjects) > 1 {
+			durable += "_" + consumerNameFor(subject)
+		}
+		if err = ensureConsumer(js, jsCfg.Stream, nats.ConsumerConfig{
+			Durable:       durable,
+			FilterSubject: subject,
+			DeliverGroup:  cfg.QueueGroup,
+			AckPolicy:     nats.AckExplicitPolicy,
+			AckWait:       cfg.ackWait,
+			MaxDeliver:    jsCfg.MaxDeliver,
+		}); err != nil {
+			unsubscribeAll(subs)
+			return nil, fmt.Errorf("failed to create durable consumer %s for %s: %w", durable, subject, err)
+		}
+
+		// binding to the consumer keeps it when the subscription is drained, so that messages published
+		// while the service is down are delivered once it restarts
+		subOpts := []nats.SubOpt{nats.Bind(jsCfg.Stream, durable), nats.ManualAck()}
+		var sub *nats.Subscription
+		if cfg.QueueGroup != "" {
+			sub, err = js.QueueSubscribe(subject, cfg.QueueGroup, handler, subOpts...)
+		} else {
+			sub, err = js.Subscribe(subject, handler, subOpts...)
+		}
+		if err != nil {
+			unsubscribeAll(subs)
+			return nil, fmt.Errorf("failed to subscribe to durable consumer %s: %w", durable, err)
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
+
+// dispatch queues the processing of a message, calling dropped when the dispatcher drops or rejects it.
+func (t *rpcTrigger) dispatch(env types.MessageEnvelope, process func(), dropped func(err error)) {
+	notProcessed := func(err error) {
+		t.tc.Logger.Warnf("message on %s (%s) not processed: %s", env.ReceivedTopic, env.CorrelationID, err.Error())
+		dropped(err)
+	}
+	if err := t.dispatcher.Submit(env.ReceivedTopic, process, notProcessed); err != nil {
+		notProcessed(err)
+	}
+}
+
+// keepInProgress tells the server the message is in progress every half AckWait, which resets its AckWait,
+// until stop is called.
+func keepInProgress(msg *nats.Msg, ackWait time.Duration) (stop func()) {
+	if ackWait <= 0 {
+		ackWait = defaultAckWait
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(ackWait / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				_ = msg.InProgress()
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// ensureConsumer creates the durable push consumer, or updates it with the current settings.
+func ensureConsumer(js nats.JetStreamContext, stream string, cc nats.ConsumerConfig) error {
+	info, err := js.ConsumerInfo(stream, cc.Durable)
+	switch {
+	case errors.Is(err, nats.ErrConsumerNotFound):
+		cc.DeliverSubject = nats.NewInbox()
+		_, err = js.AddConsumer(stream, &cc)
+	case err == nil:
+		cc.DeliverSubject = info.Config.DeliverSubject
+		_, err = js.UpdateConsumer(stream, &cc)
+	}
+	return err
+}
+
+// buildContext builds the context of the pipelines, which holds the subject the message was received on
+// like the context of the EdgeX MessageBus trigger.
+func (t *rpcTrigger) buildContext(env types.MessageEnvelope) interfaces.AppFunctionContext {
+	ctx := t.tc.ContextBuilder(env)
+	ctx.AddValue(interfaces.RECEIVEDTOPIC, env.ReceivedTopic)
+	return ctx
+}
+
+// consumerNameFor makes the subject usable in a consumer name, which cannot contain '.', '*' or '>'.
+func consumerNameFor(subject string) string {
+	return strings.NewReplacer(".", "_", "*", "any", ">", "all").Replace(subject)
+}
+
+func unsubscribeAll(subs []*nats.Subscription) {
+	for _, sub := range subs {
+		_ = sub.Unsubscribe()
+	}
+}
@@ -0,0 +1,456 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg"
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+	"github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap"
+	bootstrapInterfaces "github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap/interfaces"
+	"github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap/interfaces/mocks"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/clients/logger"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"custom-trigger/dispatch"
+)
+
+const testTimeout = 5 * time.Second
+
+// receiver records the envelopes received by the pipeline, failing the first failures deliveries.
+// The response data is the payload in upper case. The pipeline waits for release to be closed when it is set,
+// and takes delay to process each message. With noPipeline, no pipeline matches the messages.
+type receiver struct {
+	mutex      sync.Mutex
+	envelopes  []types.MessageEnvelope
+	failures   int
+	release    chan struct{}
+	delay      time.Duration
+	noPipeline bool
+}
+
+func (r *receiver) messageReceived(ctx interfaces.AppFunctionContext, env types.MessageEnvelope, responseHandler interfaces.PipelineResponseHandler) error {
+	r.mutex.Lock()
+	r.envelopes = append(r.envelopes, env)
+	fail := len(r.envelopes) <= r.failures
+	r.mutex.Unlock()
+
+	if r.release != nil {
+		<-r.release
+	}
+	time.Sleep(r.delay)
+
+	if fail {
+		return errors.New("pipeline failed")
+	}
+	if responseHandler != nil && !r.noPipeline {
+		ctx.SetResponseData(bytes.ToUpper(env.Payload))
+		ctx.SetResponseContentType(common.ContentTypeText)
+		return responseHandler(ctx, nil)
+	}
+	return nil
+}
+
+func (r *receiver) received() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	payloads := make([]string, 0, len(r.envelopes))
+	for _, env := range r.envelopes {
+		payloads = append(payloads, string(env.Payload))
+	}
+	return payloads
+}
+
+func (r *receiver) envelope(i int) types.MessageEnvelope {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.envelopes[i]
+}
+
+// runServer runs a NATS server with JetStream, the options are updated by the configure functions.
+func runServer(t *testing.T, configure ...func(*server.Options)) *server.Server {
+	opts := &server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	}
+	for _, c := range configure {
+		c(opts)
+	}
+	s, err := server.NewServer(opts)
+	require.NoError(t, err)
+	go s.Start()
+	require.True(t, s.ReadyForConnections(testTimeout), "NATS server did not start")
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+func startTrigger(t *testing.T, cfg NatsTriggerConfig, r *receiver) bootstrap.Deferred {
+	_, deferred := startTriggerWithSecrets(t, cfg, r, nil)
+	return deferred
+}
+
+func startTriggerWithSecrets(t *testing.T, cfg NatsTriggerConfig, r *receiver, secretProvider bootstrapInterfaces.SecretProvider) (*rpcTrigger, bootstrap.Deferred) {
+	metricsManager := &mocks.MetricsManager{}
+	metricsManager.On("Register", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	metricsManager.On("Unregister", mock.Anything)
+
+	trigger := &rpcTrigger{
+		secretProvider: secretProvider,
+		metricsManager: metricsManager,
+		tc: interfaces.TriggerConfig{
+			Logger: logger.NewMockClient(),
+			ContextBuilder: func(env types.MessageEnvelope) interfaces.AppFunctionContext {
+				return pkg.NewAppFuncContextForTest(env.CorrelationID, logger.NewMockClient())
+			},
+			MessageReceived: r.messageReceived,
+			ConfigLoader: func(config interfaces.UpdatableConfig, sectionName string) error {
+				require.Equal(t, natsTriggerSection, sectionName)
+				config.(*natsServiceConfig).NatsTrigger = cfg
+				return nil
+			},
+		},
+	}
+	deferred, err := trigger.Initialize(&sync.WaitGroup{}, context.Background(), nil)
+	require.NoError(t, err)
+	return trigger, deferred
+}
+
+func connect(t *testing.T, s *server.Server) (*nats.Conn, nats.JetStreamContext) {
+	nc, err := nats.Connect(s.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(nc.Close)
+	js, err := nc.JetStream()
+	require.NoError(t, err)
+	return nc, js
+}
+
+func jetStreamConfig(s *server.Server, maxDeliver int) NatsTriggerConfig {
+	return NatsTriggerConfig{
+		Servers:  []string{s.ClientURL()},
+		Subjects: []string{"rpc.*"},
+		JetStream: JetStreamConfig{
+			Enabled:    true,
+			Stream:     "RPC",
+			Durable:    "test",
+			AckWait:    "1s",
+			MaxDeliver: maxDeliver,
+		},
+	}
+}
+
+func TestCoreRequestReply(t *testing.T) {
+	s := runServer(t)
+	r := &receiver{}
+	deferred := startTrigger(t, NatsTriggerConfig{
+		Servers:    []string{s.ClientURL()},
+		Subjects:   []string{"rpc.*"},
+		QueueGroup: "workers",
+	}, r)
+	defer deferred()
+
+	nc, _ := connect(t, s)
+	req := nats.NewMsg("rpc.test")
+	req.Data = []byte("hello")
+	req.Header.Set(correlationIDHeader, "correlation-1")
+	req.Header.Set(contentTypeHeader, common.ContentTypeText)
+	req.Header.Set(requestIDHeader, "request-1")
+	reply, err := nc.RequestMsg(req, testTimeout)
+	require.NoError(t, err)
+
+	assert.Equal(t, "HELLO", string(reply.Data))
+	assert.Equal(t, common.ContentTypeText, reply.Header.Get(contentTypeHeader))
+	assert.Equal(t, "correlation-1", reply.Header.Get(correlationIDHeader))
+	assert.Equal(t, "request-1", reply.Header.Get(requestIDHeader))
+	assert.Equal(t, "0", reply.Header.Get(errorCodeHeader))
+
+	env := r.envelope(0)
+	assert.Equal(t, "rpc/test", env.ReceivedTopic)
+	assert.Equal(t, "correlation-1", env.CorrelationID)
+	assert.Equal(t, "request-1", env.RequestID)
+	assert.Equal(t, common.ContentTypeText, env.ContentType)
+}
+
+func TestCoreRequestDefaults(t *testing.T) {
+	s := runServer(t)
+	r := &receiver{}
+	deferred := startTrigger(t, NatsTriggerConfig{
+		Servers:  []string{s.ClientURL()},
+		Subjects: []string{"rpc.>"},
+	}, r)
+	defer deferred()
+
+	nc, _ := connect(t, s)
+	reply, err := nc.Request("rpc.a.b", []byte("hello"), testTimeout)
+	require.NoError(t, err)
+
+	env := r.envelope(0)
+	assert.Equal(t, "rpc/a/b", env.ReceivedTopic)
+	assert.NotEmpty(t, env.CorrelationID)
+	assert.Equal(t, common.ContentTypeJSON, env.ContentType)
+	assert.Equal(t, env.CorrelationID, reply.Header.Get(correlationIDHeader))
+}
+
+func TestCoreRequestPipelineError(t *testing.T) {
+	s := runServer(t)
+	r := &receiver{failures: 1}
+	deferred := startTrigger(t, NatsTriggerConfig{
+		Servers:  []string{s.ClientURL()},
+		Subjects: []string{"rpc.*"},
+	}, r)
+	defer deferred()
+
+	nc, _ := connect(t, s)
+	reply, err := nc.Request("rpc.test", []byte("hello"), testTimeout)
+	require.NoError(t, err)
+
+	assert.Equal(t, "1", reply.Header.Get(errorCodeHeader))
+	assert.Equal(t, "pipeline failed", string(reply.Data))
+}
+
+func TestCoreRequestQueueFull(t *testing.T) {
+	s := runServer(t)
+	r := &receiver{release: make(chan struct{})}
+	deferred := startTrigger(t, NatsTriggerConfig{
+		Servers:  []string{s.ClientURL()},
+		Subjects: []string{"rpc.*"},
+		Dispatch: dispatch.Config{Workers: 1, QueueDepth: 1, Overflow: dispatch.OverflowReject},
+	}, r)
+	defer deferred()
+
+	nc, _ := connect(t, s)
+	replies := make(chan *nats.Msg, 3)
+	request := func() {
+		reply, err := nc.Request("rpc.test", []byte("hello"), testTimeout)
+		assert.NoError(t, err)
+		replies <- reply
+	}
+
+	// one request is processed, one is queued and the last one is rejected
+	go request()
+	require.Eventually(t, func() bool { return len(r.received()) == 1 }, testTimeout, 10*time.Millisecond)
+	go request()
+	go request()
+	rejected := <-replies
+	assert.Equal(t, "1", rejected.Header.Get(errorCodeHeader))
+	assert.Equal(t, dispatch.ErrQueueFull.Error(), string(rejected.Data))
+
+	close(r.release)
+	for i := 0; i < 2; i++ {
+		reply := <-replies
+		assert.Equal(t, "0", reply.Header.Get(errorCodeHeader))
+	}
+}
+
+func TestCloseProcessesPendingRequests(t *testing.T) {
+	s := runServer(t)
+	r := &receiver{release: make(chan struct{})}
+	deferred := startTrigger(t, NatsTriggerConfig{
+		Servers:  []string{s.ClientURL()},
+		Subjects: []string{"rpc.*"},
+		Dispatch: dispatch.Config{Workers: 1, QueueDepth: 1, Overflow: dispatch.OverflowBlock},
+	}, r)
+
+	nc, _ := connect(t, s)
+	replies := make(chan *nats.Msg, 3)
+	request := func() {
+		reply, err := nc.Request("rpc.test", []byte("hello"), testTimeout)
+		assert.NoError(t, err)
+		replies <- reply
+	}
+
+	// one request is processed, one is queued and the last one waits in the subscription for space in the queue
+	go request()
+	require.Eventually(t, func() bool { return len(r.received()) == 1 }, testTimeout, 10*time.Millisecond)
+	go request()
+	go request()
+	time.Sleep(100 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		deferred()
+		close(closed)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	close(r.release)
+	for i := 0; i < 3; i++ {
+		reply := <-replies
+		assert.Equal(t, "0", reply.Header.Get(errorCodeHeader))
+		assert.Equal(t, "HELLO", string(reply.Data))
+	}
+	<-closed
+}
+
+func TestJetStreamAckAfterPipeline(t *testing.T) {
+	s := runServer(t)
+	r := &receiver{}
+	deferred := startTrigger(t, jetStreamConfig(s, 5), r)
+	defer deferred()
+
+	_, js := connect(t, s)
+	_, err := js.Publish("rpc.test", []byte("hello"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		info, err := js.ConsumerInfo("RPC", "test")
+		return err == nil && info.AckFloor.Stream == 1 && info.NumAckPending == 0
+	}, testTimeout, 10*time.Millisecond)
+	assert.Equal(t, []string{"hello"}, r.received())
+}
+
+func TestJetStreamRedeliveryOnPipelineError(t *testing.T) {
+	s := runServer(t)
+	r := &receiver{failures: 2}
+	deferred := startTrigger(t, jetStreamConfig(s, 5), r)
+	defer deferred()
+
+	_, js := connect(t, s)
+	_, err := js.Publish("rpc.test", []byte("hello"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		info, err := js.ConsumerInfo("RPC", "test")
+		return err == nil && info.AckFloor.Stream == 1
+	}, testTimeout, 10*time.Millisecond)
+	assert.Equal(t, []string{"hello", "hello", "hello"}, r.received())
+}
+
+func TestJetStreamMaxDeliver(t *testing.T) {
+	s := runServer(t)
+	r := &receiver{failures: 100}
+	deferred := startTrigger(t, jetStreamConfig(s, 2), r)
+	defer deferred()
+
+	_, js := connect(t, s)
+	_, err := js.Publish("rpc.test", []byte("hello"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		info, err := js.ConsumerInfo("RPC", "test")
+		return err == nil && info.AckFloor.Stream == 1
+	}, testTimeout, 10*time.Millisecond)
+	// the message is terminated after the last delivery, and not delivered again once AckWait expires
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, []string{"hello", "hello"}, r.received())
+}
+
+func TestJetStreamNoRedeliveryWhileQueued(t *testing.T) {
+	s := runServer(t)
+	// each message takes most of the AckWait, so the last one waits longer than the AckWait for the worker
+	r := &receiver{delay: 600 * time.Millisecond}
+	cfg := jetStreamConfig(s, 5)
+	cfg.Dispatch = dispatch.Config{Workers: 1, QueueDepth: 10}
+	deferred := startTrigger(t, cfg, r)
+	defer deferred()
+
+	_, js := connect(t, s)
+	for _, payload := range []string{"first", "second", "third"} {
+		_, err := js.Publish("rpc.test", []byte(payload))
+		require.NoError(t, err)
+	}
+
+	require.Eventually(t, func() bool {
+		info, err := js.ConsumerInfo("RPC", "test")
+		return err == nil && info.AckFloor.Stream == 3
+	}, testTimeout, 10*time.Millisecond)
+	// no message was delivered again while it was queued
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, []string{"first", "second", "third"}, r.received())
+}
+
+func TestJetStreamNoMatchingPipeline(t *testing.T) {
+	s := runServer(t)
+	r := &receiver{noPipeline: true}
+	deferred := startTrigger(t, jetStreamConfig(s, 5), r)
+	defer deferred()
+
+	_, js := connect(t, s)
+	_, err := js.Publish("rpc.test", []byte("hello"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		info, err := js.ConsumerInfo("RPC", "test")
+		return err == nil && info.AckFloor.Stream == 1 && info.NumAckPending == 0
+	}, testTimeout, 10*time.Millisecond)
+	// the message is terminated rather than acknowledged as processed, and not delivered again
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, []string{"hello"}, r.received())
+}
+
+func TestJetStreamDurableConsumerResumes(t *testing.T) {
+	s := runServer(t)
+	r := &receiver{}
+	cfg := jetStreamConfig(s, 5)
+	deferred := startTrigger(t, cfg, r)
+
+	_, js := connect(t, s)
+	_, err := js.Publish("rpc.first", []byte("first"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(r.received()) == 1 }, testTimeout, 10*time.Millisecond)
+
+	// messages published while the service is down are kept by the stream
+	deferred()
+	_, err = js.Publish("rpc.second", []byte("second"))
+	require.NoError(t, err)
+
+	deferred = startTrigger(t, cfg, r)
+	defer deferred()
+	require.Eventually(t, func() bool { return len(r.received()) == 2 }, testTimeout, 10*time.Millisecond)
+	assert.Equal(t, []string{"first", "second"}, r.received())
+}
+
+func TestParseTriggerConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     NatsTriggerConfig
+		wantErr bool
+	}{
+		{"defaults", NatsTriggerConfig{}, false},
+		{"invalid connect timeout", NatsTriggerConfig{ConnectTimeout: "soon"}, true},
+		{"jetstream without stream", NatsTriggerConfig{JetStream: JetStreamConfig{Enabled: true, Durable: "d"}}, true},
+		{"jetstream without durable", NatsTriggerConfig{JetStream: JetStreamConfig{Enabled: true, Stream: "s"}}, true},
+		{"jetstream invalid ack wait", NatsTriggerConfig{JetStream: JetStreamConfig{Enabled: true, Stream: "s", Durable: "d", AckWait: "-1s"}}, true},
+		{"jetstream", NatsTriggerConfig{JetStream: JetStreamConfig{Enabled: true, Stream: "s", Durable: "d"}}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := parseTriggerConfig(test.cfg)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, s.Servers)
+			assert.Equal(t, []string{defaultSubject}, s.Subjects)
+		})
+	}
+}
@@ -0,0 +1,294 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package coaptrigger provides a custom trigger running the pipelines for the requests of a CoAP server, such as
+// the ones of constrained devices, and answering them with the pipeline response.
+package coaptrigger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+	"github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap"
+	bootstrapInterfaces "github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap/interfaces"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+	"github.com/google/uuid"
+	"github.com/plgd-dev/go-coap/v3/message"
+	"github.com/plgd-dev/go-coap/v3/message/codes"
+	"github.com/plgd-dev/go-coap/v3/mux"
+	coapNet "github.com/plgd-dev/go-coap/v3/net"
+	"github.com/plgd-dev/go-coap/v3/net/blockwise"
+	"github.com/plgd-dev/go-coap/v3/options"
+	"github.com/plgd-dev/go-coap/v3/udp"
+
+	"custom-trigger/dispatch"
+	"custom-trigger/internal/triggerconfig"
+)
+
+const (
+	sectionName = "CoapTrigger"
+
+	defaultListenAddress = ":5683"
+)
+
+// Config holds the settings of the CoAP trigger, loaded from the CoapTrigger section
+type Config struct {
+	// ListenAddress is the UDP address of the CoAP server, defaults to :5683
+	ListenAddress string
+	// TopicPrefix precedes the URI path in the received topic, so that a request to /sensors/temp is received on
+	// <TopicPrefix>/sensors/temp. The topic is the URI path alone when it is not set.
+	TopicPrefix string
+	// Dispatch bounds the number of requests processed concurrently
+	Dispatch dispatch.Config
+}
+
+// serviceConfig wraps the CoapTrigger section so that it can be loaded by the ConfigLoader of the trigger
+type serviceConfig struct {
+	CoapTrigger Config
+}
+
+// UpdateFromRaw updates the configuration from raw data received from the Configuration Provider.
+func (c *serviceConfig) UpdateFromRaw(rawConfig interface{}) bool {
+	return triggerconfig.UpdateFromRaw(c, rawConfig)
+}
+
+// contentTypes are the content types of the CoAP content formats
+var contentTypes = map[message.MediaType]string{
+	message.TextPlain: common.ContentTypeText,
+	message.AppXML:    common.ContentTypeXML,
+	message.AppOctets: "application/octet-stream",
+	message.AppJSON:   common.ContentTypeJSON,
+	message.AppCBOR:   common.ContentTypeCBOR,
+}
+
+type trigger struct {
+	tc             interfaces.TriggerConfig
+	metricsManager bootstrapInterfaces.MetricsManager
+
+	cfg        Config
+	dispatcher *dispatch.Dispatcher
+	// addr is the address the server listens on, once initialized
+	addr net.Addr
+}
+
+// NewFactory returns the factory of the CoAP trigger, to be registered with RegisterCustomTriggerFactory.
+func NewFactory(service interfaces.ApplicationService) func(interfaces.TriggerConfig) (interfaces.Trigger, error) {
+	return func(tc interfaces.TriggerConfig) (interfaces.Trigger, error) {
+		return &trigger{
+			tc:             tc,
+			metricsManager: service.MetricsManager(),
+		}, nil
+	}
+}
+
+func (t *trigger) Initialize(wg *sync.WaitGroup, ctx context.Context, _ <-chan interfaces.BackgroundMessage) (bootstrap.Deferred, error) {
+	sc := &serviceConfig{}
+	if err := t.tc.ConfigLoader(sc, sectionName); err != nil {
+		return nil, fmt.Errorf("failed to load the %s configuration: %w", sectionName, err)
+	}
+	t.cfg = sc.CoapTrigger
+	t.cfg.TopicPrefix = strings.Trim(t.cfg.TopicPrefix, "/")
+	addr := t.cfg.ListenAddress
+	if addr == "" {
+		addr = defaultListenAddress
+	}
+
+	var err error
+	if t.dispatcher, err = dispatch.New(t.cfg.Dispatch); err != nil {
+		return nil, fmt.Errorf("invalid %s Dispatch configuration: %w", sectionName, err)
+	}
+	if err = t.dispatcher.RegisterMetrics(t.metricsManager, map[string]string{"trigger": "custom-coap"}); err != nil {
+		t.dispatcher.Stop()
+		return nil, fmt.Errorf("failed to register the dispatch metrics: %w", err)
+	}
+
+	listener, err := coapNet.NewListenUDP("udp", addr)
+	if err != nil {
+		t.dispatcher.Stop()
+		t.dispatcher.UnregisterMetrics(t.metricsManager)
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	t.addr = listener.LocalAddr()
+	// block-wise transfers are disabled, as they would send the responses to non-confirmable requests as
+	// confirmable messages. The payloads of constrained devices fit in a datagram.
+	server := udp.NewServer(
+		options.WithMux(t),
+		options.WithBlockwise(false, blockwise.SZX1024, 0),
+		options.WithErrors(func(err error) {
+			t.tc.Logger.Debugf("CoAP server: %s", err.Error())
+		}),
+	)
+
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		t.tc.Logger.Infof("CoAP trigger listening on %s", t.addr.String())
+		if err := server.Serve(listener); err != nil {
+			t.tc.Logger.Errorf("CoAP server failed: %s", err.Error())
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		server.Stop()
+		<-served
+	}()
+
+	return func() {
+		t.dispatcher.Stop()
+		t.dispatcher.UnregisterMetrics(t.metricsManager)
+	}, nil
+}
+
+// ServeCOAP runs the pipelines for the request and responds with the response data of the first one. The response
+// is piggybacked in the acknowledgement of confirmable requests, and sent as a non-confirmable message otherwise.
+func (t *trigger) ServeCOAP(w mux.ResponseWriter, r *mux.Message) {
+	if r.Type() == message.NonConfirmable {
+		defer t.sendNonConfirmable(w)
+	}
+
+	env, err := t.envelope(r)
+	if err != nil {
+		t.respondError(w, codes.BadRequest, err)
+		return
+	}
+
+	done := make(chan dispatch.Result, 1)
+	t.dispatcher.Process(t.tc, env, func(res dispatch.Result) { done <- res })
+
+	var res dispatch.Result
+	select {
+	case res = <-done:
+	case <-r.Context().Done():
+		return
+	}
+	switch {
+	case res.NotProcessed:
+		t.tc.Logger.Warnf("CoAP request on %s (%s) not processed: %s", env.ReceivedTopic, env.CorrelationID, res.Err.Error())
+		t.respondError(w, codes.ServiceUnavailable, res.Err)
+		return
+	case res.Err != nil:
+		t.tc.Logger.Errorf("failed to process CoAP request on %s (%s): %s", env.ReceivedTopic, env.CorrelationID, res.Err.Error())
+		t.respondError(w, codes.InternalServerError, res.Err)
+		return
+	}
+
+	if err = w.SetResponse(successCode(r.Code()), message.TextPlain, nil); err != nil {
+		t.tc.Logger.Errorf("failed to set the CoAP response (%s): %s", env.CorrelationID, err.Error())
+		return
+	}
+	if len(res.Data) > 0 {
+		if contentFormat, ok := contentFormatOf(res.ContentType); ok {
+			w.Message().SetContentFormat(contentFormat)
+		}
+		w.Message().SetBody(bytes.NewReader(res.Data))
+	}
+}
+
+// envelope builds the envelope of the request, with the URI path as topic and the URI queries as query parameters.
+func (t *trigger) envelope(r *mux.Message) (types.MessageEnvelope, error) {
+	path, err := r.Options().Path()
+	if err != nil && !errors.Is(err, message.ErrOptionNotFound) {
+		return types.MessageEnvelope{}, fmt.Errorf("invalid URI path: %w", err)
+	}
+	topic := strings.Trim(path, "/")
+	if t.cfg.TopicPrefix != "" {
+		topic = strings.Trim(t.cfg.TopicPrefix+"/"+topic, "/")
+	}
+	if topic == "" {
+		return types.MessageEnvelope{}, errors.New("missing URI path")
+	}
+
+	payload, err := r.ReadBody()
+	if err != nil {
+		return types.MessageEnvelope{}, fmt.Errorf("failed to read the payload: %w", err)
+	}
+
+	env := types.MessageEnvelope{
+		CorrelationID: uuid.NewString(),
+		ContentType:   common.ContentTypeJSON,
+		Payload:       payload,
+		ReceivedTopic: topic,
+	}
+	if contentFormat, err := r.ContentFormat(); err == nil {
+		if contentType, ok := contentTypes[contentFormat]; ok {
+			env.ContentType = contentType
+		}
+	}
+	if queries, err := r.Queries(); err == nil && len(queries) > 0 {
+		env.QueryParams = make(map[string]string, len(queries))
+		for _, query := range queries {
+			name, value, _ := strings.Cut(query, "=")
+			env.QueryParams[name] = value
+		}
+	}
+	return env, nil
+}
+
+// sendNonConfirmable sends the response as a non-confirmable message, as the server would send it as a confirmable
+// one expecting an acknowledgement from the client.
+func (t *trigger) sendNonConfirmable(w mux.ResponseWriter) {
+	response := w.Message()
+	if !response.IsModified() {
+		return
+	}
+	response.SetType(message.NonConfirmable)
+	if err := w.Conn().WriteMessage(response); err != nil {
+		t.tc.Logger.Errorf("failed to send the CoAP response: %s", err.Error())
+	}
+	// the response is not sent again by the server
+	response.SetModified(false)
+}
+
+func (t *trigger) respondError(w mux.ResponseWriter, code codes.Code, err error) {
+	// the error is sent as diagnostic payload
+	if err := w.SetResponse(code, message.TextPlain, strings.NewReader(err.Error())); err != nil {
+		t.tc.Logger.Errorf("failed to set the CoAP response: %s", err.Error())
+	}
+}
+
+// successCode returns the response code of a request processed successfully.
+func successCode(method codes.Code) codes.Code {
+	switch method {
+	case codes.GET:
+		return codes.Content
+	case codes.DELETE:
+		return codes.Deleted
+	default:
+		return codes.Changed
+	}
+}
+
+// contentFormatOf returns the CoAP content format of a content type, ignoring its parameters.
+func contentFormatOf(contentType string) (message.MediaType, bool) {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(mediaType)
+	for contentFormat, candidate := range contentTypes {
+		if strings.EqualFold(mediaType, candidate) {
+			return contentFormat, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,127 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package coaptrigger
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/plgd-dev/go-coap/v3/message"
+	"github.com/plgd-dev/go-coap/v3/message/codes"
+	"github.com/plgd-dev/go-coap/v3/message/pool"
+	"github.com/plgd-dev/go-coap/v3/udp"
+	"github.com/plgd-dev/go-coap/v3/udp/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"custom-trigger/internal/triggertest"
+)
+
+// startTrigger starts the trigger and returns a client connected to it.
+func startTrigger(t *testing.T, cfg Config) (*client.Conn, *triggertest.Pipeline) {
+	cfg.ListenAddress = "127.0.0.1:0"
+	p := &triggertest.Pipeline{}
+	trigger := &trigger{
+		tc: triggertest.TriggerConfig(p, func(config interfaces.UpdatableConfig) {
+			config.(*serviceConfig).CoapTrigger = cfg
+		}),
+		metricsManager: triggertest.MetricsManager(),
+	}
+	_, err := triggertest.Start(t, trigger)
+	require.NoError(t, err)
+
+	conn, err := udp.Dial(trigger.addr.String())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn, p
+}
+
+func body(t *testing.T, resp *pool.Message) string {
+	data, err := resp.ReadBody()
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestConfirmable(t *testing.T) {
+	conn, p := startTrigger(t, Config{TopicPrefix: "coap"})
+
+	resp, err := conn.Post(triggertest.Context(t), "/sensors/temp", message.TextPlain, strings.NewReader("twenty"),
+		message.Option{ID: message.URIQuery, Value: []byte("unit=c")})
+	require.NoError(t, err)
+	assert.Equal(t, message.Acknowledgement, resp.Type(), "the response is piggybacked")
+	assert.Equal(t, codes.Changed, resp.Code())
+	assert.Equal(t, "TWENTY", body(t, resp))
+	contentFormat, err := resp.ContentFormat()
+	require.NoError(t, err)
+	assert.Equal(t, message.TextPlain, contentFormat)
+
+	env := p.Last()
+	assert.Equal(t, "coap/sensors/temp", env.ReceivedTopic)
+	assert.Equal(t, common.ContentTypeText, env.ContentType)
+	assert.Equal(t, "twenty", string(env.Payload))
+	assert.Equal(t, "c", env.QueryParams["unit"])
+
+	resp, err = conn.Get(triggertest.Context(t), "/sensors/temp")
+	require.NoError(t, err)
+	assert.Equal(t, codes.Content, resp.Code())
+	assert.Equal(t, common.ContentTypeJSON, p.Last().ContentType)
+}
+
+func TestNonConfirmable(t *testing.T) {
+	conn, p := startTrigger(t, Config{})
+
+	req, err := conn.NewPostRequest(triggertest.Context(t), "/sensors/humidity", message.AppJSON, strings.NewReader(`{"h":40}`))
+	require.NoError(t, err)
+	req.SetType(message.NonConfirmable)
+	resp, err := conn.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, message.NonConfirmable, resp.Type())
+	assert.Equal(t, codes.Changed, resp.Code())
+	assert.Equal(t, `{"H":40}`, body(t, resp))
+
+	env := p.Last()
+	assert.Equal(t, "sensors/humidity", env.ReceivedTopic)
+	assert.Equal(t, common.ContentTypeJSON, env.ContentType)
+}
+
+func TestErrors(t *testing.T) {
+	conn, _ := startTrigger(t, Config{})
+
+	resp, err := conn.Post(triggertest.Context(t), "/sensors/temp", message.TextPlain, strings.NewReader("fail"))
+	require.NoError(t, err)
+	assert.Equal(t, codes.InternalServerError, resp.Code())
+	assert.Equal(t, "pipeline failed", body(t, resp))
+
+	resp, err = conn.Post(triggertest.Context(t), "/", message.TextPlain, strings.NewReader("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, codes.BadRequest, resp.Code())
+}
+
+func TestContentFormatOf(t *testing.T) {
+	contentFormat, ok := contentFormatOf("text/plain; charset=utf-8")
+	require.True(t, ok)
+	assert.Equal(t, message.TextPlain, contentFormat)
+	contentFormat, ok = contentFormatOf(common.ContentTypeCBOR)
+	require.True(t, ok)
+	assert.Equal(t, message.AppCBOR, contentFormat)
+	_, ok = contentFormatOf("image/png")
+	assert.False(t, ok)
+	_, ok = contentFormatOf("")
+	assert.False(t, ok)
+}
@@ -0,0 +1,40 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"custom-trigger/dispatch"
+	"custom-trigger/internal/triggerconfig"
+)
+
+const stdinTriggerSection = "StdinTrigger"
+
+// StdinTriggerConfig holds the settings of the stdin trigger, loaded from the StdinTrigger section
+type StdinTriggerConfig struct {
+	// Dispatch bounds the number of lines processed concurrently
+	Dispatch dispatch.Config
+}
+
+// stdinServiceConfig wraps the StdinTrigger section so that it can be loaded by the ConfigLoader of the trigger
+type stdinServiceConfig struct {
+	StdinTrigger StdinTriggerConfig
+}
+
+// UpdateFromRaw updates the configuration from raw data received from the Configuration Provider.
+func (c *stdinServiceConfig) UpdateFromRaw(rawConfig interface{}) bool {
+	return triggerconfig.UpdateFromRaw(c, rawConfig)
+}
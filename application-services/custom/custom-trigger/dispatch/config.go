@@ -0,0 +1,70 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package dispatch
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Overflow policies, applied when a message is submitted while the queue is full
+const (
+	// OverflowBlock waits until a worker takes a message from the queue
+	OverflowBlock = "block"
+	// OverflowDropOldest drops the oldest queued message to make room for the new one
+	OverflowDropOldest = "drop-oldest"
+	// OverflowReject rejects the new message
+	OverflowReject = "reject"
+)
+
+const defaultQueueDepth = 100
+
+// Config holds the settings of a Dispatcher, typically loaded as the Dispatch sub-section of a custom trigger
+type Config struct {
+	// Workers is the number of messages processed concurrently, defaults to the number of CPUs
+	Workers int
+	// QueueDepth is the number of messages waiting for a worker, defaults to 100
+	QueueDepth int
+	// Overflow is the policy applied when the queue is full: block (default), drop-oldest or reject
+	Overflow string
+	// OrderByTopic processes the messages of a topic one at a time, in the order they were submitted
+	OrderByTopic bool
+}
+
+// withDefaults applies the defaults and validates the configuration.
+func (c Config) withDefaults() (Config, error) {
+	if c.Workers < 0 {
+		return c, fmt.Errorf("invalid Workers %d", c.Workers)
+	}
+	if c.Workers == 0 {
+		c.Workers = runtime.NumCPU()
+	}
+	if c.QueueDepth < 0 {
+		return c, fmt.Errorf("invalid QueueDepth %d", c.QueueDepth)
+	}
+	if c.QueueDepth == 0 {
+		c.QueueDepth = defaultQueueDepth
+	}
+	switch c.Overflow {
+	case "":
+		c.Overflow = OverflowBlock
+	case OverflowBlock, OverflowDropOldest, OverflowReject:
+	default:
+		return c, fmt.Errorf("invalid Overflow '%s', must be %s, %s or %s", c.Overflow, OverflowBlock, OverflowDropOldest, OverflowReject)
+	}
+	return c, nil
+}
@@ -0,0 +1,258 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package dispatch bounds the number of pipelines run concurrently by a custom trigger. Messages are queued
+// for a pool of workers, with an overflow policy applied when the queue is full, and optionally processed
+// in order for each topic.
+package dispatch
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+	bootstrapInterfaces "github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap/interfaces"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+var (
+	// ErrQueueFull is returned by Submit when the queue is full and the overflow policy is reject
+	ErrQueueFull = errors.New("dispatch queue is full")
+	// ErrDropped is passed to the dropped function of a message removed from the queue by the drop-oldest policy
+	ErrDropped = errors.New("message dropped from the full dispatch queue")
+	// ErrStopped is returned by Submit once the dispatcher is stopped
+	ErrStopped = errors.New("dispatcher is stopped")
+)
+
+// Names of the metrics registered by RegisterMetrics, which are enabled with the Dispatch prefix
+// in Writable.Telemetry.Metrics
+const (
+	QueueDepthName = "DispatchQueueDepth"
+	InFlightName   = "DispatchInFlight"
+	DroppedName    = "DispatchDropped"
+	RejectedName   = "DispatchRejected"
+)
+
+type job struct {
+	topic   string
+	process func()
+	dropped func(err error)
+}
+
+// Dispatcher processes the submitted messages with a pool of workers.
+type Dispatcher struct {
+	cfg Config
+
+	mutex sync.Mutex
+	// ready is signaled when a message is queued or a topic is no longer busy
+	ready *sync.Cond
+	// space is signaled when a message is taken from the queue
+	space    *sync.Cond
+	queue    []job
+	busy     map[string]bool
+	inFlight int
+	stopped  bool
+	wg       sync.WaitGroup
+
+	queueDepthGauge gometrics.Gauge
+	inFlightGauge   gometrics.Gauge
+	dropped         gometrics.Counter
+	rejected        gometrics.Counter
+}
+
+// New validates the configuration and starts the workers.
+func New(cfg Config) (*Dispatcher, error) {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Dispatcher{
+		cfg:             cfg,
+		busy:            map[string]bool{},
+		queueDepthGauge: gometrics.NewGauge(),
+		inFlightGauge:   gometrics.NewGauge(),
+		dropped:         gometrics.NewCounter(),
+		rejected:        gometrics.NewCounter(),
+	}
+	d.ready = sync.NewCond(&d.mutex)
+	d.space = sync.NewCond(&d.mutex)
+
+	d.wg.Add(cfg.Workers)
+	for i := 0; i < cfg.Workers; i++ {
+		go d.work()
+	}
+	return d, nil
+}
+
+// Submit queues process to be run by a worker. When the queue is full, Submit waits for room with the block
+// policy, returns ErrQueueFull with the reject policy, and drops the oldest message with the drop-oldest policy,
+// calling its dropped function with ErrDropped. The dropped function may be nil.
+func (d *Dispatcher) Submit(topic string, process func(), dropped func(err error)) error {
+	d.mutex.Lock()
+	if d.cfg.Overflow == OverflowBlock {
+		for len(d.queue) >= d.cfg.QueueDepth && !d.stopped {
+			d.space.Wait()
+		}
+	}
+	if d.stopped {
+		d.mutex.Unlock()
+		return ErrStopped
+	}
+
+	var oldest job
+	if len(d.queue) >= d.cfg.QueueDepth {
+		if d.cfg.Overflow == OverflowReject {
+			d.mutex.Unlock()
+			d.rejected.Inc(1)
+			return ErrQueueFull
+		}
+		oldest = d.queue[0]
+		d.queue = d.queue[1:]
+		d.dropped.Inc(1)
+	}
+	d.queue = append(d.queue, job{topic: topic, process: process, dropped: dropped})
+	d.queueDepthGauge.Update(int64(len(d.queue)))
+	d.ready.Signal()
+	d.mutex.Unlock()
+
+	if oldest.dropped != nil {
+		oldest.dropped(ErrDropped)
+	}
+	return nil
+}
+
+// Result is the outcome of a message submitted with Process.
+type Result struct {
+	// Data and ContentType are the response data of the first pipeline which processed the message
+	Data        []byte
+	ContentType string
+	// Err is the error of the pipelines, or the error of the message the dispatcher did not process
+	Err error
+	// NotProcessed is set when the message was rejected or dropped, or the dispatcher is stopped
+	NotProcessed bool
+}
+
+// Process submits the message to run the pipelines of the trigger, then calls respond once with the response data
+// of the first pipeline, or with the error of the message.
+func (d *Dispatcher) Process(tc interfaces.TriggerConfig, env types.MessageEnvelope, respond func(Result)) {
+	process := func() {
+		var res Result
+		var respondOnce sync.Once
+		ctx := tc.ContextBuilder(env)
+		ctx.AddValue(interfaces.RECEIVEDTOPIC, env.ReceivedTopic)
+		err := tc.MessageReceived(ctx, env, func(ctx interfaces.AppFunctionContext, _ *interfaces.FunctionPipeline) error {
+			respondOnce.Do(func() {
+				res.Data, res.ContentType = ctx.ResponseData(), ctx.ResponseContentType()
+			})
+			return nil
+		})
+		if err != nil {
+			res = Result{Err: err}
+		}
+		respond(res)
+	}
+	dropped := func(err error) {
+		respond(Result{Err: err, NotProcessed: true})
+	}
+	if err := d.Submit(env.ReceivedTopic, process, dropped); err != nil {
+		dropped(err)
+	}
+}
+
+// Stop rejects the messages submitted from now on and waits for the queued messages to be processed.
+func (d *Dispatcher) Stop() {
+	d.mutex.Lock()
+	d.stopped = true
+	d.ready.Broadcast()
+	d.space.Broadcast()
+	d.mutex.Unlock()
+	d.wg.Wait()
+}
+
+// RegisterMetrics registers the queue depth, in-flight, dropped and rejected metrics with the given tags,
+// typically the name of the trigger.
+func (d *Dispatcher) RegisterMetrics(metricsManager bootstrapInterfaces.MetricsManager, tags map[string]string) error {
+	metrics := map[string]interface{}{
+		QueueDepthName: d.queueDepthGauge,
+		InFlightName:   d.inFlightGauge,
+		DroppedName:    d.dropped,
+		RejectedName:   d.rejected,
+	}
+	for name, metric := range metrics {
+		if err := metricsManager.Register(name, metric, tags); err != nil {
+			d.UnregisterMetrics(metricsManager)
+			return err
+		}
+	}
+	return nil
+}
+
+// UnregisterMetrics unregisters the metrics registered by RegisterMetrics.
+func (d *Dispatcher) UnregisterMetrics(metricsManager bootstrapInterfaces.MetricsManager) {
+	for _, name := range []string{QueueDepthName, InFlightName, DroppedName, RejectedName} {
+		metricsManager.Unregister(name)
+	}
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for {
+		j, ok := d.next()
+		if !ok {
+			return
+		}
+		j.process()
+
+		d.mutex.Lock()
+		d.inFlight--
+		d.inFlightGauge.Update(int64(d.inFlight))
+		if d.cfg.OrderByTopic {
+			delete(d.busy, j.topic)
+			// a message of this topic may be waiting for it
+			d.ready.Broadcast()
+		}
+		d.mutex.Unlock()
+	}
+}
+
+// next waits for a message which can be processed, returning false once the dispatcher is stopped and the queue is
+// empty. With OrderByTopic, the messages of a topic being processed by another worker are skipped.
+func (d *Dispatcher) next() (job, bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for {
+		for i, j := range d.queue {
+			if d.cfg.OrderByTopic {
+				if d.busy[j.topic] {
+					continue
+				}
+				d.busy[j.topic] = true
+			}
+			d.queue = append(d.queue[:i], d.queue[i+1:]...)
+			d.queueDepthGauge.Update(int64(len(d.queue)))
+			d.inFlight++
+			d.inFlightGauge.Update(int64(d.inFlight))
+			d.space.Signal()
+			return j, true
+		}
+		if d.stopped && len(d.queue) == 0 {
+			return job{}, false
+		}
+		d.ready.Wait()
+	}
+}
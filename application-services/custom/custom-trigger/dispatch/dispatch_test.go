@@ -0,0 +1,250 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package dispatch
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg"
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/clients/logger"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTimeout = 5 * time.Second
+
+// blocker holds the processed messages until released.
+type blocker struct {
+	started chan string
+	release chan struct{}
+}
+
+func newBlocker() *blocker {
+	return &blocker{started: make(chan string, 100), release: make(chan struct{})}
+}
+
+func (b *blocker) process(name string) func() {
+	return func() {
+		b.started <- name
+		<-b.release
+	}
+}
+
+func (b *blocker) waitStarted(t *testing.T, name string) {
+	select {
+	case started := <-b.started:
+		require.Equal(t, name, started)
+	case <-time.After(testTimeout):
+		require.Fail(t, "message not processed", name)
+	}
+}
+
+func newDispatcher(t *testing.T, cfg Config) *Dispatcher {
+	d, err := New(cfg)
+	require.NoError(t, err)
+	return d
+}
+
+func TestWorkersLimitConcurrency(t *testing.T) {
+	d := newDispatcher(t, Config{Workers: 2, QueueDepth: 10})
+	var running, maxRunning int32
+	for i := 0; i < 10; i++ {
+		require.NoError(t, d.Submit("topic", func() {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		}, nil))
+	}
+	d.Stop()
+	assert.Equal(t, int32(2), maxRunning)
+}
+
+func TestOverflowBlock(t *testing.T) {
+	b := newBlocker()
+	d := newDispatcher(t, Config{Workers: 1, QueueDepth: 1, Overflow: OverflowBlock})
+	require.NoError(t, d.Submit("topic", b.process("first"), nil))
+	b.waitStarted(t, "first")
+	require.NoError(t, d.Submit("topic", b.process("second"), nil))
+
+	submitted := make(chan error)
+	go func() { submitted <- d.Submit("topic", b.process("third"), nil) }()
+	select {
+	case <-submitted:
+		require.Fail(t, "Submit did not block while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(b.release)
+	require.NoError(t, <-submitted)
+	d.Stop()
+	assert.Equal(t, int64(0), d.queueDepthGauge.Value())
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	b := newBlocker()
+	d := newDispatcher(t, Config{Workers: 1, QueueDepth: 1, Overflow: OverflowDropOldest})
+	require.NoError(t, d.Submit("topic", b.process("first"), nil))
+	b.waitStarted(t, "first")
+
+	var droppedErr error
+	require.NoError(t, d.Submit("topic", b.process("second"), func(err error) { droppedErr = err }))
+	require.NoError(t, d.Submit("topic", b.process("third"), nil))
+	assert.Equal(t, ErrDropped, droppedErr)
+	assert.Equal(t, int64(1), d.dropped.Count())
+
+	close(b.release)
+	b.waitStarted(t, "third")
+	d.Stop()
+}
+
+func TestOverflowReject(t *testing.T) {
+	b := newBlocker()
+	d := newDispatcher(t, Config{Workers: 1, QueueDepth: 1, Overflow: OverflowReject})
+	require.NoError(t, d.Submit("topic", b.process("first"), nil))
+	b.waitStarted(t, "first")
+	require.NoError(t, d.Submit("topic", b.process("second"), nil))
+
+	assert.Equal(t, ErrQueueFull, d.Submit("topic", b.process("third"), nil))
+	assert.Equal(t, int64(1), d.rejected.Count())
+
+	close(b.release)
+	b.waitStarted(t, "second")
+	d.Stop()
+}
+
+func TestOrderByTopic(t *testing.T) {
+	d := newDispatcher(t, Config{Workers: 4, QueueDepth: 100, OrderByTopic: true})
+	var mutex sync.Mutex
+	processed := map[string][]int{}
+	running := map[string]bool{}
+	for i := 0; i < 20; i++ {
+		topic := fmt.Sprintf("topic%d", i%2)
+		i := i
+		require.NoError(t, d.Submit(topic, func() {
+			mutex.Lock()
+			assert.False(t, running[topic], "messages of %s processed concurrently", topic)
+			running[topic] = true
+			mutex.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mutex.Lock()
+			running[topic] = false
+			processed[topic] = append(processed[topic], i)
+			mutex.Unlock()
+		}, nil))
+	}
+	d.Stop()
+	assert.Equal(t, []int{0, 2, 4, 6, 8, 10, 12, 14, 16, 18}, processed["topic0"])
+	assert.Equal(t, []int{1, 3, 5, 7, 9, 11, 13, 15, 17, 19}, processed["topic1"])
+}
+
+func TestStop(t *testing.T) {
+	d := newDispatcher(t, Config{Workers: 1, QueueDepth: 10})
+	var count int32
+	for i := 0; i < 5; i++ {
+		require.NoError(t, d.Submit("topic", func() { atomic.AddInt32(&count, 1) }, nil))
+	}
+	d.Stop()
+	assert.Equal(t, int32(5), count, "queued messages are processed before Stop returns")
+	assert.Equal(t, ErrStopped, d.Submit("topic", func() {}, nil))
+}
+
+func TestStopUnblocksSubmit(t *testing.T) {
+	b := newBlocker()
+	d := newDispatcher(t, Config{Workers: 1, QueueDepth: 1})
+	require.NoError(t, d.Submit("topic", b.process("first"), nil))
+	b.waitStarted(t, "first")
+	require.NoError(t, d.Submit("topic", b.process("second"), nil))
+
+	submitted := make(chan error)
+	go func() { submitted <- d.Submit("topic", b.process("third"), nil) }()
+	stopped := make(chan struct{})
+	go func() {
+		d.Stop()
+		close(stopped)
+	}()
+
+	require.Equal(t, ErrStopped, <-submitted)
+	close(b.release)
+	<-stopped
+}
+
+func TestProcess(t *testing.T) {
+	d := newDispatcher(t, Config{Workers: 1, QueueDepth: 1})
+	tc := interfaces.TriggerConfig{
+		ContextBuilder: func(env types.MessageEnvelope) interfaces.AppFunctionContext {
+			return pkg.NewAppFuncContextForTest(env.CorrelationID, logger.NewMockClient())
+		},
+		MessageReceived: func(ctx interfaces.AppFunctionContext, env types.MessageEnvelope, handler interfaces.PipelineResponseHandler) error {
+			if env.ReceivedTopic == "fail" {
+				return errors.New("pipeline failed")
+			}
+			// only the response data of the first pipeline is returned
+			for _, data := range []string{"first", "second"} {
+				ctx.SetResponseData([]byte(data))
+				ctx.SetResponseContentType("text/plain")
+				if err := handler(ctx, &interfaces.FunctionPipeline{Id: data}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	process := func(topic string) Result {
+		results := make(chan Result, 1)
+		d.Process(tc, types.MessageEnvelope{ReceivedTopic: topic}, func(res Result) { results <- res })
+		select {
+		case res := <-results:
+			return res
+		case <-time.After(testTimeout):
+			require.Fail(t, "no result", topic)
+			return Result{}
+		}
+	}
+
+	assert.Equal(t, Result{Data: []byte("first"), ContentType: "text/plain"}, process("ok"))
+	assert.Equal(t, Result{Err: errors.New("pipeline failed")}, process("fail"))
+	d.Stop()
+	assert.Equal(t, Result{Err: ErrStopped, NotProcessed: true}, process("ok"))
+}
+
+func TestConfigDefaults(t *testing.T) {
+	cfg, err := Config{}.withDefaults()
+	require.NoError(t, err)
+	assert.Positive(t, cfg.Workers)
+	assert.Equal(t, defaultQueueDepth, cfg.QueueDepth)
+	assert.Equal(t, OverflowBlock, cfg.Overflow)
+
+	for _, invalid := range []Config{{Workers: -1}, {QueueDepth: -1}, {Overflow: "drop"}} {
+		_, err = New(invalid)
+		assert.Error(t, err, "%+v", invalid)
+	}
+}
@@ -0,0 +1,81 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package filewatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// position is the position reached in a file tailed in line mode
+type position struct {
+	// Path is the path the file was last read from
+	Path string
+	// Size and ModTime are the size and the modification time of the file when it was last read
+	Size    int64
+	ModTime time.Time
+	// Offset is the offset following the last line sent
+	Offset int64
+	// Line is the number of lines sent
+	Line int
+	// Failed is set once the pipeline failed to process a line, the file is then moved to the FailedDirectory
+	Failed bool
+}
+
+// checkpoint holds the positions reached in the files, by file id. It is saved after each batch of lines, so the
+// lines sent since the last save are sent again when the service is stopped abruptly.
+type checkpoint struct {
+	file      string
+	positions map[string]position
+}
+
+func loadCheckpoint(file string) (*checkpoint, error) {
+	c := &checkpoint{file: file, positions: map[string]position{}}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint file %s: %w", file, err)
+	}
+	if err = json.Unmarshal(data, &c.positions); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint file %s: %w", file, err)
+	}
+	return c, nil
+}
+
+// save writes the checkpoint to a temporary file renamed over the checkpoint file, so that it is never
+// left partially written.
+func (c *checkpoint) save() error {
+	data, err := json.MarshalIndent(c.positions, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := c.file + ".tmp"
+	if err = os.MkdirAll(filepath.Dir(c.file), 0755); err != nil {
+		return err
+	}
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.file)
+}
@@ -0,0 +1,154 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package filewatch
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"custom-trigger/internal/triggerconfig"
+)
+
+const (
+	sectionName = "FileTrigger"
+
+	defaultPollInterval   = time.Second
+	defaultSettleTime     = time.Second
+	defaultCheckpointFile = "./filetrigger-checkpoint.json"
+	// defaultFailedDirectory is the FailedDirectory within the Directory, when it is not set
+	defaultFailedDirectory = "failed"
+)
+
+// Modes of the watches
+const (
+	// ModeFile sends one message per file, once it is no longer written to
+	ModeFile = "file"
+	// ModeLine sends one message per line, tailing the files as they are appended to
+	ModeLine = "line"
+)
+
+// Config holds the settings of the file trigger, loaded from the FileTrigger section
+type Config struct {
+	// PollInterval is the interval at which the directories are scanned, defaults to 1s
+	PollInterval string
+	// CheckpointFile records the position reached in the files tailed in line mode, so that they are resumed
+	// after a restart
+	CheckpointFile string
+	// Watches are the watched directories, by name
+	Watches map[string]WatchConfig
+}
+
+// WatchConfig holds the settings of a watched directory
+type WatchConfig struct {
+	// Directory is the watched directory
+	Directory string
+	// Pattern is the glob pattern of the files, relative to the Directory, such as *.csv or */*.json
+	Pattern string
+	// Mode is file (default) or line
+	Mode string
+	// Topic precedes the path of the file relative to the Directory in the received topic, defaults to file/<name>
+	Topic string
+	// ContentType of the messages, defaults to application/json for .json files, text/csv for .csv files and
+	// text/plain otherwise
+	ContentType string
+	// DoneDirectory receives the files processed successfully, they are deleted when it is not set
+	DoneDirectory string
+	// FailedDirectory receives the files the pipeline failed to process or no pipeline matched, defaults to
+	// the failed directory of the Directory. They are never deleted.
+	FailedDirectory string
+	// SettleTime is how long a file must be left unmodified before it is processed in file mode, defaults to 1s
+	SettleTime string
+	// IdleTimeout is how long a file must be left unmodified before it is moved in line mode. Files are tailed
+	// indefinitely when it is not set.
+	IdleTimeout string
+}
+
+// serviceConfig wraps the FileTrigger section so that it can be loaded by the ConfigLoader of the trigger
+type serviceConfig struct {
+	FileTrigger Config
+}
+
+// UpdateFromRaw updates the configuration from raw data received from the Configuration Provider.
+func (c *serviceConfig) UpdateFromRaw(rawConfig interface{}) bool {
+	return triggerconfig.UpdateFromRaw(c, rawConfig)
+}
+
+// watch is a parsed WatchConfig
+type watch struct {
+	WatchConfig
+	name        string
+	settleTime  time.Duration
+	idleTimeout time.Duration
+}
+
+func parseDuration(name string, value string, defaultValue time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s '%s'", name, value)
+	}
+	return d, nil
+}
+
+// parseWatches applies the defaults and validates the configuration of the watches.
+func parseWatches(cfg Config) ([]*watch, error) {
+	if len(cfg.Watches) == 0 {
+		return nil, errors.New("no Watches configured")
+	}
+
+	watches := make([]*watch, 0, len(cfg.Watches))
+	for name, wc := range cfg.Watches {
+		w := &watch{WatchConfig: wc, name: name}
+		if w.Directory == "" {
+			return nil, fmt.Errorf("watch %s: Directory must be set", name)
+		}
+		if w.Pattern == "" {
+			w.Pattern = "*"
+		}
+		if _, err := filepath.Match(w.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("watch %s: invalid Pattern '%s'", name, w.Pattern)
+		}
+		if w.Mode == "" {
+			w.Mode = ModeFile
+		}
+		if w.Mode != ModeFile && w.Mode != ModeLine {
+			return nil, fmt.Errorf("watch %s: invalid Mode '%s', must be %s or %s", name, w.Mode, ModeFile, ModeLine)
+		}
+		if w.Topic == "" {
+			w.Topic = "file/" + name
+		}
+		if w.FailedDirectory == "" {
+			w.FailedDirectory = filepath.Join(w.Directory, defaultFailedDirectory)
+		}
+
+		var err error
+		if w.settleTime, err = parseDuration("SettleTime", w.SettleTime, defaultSettleTime); err != nil {
+			return nil, fmt.Errorf("watch %s: %w", name, err)
+		}
+		if w.idleTimeout, err = parseDuration("IdleTimeout", w.IdleTimeout, 0); err != nil {
+			return nil, fmt.Errorf("watch %s: %w", name, err)
+		}
+		watches = append(watches, w)
+	}
+	sort.Slice(watches, func(i, j int) bool { return watches[i].name < watches[j].name })
+	return watches, nil
+}
@@ -0,0 +1,335 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package filewatch provides a custom trigger sending the files dropped in watched directories through the
+// pipelines, either whole or line by line. The directories are scanned periodically rather than watched with
+// file system notifications, which are not available on the network shares such files are often dropped in.
+package filewatch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+	"github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+	"github.com/google/uuid"
+)
+
+// Keys of the envelope QueryParams
+const (
+	PathParam = "path"
+	LineParam = "line"
+)
+
+// errNoPipeline is the error of the messages no pipeline processed, whose file is moved to the FailedDirectory
+var errNoPipeline = errors.New("no pipeline matches the topic")
+
+type trigger struct {
+	tc         interfaces.TriggerConfig
+	watches    []*watch
+	checkpoint *checkpoint
+}
+
+// NewTrigger is the factory of the file trigger, to be registered with RegisterCustomTriggerFactory.
+func NewTrigger(tc interfaces.TriggerConfig) (interfaces.Trigger, error) {
+	return &trigger{tc: tc}, nil
+}
+
+func (t *trigger) Initialize(wg *sync.WaitGroup, ctx context.Context, _ <-chan interfaces.BackgroundMessage) (bootstrap.Deferred, error) {
+	sc := &serviceConfig{}
+	if err := t.tc.ConfigLoader(sc, sectionName); err != nil {
+		return nil, fmt.Errorf("failed to load the %s configuration: %w", sectionName, err)
+	}
+	cfg := sc.FileTrigger
+
+	var err error
+	if t.watches, err = parseWatches(cfg); err != nil {
+		return nil, fmt.Errorf("invalid %s configuration: %w", sectionName, err)
+	}
+	interval, err := parseDuration("PollInterval", cfg.PollInterval, defaultPollInterval)
+	if err != nil || interval == 0 {
+		return nil, fmt.Errorf("invalid %s configuration: invalid PollInterval '%s'", sectionName, cfg.PollInterval)
+	}
+	checkpointFile := cfg.CheckpointFile
+	if checkpointFile == "" {
+		checkpointFile = defaultCheckpointFile
+	}
+	if t.checkpoint, err = loadCheckpoint(checkpointFile); err != nil {
+		return nil, err
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			t.poll(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {}, nil
+}
+
+// poll processes the files matching the watches, then removes the positions of the files which no longer exist
+// from the checkpoint.
+func (t *trigger) poll(ctx context.Context) {
+	// tailed are the files tailed by this poll, the positions of the others are removed once every
+	// directory was listed
+	tailed := make(map[string]bool)
+	listed := true
+	for _, w := range t.watches {
+		paths, err := filepath.Glob(filepath.Join(w.Directory, w.Pattern))
+		if err != nil {
+			t.tc.Logger.Errorf("failed to list the files of watch %s: %s", w.name, err.Error())
+			listed = false
+			continue
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			if ctx.Err() != nil {
+				return
+			}
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() || w.isOutput(path) {
+				continue
+			}
+			if w.Mode == ModeLine {
+				tailed[t.tailFile(w, path, info)] = true
+			} else {
+				t.processFile(w, path, info)
+			}
+		}
+	}
+	if !listed {
+		return
+	}
+
+	saved := len(t.checkpoint.positions)
+	for id := range t.checkpoint.positions {
+		if !tailed[id] {
+			delete(t.checkpoint.positions, id)
+		}
+	}
+	if len(t.checkpoint.positions) != saved {
+		t.saveCheckpoint()
+	}
+}
+
+// processFile sends the whole file once it has settled, then moves it according to the result of the pipeline.
+func (t *trigger) processFile(w *watch, path string, info fs.FileInfo) {
+	if time.Since(info.ModTime()) < w.settleTime {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.tc.Logger.Errorf("failed to read %s: %s", path, err.Error())
+		return
+	}
+
+	err = t.send(w, path, data, 0)
+	t.move(w, path, err == nil)
+}
+
+// tailFile sends the lines appended to the file since the last poll. The file is moved once it has been idle
+// for the IdleTimeout, along with its last line when it does not end with a newline. The position is kept by
+// file id, so that it follows the file when it is renamed, and the id is returned.
+func (t *trigger) tailFile(w *watch, path string, info fs.FileInfo) string {
+	id := fileID(path, info)
+	pos, found := t.checkpoint.positions[id]
+	switch {
+	case found && info.Size() < pos.Size:
+		t.tc.Logger.Warnf("%s was truncated, reading it from the start", path)
+		pos = position{}
+	case found && info.Size() == pos.Size && !info.ModTime().Equal(pos.ModTime):
+		// appending to the file changes its size
+		t.tc.Logger.Warnf("%s was rewritten, reading it from the start", path)
+		pos = position{}
+	}
+	pos.Path = path
+	idle := w.idleTimeout > 0 && time.Since(info.ModTime()) >= w.idleTimeout
+
+	if info.Size() > pos.Offset || !found {
+		if err := t.sendLines(w, path, &pos, idle); err != nil {
+			t.tc.Logger.Errorf("failed to read %s: %s", path, err.Error())
+		}
+		pos.Size, pos.ModTime = info.Size(), info.ModTime()
+		t.checkpoint.positions[id] = pos
+		t.saveCheckpoint()
+	}
+
+	if idle {
+		t.move(w, path, !pos.Failed)
+		delete(t.checkpoint.positions, id)
+		t.saveCheckpoint()
+	}
+	return id
+}
+
+// sendLines sends the complete lines following the position, and the last incomplete one when final is set.
+func (t *trigger) sendLines(w *watch, path string, pos *position, final bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err = file.Seek(pos.Offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) && (!final || len(line) == 0) {
+			// the incomplete line is sent once the rest of it is written
+			return nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		pos.Offset += int64(len(line))
+		pos.Line++
+		if line = bytes.TrimRight(line, "\r\n"); len(line) > 0 {
+			if sendErr := t.send(w, path, line, pos.Line); sendErr != nil {
+				pos.Failed = true
+			}
+		}
+		if err != nil {
+			return nil
+		}
+	}
+}
+
+// send runs the pipelines with the data read from the file, received on the topic of the watch followed by the
+// path of the file relative to the Directory.
+func (t *trigger) send(w *watch, path string, data []byte, line int) error {
+	rel, err := filepath.Rel(w.Directory, path)
+	if err != nil {
+		rel = filepath.Base(path)
+	}
+
+	env := types.MessageEnvelope{
+		CorrelationID: uuid.NewString(),
+		ContentType:   w.contentTypeOf(path),
+		Payload:       data,
+		ReceivedTopic: w.Topic + "/" + filepath.ToSlash(rel),
+		QueryParams:   map[string]string{PathParam: path},
+	}
+	if line > 0 {
+		env.QueryParams[LineParam] = strconv.Itoa(line)
+	}
+
+	t.tc.Logger.Tracef("sending message to runtime %+v", env)
+
+	ctx := t.tc.ContextBuilder(env)
+	ctx.AddValue(interfaces.RECEIVEDTOPIC, env.ReceivedTopic)
+	// the response handler counts the pipelines which processed the message, as there is no response
+	processed := 0
+	var mutex sync.Mutex
+	err = t.tc.MessageReceived(ctx, env, func(interfaces.AppFunctionContext, *interfaces.FunctionPipeline) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		processed++
+		return nil
+	})
+	if err == nil && processed == 0 {
+		err = errNoPipeline
+	}
+	if err != nil {
+		t.tc.Logger.Errorf("failed to process %s: %s", env.ReceivedTopic, err.Error())
+	}
+	return err
+}
+
+// move moves the file to the DoneDirectory or the FailedDirectory, keeping its path relative to the Directory.
+// The file is deleted when it succeeded and the DoneDirectory is not set.
+func (t *trigger) move(w *watch, path string, succeeded bool) {
+	dir := w.DoneDirectory
+	if !succeeded {
+		dir = w.FailedDirectory
+	}
+	if dir == "" && succeeded {
+		if err := os.Remove(path); err != nil {
+			t.tc.Logger.Errorf("failed to remove %s: %s", path, err.Error())
+		}
+		return
+	}
+
+	rel, err := filepath.Rel(w.Directory, path)
+	if err != nil {
+		rel = filepath.Base(path)
+	}
+	dest := filepath.Join(dir, rel)
+	if _, err = os.Stat(dest); err == nil {
+		// keep the file processed previously with the same name
+		dest += "." + time.Now().Format("20060102T150405.000000000")
+	}
+	if err = os.MkdirAll(filepath.Dir(dest), 0755); err == nil {
+		err = os.Rename(path, dest)
+	}
+	if err != nil {
+		t.tc.Logger.Errorf("failed to move %s to %s: %s", path, dir, err.Error())
+	}
+}
+
+func (t *trigger) saveCheckpoint() {
+	if err := t.checkpoint.save(); err != nil {
+		t.tc.Logger.Errorf("failed to save checkpoint file %s: %s", t.checkpoint.file, err.Error())
+	}
+}
+
+// isOutput reports whether the path is in the DoneDirectory or the FailedDirectory, which may be in the Directory.
+func (w *watch) isOutput(path string) bool {
+	for _, dir := range []string{w.DoneDirectory, w.FailedDirectory} {
+		if rel, err := filepath.Rel(dir, path); dir != "" && err == nil && !strings.HasPrefix(rel, "..") {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *watch) contentTypeOf(path string) string {
+	if w.ContentType != "" {
+		return w.ContentType
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return common.ContentTypeJSON
+	case ".csv":
+		return "text/csv"
+	}
+	return common.ContentTypeText
+}
@@ -0,0 +1,276 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package filewatch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"custom-trigger/internal/triggertest"
+)
+
+func newTrigger(cfg Config, p *triggertest.Pipeline) *trigger {
+	return &trigger{
+		tc: triggertest.TriggerConfig(p, func(config interfaces.UpdatableConfig) {
+			config.(*serviceConfig).FileTrigger = cfg
+		}),
+	}
+}
+
+// pollOnce loads the configuration and the checkpoint as Initialize does, then scans the directories once.
+func pollOnce(t *testing.T, cfg Config, p *triggertest.Pipeline) {
+	trigger := newTrigger(cfg, p)
+	var err error
+	trigger.watches, err = parseWatches(cfg)
+	require.NoError(t, err)
+	trigger.checkpoint, err = loadCheckpoint(cfg.CheckpointFile)
+	require.NoError(t, err)
+	trigger.poll(context.Background())
+}
+
+func writeFile(t *testing.T, path string, content string, modTime time.Time) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func appendFile(t *testing.T, path string, content string, modTime time.Time) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(content)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestFileMode(t *testing.T) {
+	dir := t.TempDir()
+	in, done, failed := filepath.Join(dir, "in"), filepath.Join(dir, "done"), filepath.Join(dir, "failed")
+	cfg := Config{
+		CheckpointFile: filepath.Join(dir, "checkpoint.json"),
+		Watches: map[string]WatchConfig{
+			"plc": {Directory: in, Pattern: "*/*.json", DoneDirectory: done, FailedDirectory: failed},
+		},
+	}
+	old := time.Now().Add(-time.Minute)
+	writeFile(t, filepath.Join(in, "line1", "ok.json"), "ok", old)
+	writeFile(t, filepath.Join(in, "line1", "bad.json"), "fail", old)
+	writeFile(t, filepath.Join(in, "line1", "unmatched.json"), "skip", old)
+	writeFile(t, filepath.Join(in, "line1", "other.csv"), "ignored", old)
+	writeFile(t, filepath.Join(in, "line1", "writing.json"), "not settled", time.Now())
+
+	p := &triggertest.Pipeline{}
+	pollOnce(t, cfg, p)
+
+	assert.Equal(t, []string{"fail", "ok", "skip"}, p.Payloads())
+	env := p.Envelopes()[1]
+	assert.Equal(t, "file/plc/line1/ok.json", env.ReceivedTopic)
+	assert.Equal(t, common.ContentTypeJSON, env.ContentType)
+	assert.Equal(t, filepath.Join(in, "line1", "ok.json"), env.QueryParams[PathParam])
+
+	assert.FileExists(t, filepath.Join(done, "line1", "ok.json"))
+	assert.FileExists(t, filepath.Join(failed, "line1", "bad.json"))
+	assert.FileExists(t, filepath.Join(failed, "line1", "unmatched.json"), "the files no pipeline processed failed")
+	assert.NoFileExists(t, filepath.Join(in, "line1", "ok.json"))
+	assert.FileExists(t, filepath.Join(in, "line1", "writing.json"))
+	assert.FileExists(t, filepath.Join(in, "line1", "other.csv"))
+}
+
+func TestLineModeResumesFromCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	in, done, failed := filepath.Join(dir, "in"), filepath.Join(dir, "done"), filepath.Join(dir, "failed")
+	cfg := Config{
+		CheckpointFile: filepath.Join(dir, "checkpoint.json"),
+		Watches: map[string]WatchConfig{
+			"log": {Directory: in, Pattern: "*.csv", Mode: ModeLine, Topic: "press",
+				DoneDirectory: done, FailedDirectory: failed, IdleTimeout: "30s"},
+		},
+	}
+	path := filepath.Join(in, "press.csv")
+	writeFile(t, path, "a,1\nb,2\nc,", time.Now())
+
+	p := &triggertest.Pipeline{}
+	pollOnce(t, cfg, p)
+	assert.Equal(t, []string{"a,1", "b,2"}, p.Payloads(), "the incomplete line is not sent")
+	assert.Equal(t, "press/press.csv", p.Envelopes()[1].ReceivedTopic)
+	assert.Equal(t, "2", p.Envelopes()[1].QueryParams[LineParam])
+	assert.Equal(t, "text/csv", p.Envelopes()[1].ContentType)
+
+	// a new trigger resumes after the lines already sent
+	appendFile(t, path, "3\n\nd,4\n", time.Now())
+	p = &triggertest.Pipeline{}
+	pollOnce(t, cfg, p)
+	assert.Equal(t, []string{"c,3", "d,4"}, p.Payloads())
+	assert.Equal(t, "5", p.Envelopes()[1].QueryParams[LineParam])
+	assert.FileExists(t, path)
+
+	// the file is moved once idle, along with its last line
+	appendFile(t, path, "e,5", time.Now().Add(-time.Minute))
+	p = &triggertest.Pipeline{}
+	pollOnce(t, cfg, p)
+	assert.Equal(t, []string{"e,5"}, p.Payloads())
+	assert.FileExists(t, filepath.Join(done, "press.csv"))
+
+	checkpoint, err := loadCheckpoint(cfg.CheckpointFile)
+	require.NoError(t, err)
+	assert.Empty(t, checkpoint.positions)
+}
+
+func TestLineModeFailedAndTruncated(t *testing.T) {
+	dir := t.TempDir()
+	in, failed := filepath.Join(dir, "in"), filepath.Join(dir, "failed")
+	cfg := Config{
+		CheckpointFile: filepath.Join(dir, "checkpoint.json"),
+		Watches: map[string]WatchConfig{
+			"log": {Directory: in, Mode: ModeLine, FailedDirectory: failed, IdleTimeout: "30s"},
+		},
+	}
+	path := filepath.Join(in, "events.log")
+	writeFile(t, path, "one\ntwo\n", time.Now())
+	p := &triggertest.Pipeline{}
+	pollOnce(t, cfg, p)
+
+	// the file is read again from the start once truncated
+	writeFile(t, path, "fail\n", time.Now().Add(-time.Minute))
+	pollOnce(t, cfg, p)
+	assert.Equal(t, []string{"one", "two", "fail"}, p.Payloads())
+	assert.FileExists(t, filepath.Join(failed, "events.log"))
+
+	// so is a file rewritten with the same size
+	writeFile(t, path, "six\n", time.Now())
+	pollOnce(t, cfg, p)
+	writeFile(t, path, "ten\n", time.Now().Add(time.Second))
+	pollOnce(t, cfg, p)
+	assert.Equal(t, []string{"one", "two", "fail", "six", "ten"}, p.Payloads())
+}
+
+func TestFailedFilesAreKept(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in")
+	cfg := Config{
+		CheckpointFile: filepath.Join(dir, "checkpoint.json"),
+		Watches:        map[string]WatchConfig{"data": {Directory: in, Pattern: "*.json"}},
+	}
+	old := time.Now().Add(-time.Minute)
+	writeFile(t, filepath.Join(in, "ok.json"), "ok", old)
+	writeFile(t, filepath.Join(in, "bad.json"), "fail", old)
+	writeFile(t, filepath.Join(in, "unmatched.json"), "skip", old)
+
+	p := &triggertest.Pipeline{}
+	pollOnce(t, cfg, p)
+	assert.NoFileExists(t, filepath.Join(in, "ok.json"))
+	assert.FileExists(t, filepath.Join(in, defaultFailedDirectory, "bad.json"))
+	assert.FileExists(t, filepath.Join(in, defaultFailedDirectory, "unmatched.json"))
+
+	// the failed files are not processed again
+	pollOnce(t, cfg, p)
+	assert.Len(t, p.Payloads(), 3)
+}
+
+func TestLineModeFollowsRenamedFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("files are identified by path")
+	}
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in")
+	cfg := Config{
+		CheckpointFile: filepath.Join(dir, "checkpoint.json"),
+		Watches:        map[string]WatchConfig{"log": {Directory: in, Pattern: "app.log*", Mode: ModeLine}},
+	}
+	path := filepath.Join(in, "app.log")
+	writeFile(t, path, "one\ntwo\n", time.Now())
+	p := &triggertest.Pipeline{}
+	pollOnce(t, cfg, p)
+
+	// the rotated file continues from its position, and the new file at the same path is read from the start
+	appendFile(t, path, "three\n", time.Now())
+	require.NoError(t, os.Rename(path, path+".1"))
+	writeFile(t, path, "four\n", time.Now())
+	pollOnce(t, cfg, p)
+	assert.Equal(t, []string{"one", "two", "four", "three"}, p.Payloads())
+
+	checkpoint, err := loadCheckpoint(cfg.CheckpointFile)
+	require.NoError(t, err)
+	require.Len(t, checkpoint.positions, 2)
+
+	// the position of the file removed is dropped
+	require.NoError(t, os.Remove(path+".1"))
+	pollOnce(t, cfg, p)
+	checkpoint, err = loadCheckpoint(cfg.CheckpointFile)
+	require.NoError(t, err)
+	require.Len(t, checkpoint.positions, 1)
+	for _, pos := range checkpoint.positions {
+		assert.Equal(t, path, pos.Path)
+	}
+}
+
+func TestInitialize(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in")
+	writeFile(t, filepath.Join(in, "data.json"), "{}", time.Now().Add(-time.Minute))
+
+	p := &triggertest.Pipeline{}
+	trigger := newTrigger(Config{
+		PollInterval:   "10ms",
+		CheckpointFile: filepath.Join(dir, "checkpoint.json"),
+		Watches:        map[string]WatchConfig{"data": {Directory: in}},
+	}, p)
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	deferred, err := trigger.Initialize(wg, ctx, nil)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return len(p.Payloads()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(in, "data.json"))
+		return os.IsNotExist(err)
+	}, 5*time.Second, 10*time.Millisecond, "the file is deleted without DoneDirectory")
+	assert.NoDirExists(t, filepath.Join(in, defaultFailedDirectory))
+
+	cancel()
+	wg.Wait()
+	deferred()
+}
+
+func TestParseWatches(t *testing.T) {
+	tests := []struct {
+		name  string
+		watch WatchConfig
+	}{
+		{"missing directory", WatchConfig{}},
+		{"invalid pattern", WatchConfig{Directory: "in", Pattern: "[a"}},
+		{"invalid mode", WatchConfig{Directory: "in", Mode: "block"}},
+		{"invalid settle time", WatchConfig{Directory: "in", SettleTime: "soon"}},
+		{"invalid idle timeout", WatchConfig{Directory: "in", IdleTimeout: "-1s"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseWatches(Config{Watches: map[string]WatchConfig{"watch": test.watch}})
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package grpctrigger
+
+import (
+	"custom-trigger/dispatch"
+	"custom-trigger/internal/triggerconfig"
+)
+
+const (
+	sectionName = "GrpcTrigger"
+
+	defaultListenAddress = ":59782"
+)
+
+// Keys of the secret named by SecretName, which are all optional
+const (
+	// SecretTokenKey holds the token the clients must send in the authorization metadata, as Bearer <token>
+	SecretTokenKey = "token"
+	// SecretCertKey and SecretKeyKey hold the PEM encoded certificate and key of the server, enabling TLS
+	SecretCertKey = "cert"
+	SecretKeyKey  = "key"
+	// SecretCACertKey holds the PEM encoded CA certificate of the clients, which must then present a certificate
+	SecretCACertKey = "cacert"
+)
+
+// Config holds the settings of the gRPC trigger, loaded from the GrpcTrigger section
+type Config struct {
+	// ListenAddress is the address of the gRPC server, defaults to :59782
+	ListenAddress string
+	// SecretName is the secret holding the token and the TLS certificates. The token is read for each call so
+	// that it can be rotated, the certificates are read when the server starts.
+	SecretName string
+	// MaxMessageSize is the maximum size in bytes of a request, defaults to the 4MB of gRPC
+	MaxMessageSize int
+	// Dispatch bounds the number of requests processed concurrently
+	Dispatch dispatch.Config
+}
+
+// serviceConfig wraps the GrpcTrigger section so that it can be loaded by the ConfigLoader of the trigger
+type serviceConfig struct {
+	GrpcTrigger Config
+}
+
+// UpdateFromRaw updates the configuration from raw data received from the Configuration Provider.
+func (c *serviceConfig) UpdateFromRaw(rawConfig interface{}) bool {
+	return triggerconfig.UpdateFromRaw(c, rawConfig)
+}
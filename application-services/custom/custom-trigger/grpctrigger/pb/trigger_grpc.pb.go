@@ -0,0 +1,203 @@
+//
+// Copyright (c) 2021 One Track Consulting
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: grpctrigger/pb/trigger.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Trigger_Process_FullMethodName       = "/edgex.trigger.v1.Trigger/Process"
+	Trigger_ProcessStream_FullMethodName = "/edgex.trigger.v1.Trigger/ProcessStream"
+)
+
+// TriggerClient is the client API for Trigger service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TriggerClient interface {
+	// Process runs the pipelines matching the topic and returns the response data of the first one.
+	// A pipeline error is returned as an INTERNAL status.
+	Process(ctx context.Context, in *ProcessRequest, opts ...grpc.CallOption) (*ProcessResponse, error)
+	// ProcessStream processes the requests of the stream concurrently, returning a response for each request
+	// as soon as it is processed. Responses are matched with the requests by their correlation id.
+	ProcessStream(ctx context.Context, opts ...grpc.CallOption) (Trigger_ProcessStreamClient, error)
+}
+
+type triggerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTriggerClient(cc grpc.ClientConnInterface) TriggerClient {
+	return &triggerClient{cc}
+}
+
+func (c *triggerClient) Process(ctx context.Context, in *ProcessRequest, opts ...grpc.CallOption) (*ProcessResponse, error) {
+	out := new(ProcessResponse)
+	err := c.cc.Invoke(ctx, Trigger_Process_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *triggerClient) ProcessStream(ctx context.Context, opts ...grpc.CallOption) (Trigger_ProcessStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Trigger_ServiceDesc.Streams[0], Trigger_ProcessStream_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &triggerProcessStreamClient{stream}
+	return x, nil
+}
+
+type Trigger_ProcessStreamClient interface {
+	Send(*ProcessRequest) error
+	Recv() (*ProcessResponse, error)
+	grpc.ClientStream
+}
+
+type triggerProcessStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *triggerProcessStreamClient) Send(m *ProcessRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *triggerProcessStreamClient) Recv() (*ProcessResponse, error) {
+	m := new(ProcessResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// TriggerServer is the server API for Trigger service.
+// All implementations must embed UnimplementedTriggerServer
+// for forward compatibility
+type TriggerServer interface {
+	// Process runs the pipelines matching the topic and returns the response data of the first one.
+	// A pipeline error is returned as an INTERNAL status.
+	Process(context.Context, *ProcessRequest) (*ProcessResponse, error)
+	// ProcessStream processes the requests of the stream concurrently, returning a response for each request
+	// as soon as it is processed. Responses are matched with the requests by their correlation id.
+	ProcessStream(Trigger_ProcessStreamServer) error
+	mustEmbedUnimplementedTriggerServer()
+}
+
+// UnimplementedTriggerServer must be embedded to have forward compatible implementations.
+type UnimplementedTriggerServer struct {
+}
+
+func (UnimplementedTriggerServer) Process(context.Context, *ProcessRequest) (*ProcessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Process not implemented")
+}
+func (UnimplementedTriggerServer) ProcessStream(Trigger_ProcessStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method ProcessStream not implemented")
+}
+func (UnimplementedTriggerServer) mustEmbedUnimplementedTriggerServer() {}
+
+// UnsafeTriggerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TriggerServer will
+// result in compilation errors.
+type UnsafeTriggerServer interface {
+	mustEmbedUnimplementedTriggerServer()
+}
+
+func RegisterTriggerServer(s grpc.ServiceRegistrar, srv TriggerServer) {
+	s.RegisterService(&Trigger_ServiceDesc, srv)
+}
+
+func _Trigger_Process_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProcessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TriggerServer).Process(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Trigger_Process_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TriggerServer).Process(ctx, req.(*ProcessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Trigger_ProcessStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(TriggerServer).ProcessStream(&triggerProcessStreamServer{stream})
+}
+
+type Trigger_ProcessStreamServer interface {
+	Send(*ProcessResponse) error
+	Recv() (*ProcessRequest, error)
+	grpc.ServerStream
+}
+
+type triggerProcessStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *triggerProcessStreamServer) Send(m *ProcessResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *triggerProcessStreamServer) Recv() (*ProcessRequest, error) {
+	m := new(ProcessRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Trigger_ServiceDesc is the grpc.ServiceDesc for Trigger service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Trigger_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "edgex.trigger.v1.Trigger",
+	HandlerType: (*TriggerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Process",
+			Handler:    _Trigger_Process_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ProcessStream",
+			Handler:       _Trigger_ProcessStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "grpctrigger/pb/trigger.proto",
+}
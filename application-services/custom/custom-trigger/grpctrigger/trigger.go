@@ -0,0 +1,347 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package grpctrigger provides a custom trigger running the pipelines for the requests of a gRPC server, and
+// answering them with the pipeline response. The service is defined by pb/trigger.proto.
+package grpctrigger
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+	"github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap"
+	bootstrapInterfaces "github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap/interfaces"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"custom-trigger/dispatch"
+	"custom-trigger/grpctrigger/pb"
+)
+
+const (
+	shutdownTimeout = 5 * time.Second
+
+	authorizationKey = "authorization"
+	bearerPrefix     = "Bearer "
+)
+
+type trigger struct {
+	pb.UnimplementedTriggerServer
+
+	tc             interfaces.TriggerConfig
+	secretProvider bootstrapInterfaces.SecretProvider
+	metricsManager bootstrapInterfaces.MetricsManager
+
+	cfg        Config
+	dispatcher *dispatch.Dispatcher
+	// addr is the address the server listens on, once initialized
+	addr net.Addr
+}
+
+// NewFactory returns the factory of the gRPC trigger, to be registered with RegisterCustomTriggerFactory.
+func NewFactory(service interfaces.ApplicationService) func(interfaces.TriggerConfig) (interfaces.Trigger, error) {
+	return func(tc interfaces.TriggerConfig) (interfaces.Trigger, error) {
+		return &trigger{
+			tc:             tc,
+			secretProvider: service.SecretProvider(),
+			metricsManager: service.MetricsManager(),
+		}, nil
+	}
+}
+
+func (t *trigger) Initialize(wg *sync.WaitGroup, ctx context.Context, _ <-chan interfaces.BackgroundMessage) (bootstrap.Deferred, error) {
+	sc := &serviceConfig{}
+	if err := t.tc.ConfigLoader(sc, sectionName); err != nil {
+		return nil, fmt.Errorf("failed to load the %s configuration: %w", sectionName, err)
+	}
+	t.cfg = sc.GrpcTrigger
+	addr := t.cfg.ListenAddress
+	if addr == "" {
+		addr = defaultListenAddress
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(t.authorizeUnary),
+		grpc.StreamInterceptor(t.authorizeStream),
+	}
+	if t.cfg.MaxMessageSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(t.cfg.MaxMessageSize))
+	}
+	tlsConfig, err := t.tlsConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s secret: %w", t.cfg.SecretName, err)
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	if t.dispatcher, err = dispatch.New(t.cfg.Dispatch); err != nil {
+		return nil, fmt.Errorf("invalid %s Dispatch configuration: %w", sectionName, err)
+	}
+	if err = t.dispatcher.RegisterMetrics(t.metricsManager, map[string]string{"trigger": "custom-grpc"}); err != nil {
+		t.dispatcher.Stop()
+		return nil, fmt.Errorf("failed to register the dispatch metrics: %w", err)
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.dispatcher.Stop()
+		t.dispatcher.UnregisterMetrics(t.metricsManager)
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	t.addr = listener.Addr()
+	server := grpc.NewServer(opts...)
+	pb.RegisterTriggerServer(server, t)
+
+	go func() {
+		t.tc.Logger.Infof("gRPC trigger listening on %s (TLS: %t)", t.addr.String(), tlsConfig != nil)
+		if err := server.Serve(listener); err != nil {
+			t.tc.Logger.Errorf("gRPC server failed: %s", err.Error())
+		}
+	}()
+
+	// the calls being processed are completed before the service exits, the streams left open are closed
+	// after the shutdown timeout
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(shutdownTimeout):
+			t.tc.Logger.Warn("gRPC server shutdown timed out, closing the remaining calls")
+			server.Stop()
+		}
+	}()
+
+	return func() {
+		t.dispatcher.Stop()
+		t.dispatcher.UnregisterMetrics(t.metricsManager)
+	}, nil
+}
+
+// secret returns the secret named by SecretName, which is empty when no SecretName is configured.
+func (t *trigger) secret() (map[string]string, error) {
+	if t.cfg.SecretName == "" {
+		return map[string]string{}, nil
+	}
+	secret, err := t.secretProvider.GetSecret(t.cfg.SecretName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the %s secret: %w", t.cfg.SecretName, err)
+	}
+	return secret, nil
+}
+
+// tlsConfig returns the TLS configuration of the server when the secret holds a certificate, nil otherwise.
+// Client certificates are required when the secret also holds a CA certificate.
+func (t *trigger) tlsConfig() (*tls.Config, error) {
+	secret, err := t.secret()
+	if err != nil {
+		return nil, err
+	}
+	cert, key, caCert := secret[SecretCertKey], secret[SecretKeyKey], secret[SecretCACertKey]
+	if cert == "" && key == "" {
+		if caCert != "" {
+			return nil, fmt.Errorf("%s requires %s and %s", SecretCACertKey, SecretCertKey, SecretKeyKey)
+		}
+		return nil, nil
+	}
+
+	certificate, err := tls.X509KeyPair([]byte(cert), []byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("invalid server certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if caCert != "" {
+		tlsConfig.ClientCAs = x509.NewCertPool()
+		if !tlsConfig.ClientCAs.AppendCertsFromPEM([]byte(caCert)) {
+			return nil, fmt.Errorf("invalid %s: no PEM certificate found", SecretCACertKey)
+		}
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// authorize verifies the bearer token of the call when the secret holds a token.
+func (t *trigger) authorize(ctx context.Context) error {
+	secret, err := t.secret()
+	if err != nil {
+		t.tc.Logger.Errorf("gRPC call not authorized: %s", err.Error())
+		return status.Error(codes.Unavailable, "failed to verify the token")
+	}
+	token := secret[SecretTokenKey]
+	if token == "" {
+		return nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get(authorizationKey) {
+		if strings.HasPrefix(value, bearerPrefix) &&
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(value, bearerPrefix)), []byte(token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or invalid token")
+}
+
+func (t *trigger) authorizeUnary(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := t.authorize(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (t *trigger) authorizeStream(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := t.authorize(stream.Context()); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// Process runs the pipelines matching the topic of the request and returns the response data of the first one.
+func (t *trigger) Process(ctx context.Context, req *pb.ProcessRequest) (*pb.ProcessResponse, error) {
+	env, err := envelope(req)
+	if err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		resp *pb.ProcessResponse
+		err  error
+	}
+	done := make(chan result, 1)
+	t.submit(env, func(resp *pb.ProcessResponse, err error) {
+		done <- result{resp: resp, err: err}
+	})
+
+	select {
+	case res := <-done:
+		return res.resp, res.err
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+// ProcessStream processes the requests of the stream concurrently through the dispatcher, sending the response of
+// each request as soon as it is processed. Failed requests are answered with their status in the response, so
+// that the stream is kept open.
+func (t *trigger) ProcessStream(stream pb.Trigger_ProcessStreamServer) error {
+	var sendMutex sync.Mutex
+	var pending sync.WaitGroup
+	send := func(resp *pb.ProcessResponse) {
+		sendMutex.Lock()
+		defer sendMutex.Unlock()
+		if err := stream.Send(resp); err != nil {
+			t.tc.Logger.Warnf("failed to send the gRPC response (%s): %s", resp.CorrelationId, err.Error())
+		}
+	}
+	respond := func(correlationID string, resp *pb.ProcessResponse, err error) {
+		if err != nil {
+			s := status.Convert(err)
+			resp = &pb.ProcessResponse{CorrelationId: correlationID, Code: int32(s.Code()), Error: s.Message()}
+		}
+		send(resp)
+	}
+
+	// the responses are sent before the handler returns, which ends the stream
+	defer pending.Wait()
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		env, err := envelope(req)
+		if err != nil {
+			respond(req.CorrelationId, nil, err)
+			continue
+		}
+		pending.Add(1)
+		t.submit(env, func(resp *pb.ProcessResponse, err error) {
+			defer pending.Done()
+			respond(env.CorrelationID, resp, err)
+		})
+	}
+}
+
+// envelope builds the envelope of the request, generating its correlation id when not set.
+func envelope(req *pb.ProcessRequest) (types.MessageEnvelope, error) {
+	if req.Topic == "" {
+		return types.MessageEnvelope{}, status.Error(codes.InvalidArgument, "missing topic")
+	}
+	env := types.MessageEnvelope{
+		CorrelationID: req.CorrelationId,
+		ContentType:   req.ContentType,
+		Payload:       req.Payload,
+		ReceivedTopic: req.Topic,
+		QueryParams:   req.Metadata,
+	}
+	if env.CorrelationID == "" {
+		env.CorrelationID = uuid.NewString()
+	}
+	if env.ContentType == "" {
+		env.ContentType = common.ContentTypeJSON
+	}
+	return env, nil
+}
+
+// submit runs the pipelines through the dispatcher, calling respond with the response data of the first one,
+// or with the status of the failure.
+func (t *trigger) submit(env types.MessageEnvelope, respond func(*pb.ProcessResponse, error)) {
+	t.dispatcher.Process(t.tc, env, func(res dispatch.Result) {
+		switch {
+		case res.NotProcessed:
+			t.tc.Logger.Warnf("gRPC request on %s (%s) not processed: %s", env.ReceivedTopic, env.CorrelationID, res.Err.Error())
+			code := codes.ResourceExhausted
+			if errors.Is(res.Err, dispatch.ErrStopped) {
+				code = codes.Unavailable
+			}
+			respond(nil, status.Error(code, res.Err.Error()))
+		case res.Err != nil:
+			t.tc.Logger.Errorf("failed to process gRPC request on %s (%s): %s", env.ReceivedTopic, env.CorrelationID, res.Err.Error())
+			respond(nil, status.Error(codes.Internal, res.Err.Error()))
+		default:
+			respond(&pb.ProcessResponse{CorrelationId: env.CorrelationID, Payload: res.Data, ContentType: res.ContentType}, nil)
+		}
+	})
+}
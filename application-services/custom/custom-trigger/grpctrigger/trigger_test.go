@@ -0,0 +1,270 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package grpctrigger
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+	"github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap/interfaces/mocks"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"custom-trigger/dispatch"
+	"custom-trigger/grpctrigger/pb"
+	"custom-trigger/internal/triggertest"
+)
+
+const (
+	testSecret = "grpc"
+	testToken  = "t0k3n"
+)
+
+func startTrigger(t *testing.T, cfg Config, p *triggertest.Pipeline, secret map[string]string) (string, error) {
+	secretProvider := &mocks.SecretProvider{}
+	secretProvider.On("GetSecret", testSecret).Return(secret, nil)
+
+	if cfg.ListenAddress == "" {
+		cfg.ListenAddress = "127.0.0.1:0"
+	}
+	trigger := &trigger{
+		tc: triggertest.TriggerConfig(p, func(config interfaces.UpdatableConfig) {
+			config.(*serviceConfig).GrpcTrigger = cfg
+		}),
+		secretProvider: secretProvider,
+		metricsManager: triggertest.MetricsManager(),
+	}
+	if _, err := triggertest.Start(t, trigger); err != nil {
+		return "", err
+	}
+	return trigger.addr.String(), nil
+}
+
+func dial(t *testing.T, addr string, opts ...grpc.DialOption) pb.TriggerClient {
+	if len(opts) == 0 {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+	conn, err := grpc.Dial(addr, opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return pb.NewTriggerClient(conn)
+}
+
+func TestProcess(t *testing.T) {
+	p := &triggertest.Pipeline{}
+	addr, err := startTrigger(t, Config{}, p, nil)
+	require.NoError(t, err)
+	client := dial(t, addr)
+	ctx := triggertest.Context(t)
+
+	resp, err := client.Process(ctx, &pb.ProcessRequest{
+		Topic:         "grpc/sensors",
+		Payload:       []byte("hello"),
+		Metadata:      map[string]string{"device": "d1"},
+		CorrelationId: "c1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "c1", resp.CorrelationId)
+	assert.Equal(t, "HELLO", string(resp.Payload))
+	assert.Equal(t, common.ContentTypeText, resp.ContentType)
+
+	env := p.Envelopes()[0]
+	assert.Equal(t, "grpc/sensors", env.ReceivedTopic)
+	assert.Equal(t, common.ContentTypeJSON, env.ContentType)
+	assert.Equal(t, "d1", env.QueryParams["device"])
+
+	_, err = client.Process(ctx, &pb.ProcessRequest{Topic: "grpc/sensors", Payload: []byte("fail")})
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	_, err = client.Process(ctx, &pb.ProcessRequest{Payload: []byte("hello")})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestProcessStream(t *testing.T) {
+	p := &triggertest.Pipeline{}
+	addr, err := startTrigger(t, Config{Dispatch: dispatch.Config{Workers: 2}}, p, nil)
+	require.NoError(t, err)
+	stream, err := dial(t, addr).ProcessStream(triggertest.Context(t))
+	require.NoError(t, err)
+
+	requests := []*pb.ProcessRequest{
+		{Topic: "grpc/a", Payload: []byte("one"), CorrelationId: "1"},
+		{Topic: "grpc/b", Payload: []byte("fail"), CorrelationId: "2"},
+		{Payload: []byte("no topic"), CorrelationId: "3"},
+		{Topic: "grpc/a", Payload: []byte("four"), CorrelationId: "4"},
+	}
+	for _, req := range requests {
+		require.NoError(t, stream.Send(req))
+	}
+	require.NoError(t, stream.CloseSend())
+
+	responses := map[string]*pb.ProcessResponse{}
+	for range requests {
+		resp, err := stream.Recv()
+		require.NoError(t, err)
+		responses[resp.CorrelationId] = resp
+	}
+
+	assert.Equal(t, "ONE", string(responses["1"].Payload))
+	assert.Equal(t, int32(codes.OK), responses["1"].Code)
+	assert.Equal(t, int32(codes.Internal), responses["2"].Code)
+	assert.Equal(t, "pipeline failed", responses["2"].Error)
+	assert.Equal(t, int32(codes.InvalidArgument), responses["3"].Code)
+	assert.Equal(t, "FOUR", string(responses["4"].Payload))
+}
+
+func TestQueueFull(t *testing.T) {
+	p := &triggertest.Pipeline{Release: make(chan struct{})}
+	addr, err := startTrigger(t, Config{
+		Dispatch: dispatch.Config{Workers: 1, QueueDepth: 1, Overflow: dispatch.OverflowReject},
+	}, p, nil)
+	require.NoError(t, err)
+	client := dial(t, addr)
+	ctx := triggertest.Context(t)
+
+	// the first request is processed and the second one queued, the third one is rejected
+	errs := make(chan error, 2)
+	go func() {
+		_, err := client.Process(ctx, &pb.ProcessRequest{Topic: "grpc/a", Payload: []byte("1")})
+		errs <- err
+	}()
+	require.Eventually(t, func() bool { return p.Count() == 1 }, triggertest.Timeout, 10*time.Millisecond)
+	go func() {
+		_, err := client.Process(ctx, &pb.ProcessRequest{Topic: "grpc/a", Payload: []byte("2")})
+		errs <- err
+	}()
+	require.Eventually(t, func() bool {
+		_, err := client.Process(ctx, &pb.ProcessRequest{Topic: "grpc/a", Payload: []byte("3")})
+		return status.Code(err) == codes.ResourceExhausted
+	}, triggertest.Timeout, 10*time.Millisecond)
+
+	close(p.Release)
+	require.NoError(t, <-errs)
+	require.NoError(t, <-errs)
+}
+
+func TestTokenAuth(t *testing.T) {
+	addr, err := startTrigger(t, Config{SecretName: testSecret}, &triggertest.Pipeline{}, map[string]string{SecretTokenKey: testToken})
+	require.NoError(t, err)
+	client := dial(t, addr)
+	req := &pb.ProcessRequest{Topic: "grpc/a", Payload: []byte("hello")}
+
+	_, err = client.Process(triggertest.Context(t), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(triggertest.Context(t), authorizationKey, bearerPrefix+"wrong")
+	_, err = client.Process(ctx, req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	stream, err := dial(t, addr).ProcessStream(ctx)
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx = metadata.AppendToOutgoingContext(triggertest.Context(t), authorizationKey, bearerPrefix+testToken)
+	resp, err := client.Process(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO", string(resp.Payload))
+}
+
+func TestTLS(t *testing.T) {
+	cert, key := selfSignedCert(t)
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(cert))
+	certificate, err := tls.X509KeyPair(cert, key)
+	require.NoError(t, err)
+	req := &pb.ProcessRequest{Topic: "grpc/a", Payload: []byte("hello")}
+
+	addr, err := startTrigger(t, Config{SecretName: testSecret}, &triggertest.Pipeline{},
+		map[string]string{SecretCertKey: string(cert), SecretKeyKey: string(key)})
+	require.NoError(t, err)
+	tlsClient := dial(t, addr, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{RootCAs: pool})))
+	resp, err := tlsClient.Process(triggertest.Context(t), req)
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO", string(resp.Payload))
+	_, err = dial(t, addr).Process(triggertest.Context(t), req)
+	assert.Equal(t, codes.Unavailable, status.Code(err), "plaintext clients are rejected")
+
+	// client certificates are required along with a CA certificate
+	addr, err = startTrigger(t, Config{SecretName: testSecret}, &triggertest.Pipeline{},
+		map[string]string{SecretCertKey: string(cert), SecretKeyKey: string(key), SecretCACertKey: string(cert)})
+	require.NoError(t, err)
+	_, err = dial(t, addr, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{RootCAs: pool}))).
+		Process(triggertest.Context(t), req)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	mtlsClient := dial(t, addr, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+		RootCAs:      pool,
+		Certificates: []tls.Certificate{certificate},
+	})))
+	_, err = mtlsClient.Process(triggertest.Context(t), req)
+	require.NoError(t, err)
+}
+
+func TestInvalidSecret(t *testing.T) {
+	cert, key := selfSignedCert(t)
+	for name, secret := range map[string]map[string]string{
+		"cert without key": {SecretCertKey: string(cert)},
+		"invalid key":      {SecretCertKey: string(cert), SecretKeyKey: "key"},
+		"ca without cert":  {SecretCACertKey: string(cert)},
+		"invalid ca":       {SecretCertKey: string(cert), SecretKeyKey: string(key), SecretCACertKey: "ca"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := startTrigger(t, Config{SecretName: testSecret}, &triggertest.Pipeline{}, secret)
+			require.Error(t, err)
+		})
+	}
+}
+
+// selfSignedCert returns a PEM encoded certificate and key for 127.0.0.1, usable by the server and the clients.
+func selfSignedCert(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "grpc"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
@@ -0,0 +1,31 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package triggerconfig holds the helpers shared by the configurations of the custom triggers.
+package triggerconfig
+
+// UpdateFromRaw updates the configuration from raw data received from the Configuration Provider, returning false
+// when the raw data is not of the type of the configuration. It implements UpdatableConfig.UpdateFromRaw.
+func UpdateFromRaw[T any](config *T, rawConfig interface{}) bool {
+	configuration, ok := rawConfig.(*T)
+	if !ok {
+		return false
+	}
+
+	*config = *configuration
+
+	return true
+}
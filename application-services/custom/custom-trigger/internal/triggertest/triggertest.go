@@ -0,0 +1,175 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package triggertest provides the fixtures shared by the tests of the custom triggers: a pipeline recording the
+// messages it receives, and the helpers starting a trigger for the duration of a test.
+package triggertest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg"
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+	"github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap/interfaces/mocks"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/clients/logger"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+	"github.com/stretchr/testify/mock"
+)
+
+// Timeout bounds the waits of the tests
+const Timeout = 5 * time.Second
+
+// Received is a message received by the Pipeline
+type Received struct {
+	Envelope types.MessageEnvelope
+	At       time.Time
+}
+
+// Pipeline records the messages and responds with the payload in upper case. The payloads starting with fail fail
+// the pipeline, and the ones starting with skip match no pipeline.
+type Pipeline struct {
+	// Release blocks the pipeline until closed, when set
+	Release chan struct{}
+	// Respond returns the response data of the payload instead of the payload in upper case, when set
+	Respond func(payload []byte) []byte
+
+	mutex    sync.Mutex
+	received []Received
+}
+
+// MessageReceived runs the pipeline, it is the MessageReceived function of the TriggerConfig.
+func (p *Pipeline) MessageReceived(ctx interfaces.AppFunctionContext, env types.MessageEnvelope, responseHandler interfaces.PipelineResponseHandler) error {
+	p.mutex.Lock()
+	p.received = append(p.received, Received{Envelope: env, At: time.Now()})
+	p.mutex.Unlock()
+
+	if p.Release != nil {
+		<-p.Release
+	}
+	if bytes.HasPrefix(env.Payload, []byte("fail")) {
+		return errors.New("pipeline failed")
+	}
+	if bytes.HasPrefix(env.Payload, []byte("skip")) || responseHandler == nil {
+		return nil
+	}
+	if p.Respond != nil {
+		ctx.SetResponseData(p.Respond(env.Payload))
+	} else {
+		ctx.SetResponseData(bytes.ToUpper(env.Payload))
+	}
+	ctx.SetResponseContentType(common.ContentTypeText)
+	return responseHandler(ctx, &interfaces.FunctionPipeline{Id: interfaces.DefaultPipelineId})
+}
+
+// Received returns the messages received so far.
+func (p *Pipeline) Received() []Received {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]Received(nil), p.received...)
+}
+
+// Envelopes returns the envelopes received so far.
+func (p *Pipeline) Envelopes() []types.MessageEnvelope {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	envelopes := make([]types.MessageEnvelope, 0, len(p.received))
+	for _, r := range p.received {
+		envelopes = append(envelopes, r.Envelope)
+	}
+	return envelopes
+}
+
+// Last returns the last envelope received.
+func (p *Pipeline) Last() types.MessageEnvelope {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.received[len(p.received)-1].Envelope
+}
+
+// Count returns the number of messages received.
+func (p *Pipeline) Count() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return len(p.received)
+}
+
+// Payloads returns the payloads received so far.
+func (p *Pipeline) Payloads() []string {
+	envelopes := p.Envelopes()
+	payloads := make([]string, 0, len(envelopes))
+	for _, env := range envelopes {
+		payloads = append(payloads, string(env.Payload))
+	}
+	return payloads
+}
+
+// TriggerConfig returns the configuration of a trigger running the pipeline. Its ConfigLoader calls load with the
+// configuration to fill in.
+func TriggerConfig(p *Pipeline, load func(config interfaces.UpdatableConfig)) interfaces.TriggerConfig {
+	return interfaces.TriggerConfig{
+		Logger: logger.NewMockClient(),
+		ContextBuilder: func(env types.MessageEnvelope) interfaces.AppFunctionContext {
+			return pkg.NewAppFuncContextForTest(env.CorrelationID, logger.NewMockClient())
+		},
+		MessageReceived: p.MessageReceived,
+		ConfigLoader: func(config interfaces.UpdatableConfig, sectionName string) error {
+			load(config)
+			return nil
+		},
+	}
+}
+
+// MetricsManager returns a metrics manager accepting the metrics of the trigger.
+func MetricsManager() *mocks.MetricsManager {
+	metricsManager := &mocks.MetricsManager{}
+	metricsManager.On("Register", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	metricsManager.On("Unregister", mock.Anything)
+	return metricsManager
+}
+
+// Start initializes the trigger, which is stopped at the end of the test or once the returned function is called.
+func Start(t *testing.T, trigger interfaces.Trigger) (func(), error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	deferred, err := trigger.Initialize(wg, ctx, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	var stopOnce sync.Once
+	stop := func() {
+		stopOnce.Do(func() {
+			cancel()
+			wg.Wait()
+			deferred()
+		})
+	}
+	t.Cleanup(stop)
+	return stop, nil
+}
+
+// Context returns a context bounded by the Timeout.
+func Context(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	t.Cleanup(cancel)
+	return ctx
+}
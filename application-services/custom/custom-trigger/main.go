@@ -28,11 +28,21 @@ import (
 	"time"
 
 	"github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap"
+	bootstrapInterfaces "github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap/interfaces"
 	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
 
 	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg"
 	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
 	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/util"
+
+	"custom-trigger/coaptrigger"
+	"custom-trigger/dispatch"
+	"custom-trigger/filewatch"
+	"custom-trigger/grpctrigger"
+	"custom-trigger/replay"
+	"custom-trigger/udptrigger"
+	"custom-trigger/webhook"
+	"custom-trigger/wstrigger"
 )
 
 const (
@@ -40,10 +50,24 @@ const (
 )
 
 type stdinTrigger struct {
-	tc interfaces.TriggerConfig
+	tc             interfaces.TriggerConfig
+	metricsManager bootstrapInterfaces.MetricsManager
 }
 
 func (t *stdinTrigger) Initialize(_ *sync.WaitGroup, ctx context.Context, _ <-chan interfaces.BackgroundMessage) (bootstrap.Deferred, error) {
+	sc := &stdinServiceConfig{}
+	if err := t.tc.ConfigLoader(sc, stdinTriggerSection); err != nil {
+		return nil, fmt.Errorf("failed to load the %s configuration: %w", stdinTriggerSection, err)
+	}
+	dispatcher, err := dispatch.New(sc.StdinTrigger.Dispatch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s Dispatch configuration: %w", stdinTriggerSection, err)
+	}
+	if err = dispatcher.RegisterMetrics(t.metricsManager, map[string]string{"trigger": "custom-stdin"}); err != nil {
+		dispatcher.Stop()
+		return nil, fmt.Errorf("failed to register the dispatch metrics: %w", err)
+	}
+
 	msgs := make(chan []byte)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -73,19 +97,20 @@ func (t *stdinTrigger) Initialize(_ *sync.WaitGroup, ctx context.Context, _ <-ch
 			case <-ctx.Done():
 				receiveMessage = false
 			case m := <-msgs:
-				go func() {
-					spoofTopic := "even"
+				spoofTopic := "even"
 
-					if len(m)%2 == 1 {
-						spoofTopic = "odd"
-					}
+				if len(m)%2 == 1 {
+					spoofTopic = "odd"
+				}
 
-					env := types.MessageEnvelope{
-						CorrelationID: uuid.NewString(),
-						Payload:       m,
-						ReceivedTopic: spoofTopic,
-					}
+				env := types.MessageEnvelope{
+					CorrelationID: uuid.NewString(),
+					Payload:       m,
+					ReceivedTopic: spoofTopic,
+				}
 
+				// blocks while the dispatch queue is full with the block overflow policy
+				err := dispatcher.Submit(spoofTopic, func() {
 					t.tc.Logger.Tracef("sending message to runtime %+v", env)
 
 					err := t.tc.MessageReceived(nil, env, t.responseHandler)
@@ -93,13 +118,21 @@ func (t *stdinTrigger) Initialize(_ *sync.WaitGroup, ctx context.Context, _ <-ch
 					if err != nil {
 						t.tc.Logger.Error(err.Error())
 					}
-				}()
+				}, func(err error) {
+					t.tc.Logger.Warnf("message %s dropped: %s", env.CorrelationID, err.Error())
+				})
+
+				if err != nil {
+					t.tc.Logger.Warnf("message %s not processed: %s", env.CorrelationID, err.Error())
+				}
 			}
 		}
 	}()
 
 	return func() {
 		cancel()
+		dispatcher.Stop()
+		dispatcher.UnregisterMetrics(t.metricsManager)
 	}, nil
 }
 
@@ -121,10 +154,19 @@ func main() {
 
 	service.RegisterCustomTriggerFactory("custom-stdin", func(config interfaces.TriggerConfig) (interfaces.Trigger, error) {
 		return &stdinTrigger{
-			tc: config,
+			tc:             config,
+			metricsManager: service.MetricsManager(),
 		}, nil
 	})
 
+	service.RegisterCustomTriggerFactory("custom-webhook", webhook.NewFactory(service))
+	service.RegisterCustomTriggerFactory("custom-file", filewatch.NewTrigger)
+	service.RegisterCustomTriggerFactory("custom-replay", replay.NewTrigger)
+	service.RegisterCustomTriggerFactory("custom-grpc", grpctrigger.NewFactory(service))
+	service.RegisterCustomTriggerFactory("custom-websocket", wstrigger.NewFactory(service))
+	service.RegisterCustomTriggerFactory("custom-coap", coaptrigger.NewFactory(service))
+	service.RegisterCustomTriggerFactory("custom-udp", udptrigger.NewFactory(service))
+
 	var err error
 
 	//use this to process using default pipeline only
@@ -134,9 +176,21 @@ func main() {
 	//	os.Exit(-1)
 	//}
 
+	//record the messages of the odd/even pipelines to a capture file, which the custom-replay trigger replays
+	var recordFunctions []interfaces.AppFunction
+	var recorder *replay.Recorder
+	if recordFile := service.ApplicationSettings()["RecordFile"]; recordFile != "" {
+		recorder, err = replay.NewRecorder(recordFile)
+		if err != nil {
+			service.LoggingClient().Errorf("NewRecorder returned error: %s", err.Error())
+			os.Exit(-1)
+		}
+		recordFunctions = append(recordFunctions, recorder.Record)
+	}
+
 	//use this to process using varied pipelines by topic (odd/even string length)
 	err = service.AddFunctionsPipelineForTopics("odd", []string{"odd"},
-		printLowerToConsole,
+		append(recordFunctions, printLowerToConsole)...,
 	)
 
 	if err != nil {
@@ -145,6 +199,56 @@ func main() {
 	}
 
 	err = service.AddFunctionsPipelineForTopics("even", []string{"even"},
+		append(recordFunctions, printUpperToConsole)...,
+	)
+
+	if err != nil {
+		service.LoggingClient().Errorf("AddFunctionsPipelineForTopic returned error: %s", err.Error())
+		os.Exit(-1)
+	}
+
+	//webhooks received by the custom-webhook trigger
+	err = service.AddFunctionsPipelineForTopics("webhooks", []string{"webhook/#"},
+		printUpperToConsole,
+	)
+
+	if err != nil {
+		service.LoggingClient().Errorf("AddFunctionsPipelineForTopic returned error: %s", err.Error())
+		os.Exit(-1)
+	}
+
+	//files and lines read by the custom-file trigger
+	err = service.AddFunctionsPipelineForTopics("files", []string{"file/#"},
+		printUpperToConsole,
+	)
+
+	if err != nil {
+		service.LoggingClient().Errorf("AddFunctionsPipelineForTopic returned error: %s", err.Error())
+		os.Exit(-1)
+	}
+
+	//requests answered by the custom-grpc trigger
+	err = service.AddFunctionsPipelineForTopics("grpc", []string{"grpc/#"},
+		printUpperToConsole,
+	)
+
+	if err != nil {
+		service.LoggingClient().Errorf("AddFunctionsPipelineForTopic returned error: %s", err.Error())
+		os.Exit(-1)
+	}
+
+	//messages received by the custom-websocket trigger, such as the commands of dashboards
+	err = service.AddFunctionsPipelineForTopics("commands", []string{"commands/#"},
+		printUpperToConsole,
+	)
+
+	if err != nil {
+		service.LoggingClient().Errorf("AddFunctionsPipelineForTopic returned error: %s", err.Error())
+		os.Exit(-1)
+	}
+
+	//requests and datagrams of constrained devices received by the custom-coap and custom-udp triggers
+	err = service.AddFunctionsPipelineForTopics("devices", []string{"coap/#", "udp"},
 		printUpperToConsole,
 	)
 
@@ -155,6 +259,14 @@ func main() {
 
 	// Lastly, we'll go ahead and tell the SDK to "start" and begin listening for events
 	err = service.Run()
+
+	// the pipelines are stopped once Run returns, so the messages recorded are flushed to the capture file
+	if recorder != nil {
+		if closeErr := recorder.Close(); closeErr != nil {
+			service.LoggingClient().Errorf("failed to close the capture file: %s", closeErr.Error())
+		}
+	}
+
 	if err != nil {
 		service.LoggingClient().Error("Run returned error: ", err.Error())
 		os.Exit(-1)
@@ -0,0 +1,103 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/util"
+)
+
+// Record is a message recorded in a capture file, which holds one JSON record per line
+type Record struct {
+	// Timestamp is when the message was received
+	Timestamp   time.Time `json:"timestamp"`
+	Topic       string    `json:"topic"`
+	ContentType string    `json:"contentType,omitempty"`
+	// Payload is base64 encoded in the capture file
+	Payload []byte `json:"payload"`
+}
+
+// Recorder is a pipeline function appending the messages it receives to a capture file, to be replayed
+// by the replay trigger.
+type Recorder struct {
+	mutex  sync.Mutex
+	file   *os.File
+	writer *bufio.Writer
+}
+
+// NewRecorder opens the capture file, which is appended to when it exists.
+func NewRecorder(path string) (*Recorder, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create the directory of capture file %s: %w", path, err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open capture file %s: %w", path, err)
+	}
+	return &Recorder{file: file, writer: bufio.NewWriter(file)}, nil
+}
+
+// Record records the data received by the pipeline along with the topic it was received on, then passes it
+// on unchanged to the next function. It is meant to be the first function of the pipeline, so that the data
+// is recorded as it was received.
+func (r *Recorder) Record(ctx interfaces.AppFunctionContext, data interface{}) (bool, interface{}) {
+	payload, err := util.CoerceType(data)
+	if err != nil {
+		return false, fmt.Errorf("failed to record data: %w", err)
+	}
+	topic, _ := ctx.GetValue(interfaces.RECEIVEDTOPIC)
+	line, err := json.Marshal(Record{
+		Timestamp:   time.Now().UTC(),
+		Topic:       topic,
+		ContentType: ctx.InputContentType(),
+		Payload:     payload,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to record data: %w", err)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	// each record is flushed, so that the capture file is complete when the service is stopped
+	_, err = r.writer.Write(append(line, '\n'))
+	if err == nil {
+		err = r.writer.Flush()
+	}
+	if err != nil {
+		ctx.LoggingClient().Errorf("failed to write to capture file %s: %s", r.file.Name(), err.Error())
+	}
+	return true, data
+}
+
+// Close closes the capture file.
+func (r *Recorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := r.writer.Flush(); err != nil {
+		_ = r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
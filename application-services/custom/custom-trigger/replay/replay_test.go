@@ -0,0 +1,128 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package replay
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg"
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/clients/logger"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"custom-trigger/internal/triggertest"
+)
+
+func startTrigger(t *testing.T, cfg Config, p *triggertest.Pipeline) error {
+	trigger := &trigger{
+		tc: triggertest.TriggerConfig(p, func(config interfaces.UpdatableConfig) {
+			config.(*serviceConfig).ReplayTrigger = cfg
+		}),
+	}
+	_, err := triggertest.Start(t, trigger)
+	return err
+}
+
+func writeCapture(t *testing.T, records ...Record) string {
+	path := filepath.Join(t.TempDir(), "capture.jsonl")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+	encoder := json.NewEncoder(file)
+	for _, record := range records {
+		require.NoError(t, encoder.Encode(record))
+	}
+	return path
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "captures", "capture.jsonl")
+	recorder, err := NewRecorder(path)
+	require.NoError(t, err)
+
+	for _, topic := range []string{"odd", "even"} {
+		ctx := pkg.NewAppFuncContextForTest("", logger.NewMockClient())
+		ctx.AddValue(interfaces.RECEIVEDTOPIC, topic)
+		next, data := recorder.Record(ctx, []byte("payload "+topic))
+		require.True(t, next)
+		assert.Equal(t, []byte("payload "+topic), data)
+	}
+	require.NoError(t, recorder.Close())
+
+	p := &triggertest.Pipeline{}
+	require.NoError(t, startTrigger(t, Config{File: path, Mode: ModeFast}, p))
+	require.Eventually(t, func() bool { return p.Count() == 2 }, triggertest.Timeout, 10*time.Millisecond)
+
+	received := p.Envelopes()
+	assert.Equal(t, "odd", received[0].ReceivedTopic)
+	assert.Equal(t, "payload odd", string(received[0].Payload))
+	assert.Equal(t, common.ContentTypeJSON, received[0].ContentType)
+	assert.Equal(t, "even", received[1].ReceivedTopic)
+	assert.NotEqual(t, received[0].CorrelationID, received[1].CorrelationID)
+}
+
+func TestReplayAccelerated(t *testing.T) {
+	recorded := time.Now()
+	path := writeCapture(t,
+		Record{Timestamp: recorded, Topic: "a", ContentType: common.ContentTypeText, Payload: []byte("1")},
+		Record{Timestamp: recorded.Add(2 * time.Second), Topic: "a", Payload: []byte("2")},
+	)
+
+	p := &triggertest.Pipeline{}
+	require.NoError(t, startTrigger(t, Config{File: path, Mode: ModeAccelerated, Speed: 10}, p))
+	require.Eventually(t, func() bool { return p.Count() == 2 }, triggertest.Timeout, 10*time.Millisecond)
+
+	received := p.Received()
+	assert.Equal(t, common.ContentTypeText, received[0].Envelope.ContentType)
+	interval := received[1].At.Sub(received[0].At)
+	assert.GreaterOrEqual(t, interval, 150*time.Millisecond)
+	assert.Less(t, interval, time.Second)
+}
+
+func TestReplayLoop(t *testing.T) {
+	path := writeCapture(t,
+		Record{Timestamp: time.Now(), Topic: "a", Payload: []byte("1")},
+		Record{Timestamp: time.Now(), Topic: "b", Payload: []byte("2")},
+	)
+	// invalid records are skipped
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = file.WriteString("not json\n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	p := &triggertest.Pipeline{}
+	require.NoError(t, startTrigger(t, Config{File: path, Mode: ModeFast, Loop: true}, p))
+	require.Eventually(t, func() bool { return p.Count() >= 6 }, triggertest.Timeout, 10*time.Millisecond)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	path := writeCapture(t)
+	for _, cfg := range []Config{
+		{File: path, Mode: "slow"},
+		{File: path, Mode: ModeAccelerated},
+		{File: filepath.Join(t.TempDir(), "missing.jsonl")},
+	} {
+		assert.Error(t, startTrigger(t, cfg, &triggertest.Pipeline{}), "%+v", cfg)
+	}
+}
@@ -0,0 +1,197 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package replay provides a custom trigger replaying the messages of a capture file through the pipelines, and
+// the Recorder pipeline function writing such capture files from live traffic.
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+	"github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+	"github.com/google/uuid"
+
+	"custom-trigger/internal/triggerconfig"
+)
+
+const sectionName = "ReplayTrigger"
+
+// Replay modes
+const (
+	// ModeOriginal replays the messages with the intervals they were recorded with
+	ModeOriginal = "original"
+	// ModeAccelerated divides the recorded intervals by the Speed
+	ModeAccelerated = "accelerated"
+	// ModeFast replays the messages as fast as the pipelines process them
+	ModeFast = "fast"
+)
+
+// Config holds the settings of the replay trigger, loaded from the ReplayTrigger section
+type Config struct {
+	// File is the capture file, written by the Recorder
+	File string
+	// Mode is original (default), accelerated or fast
+	Mode string
+	// Speed divides the recorded intervals in accelerated mode, such as 10 to replay ten times faster
+	Speed float64
+	// Loop replays the capture file again once its end is reached, until the service stops
+	Loop bool
+}
+
+// serviceConfig wraps the ReplayTrigger section so that it can be loaded by the ConfigLoader of the trigger
+type serviceConfig struct {
+	ReplayTrigger Config
+}
+
+// UpdateFromRaw updates the configuration from raw data received from the Configuration Provider.
+func (c *serviceConfig) UpdateFromRaw(rawConfig interface{}) bool {
+	return triggerconfig.UpdateFromRaw(c, rawConfig)
+}
+
+type trigger struct {
+	tc  interfaces.TriggerConfig
+	cfg Config
+	// speed divides the recorded intervals, 0 replays as fast as possible
+	speed float64
+}
+
+// NewTrigger is the factory of the replay trigger, to be registered with RegisterCustomTriggerFactory.
+func NewTrigger(tc interfaces.TriggerConfig) (interfaces.Trigger, error) {
+	return &trigger{tc: tc}, nil
+}
+
+func (t *trigger) Initialize(wg *sync.WaitGroup, ctx context.Context, _ <-chan interfaces.BackgroundMessage) (bootstrap.Deferred, error) {
+	sc := &serviceConfig{}
+	if err := t.tc.ConfigLoader(sc, sectionName); err != nil {
+		return nil, fmt.Errorf("failed to load the %s configuration: %w", sectionName, err)
+	}
+	t.cfg = sc.ReplayTrigger
+
+	switch t.cfg.Mode {
+	case ModeOriginal, "":
+		t.speed = 1
+	case ModeAccelerated:
+		if t.cfg.Speed <= 0 {
+			return nil, fmt.Errorf("invalid %s configuration: Speed must be positive in %s mode", sectionName, ModeAccelerated)
+		}
+		t.speed = t.cfg.Speed
+	case ModeFast:
+		t.speed = 0
+	default:
+		return nil, fmt.Errorf("invalid %s configuration: invalid Mode '%s', must be %s, %s or %s",
+			sectionName, t.cfg.Mode, ModeOriginal, ModeAccelerated, ModeFast)
+	}
+	// fail now rather than in the background when the file cannot be read
+	if _, err := os.Stat(t.cfg.File); err != nil {
+		return nil, fmt.Errorf("invalid %s configuration: %w", sectionName, err)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			count, err := t.replay(ctx)
+			if err != nil {
+				t.tc.Logger.Errorf("failed to replay %s: %s", t.cfg.File, err.Error())
+				return
+			}
+			t.tc.Logger.Infof("replayed %d messages from %s", count, t.cfg.File)
+			if !t.cfg.Loop || count == 0 || ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+
+	return func() {}, nil
+}
+
+// replay sends the records of the capture file through the pipelines, waiting between them according to
+// their timestamps, and returns the number of records sent.
+func (t *trigger) replay(ctx context.Context) (int, error) {
+	file, err := os.Open(t.cfg.File)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var first time.Time
+	start := time.Now()
+	count := 0
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if len(line) == 0 && errors.Is(err, io.EOF) {
+			return count, nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return count, err
+		}
+
+		var record Record
+		if err = json.Unmarshal(line, &record); err != nil {
+			t.tc.Logger.Warnf("skipping invalid record at line %d of %s: %s", lineNumber, t.cfg.File, err.Error())
+			continue
+		}
+
+		if first.IsZero() {
+			first = record.Timestamp
+		}
+		if t.speed > 0 {
+			due := start.Add(time.Duration(float64(record.Timestamp.Sub(first)) / t.speed))
+			select {
+			case <-ctx.Done():
+				return count, nil
+			case <-time.After(time.Until(due)):
+			}
+		} else if ctx.Err() != nil {
+			return count, nil
+		}
+
+		t.send(record)
+		count++
+	}
+}
+
+func (t *trigger) send(record Record) {
+	env := types.MessageEnvelope{
+		CorrelationID: uuid.NewString(),
+		ContentType:   record.ContentType,
+		Payload:       record.Payload,
+		ReceivedTopic: record.Topic,
+	}
+	if env.ContentType == "" {
+		env.ContentType = common.ContentTypeJSON
+	}
+
+	t.tc.Logger.Tracef("sending message to runtime %+v", env)
+
+	ctx := t.tc.ContextBuilder(env)
+	ctx.AddValue(interfaces.RECEIVEDTOPIC, env.ReceivedTopic)
+	if err := t.tc.MessageReceived(ctx, env, nil); err != nil {
+		t.tc.Logger.Errorf("failed to process replayed message on %s: %s", env.ReceivedTopic, err.Error())
+	}
+}
@@ -0,0 +1,254 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package udptrigger provides a custom trigger running the pipelines for the raw UDP datagrams received, such as
+// the ones of constrained devices.
+package udptrigger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+	"github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap"
+	bootstrapInterfaces "github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap/interfaces"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+	"github.com/google/uuid"
+
+	"custom-trigger/dispatch"
+	"custom-trigger/internal/triggerconfig"
+)
+
+const (
+	sectionName = "UdpTrigger"
+
+	defaultListenAddress   = ":59784"
+	defaultTopic           = "udp"
+	defaultMaxDatagramSize = 65507
+)
+
+// SourceParam is the query parameter of the envelopes holding the source address of the datagram, as ip:port
+const SourceParam = "source"
+
+// Config holds the settings of the UDP trigger, loaded from the UdpTrigger section
+type Config struct {
+	// ListenAddress is the UDP address the datagrams are received on, defaults to :59784
+	ListenAddress string
+	// Topic is the received topic of the messages, defaults to udp
+	Topic string
+	// ContentType of the messages, defaults to application/json
+	ContentType string
+	// MaxDatagramSize is the maximum size in bytes of a datagram, larger ones are dropped. Defaults to 65507.
+	MaxDatagramSize int
+	// Reply sends the response data of the pipeline back to the source address of the datagram. As the source
+	// address can be spoofed, responses larger than the datagram are only sent to the ReplyPeers.
+	Reply bool
+	// ReplyPeers are the IP addresses or CIDR networks replied to, when set the other sources are not replied to
+	ReplyPeers []string
+	// Dispatch bounds the number of datagrams processed concurrently
+	Dispatch dispatch.Config
+}
+
+// serviceConfig wraps the UdpTrigger section so that it can be loaded by the ConfigLoader of the trigger
+type serviceConfig struct {
+	UdpTrigger Config
+}
+
+// UpdateFromRaw updates the configuration from raw data received from the Configuration Provider.
+func (c *serviceConfig) UpdateFromRaw(rawConfig interface{}) bool {
+	return triggerconfig.UpdateFromRaw(c, rawConfig)
+}
+
+type trigger struct {
+	tc             interfaces.TriggerConfig
+	metricsManager bootstrapInterfaces.MetricsManager
+
+	cfg        Config
+	replyPeers []*net.IPNet
+	conn       *net.UDPConn
+	dispatcher *dispatch.Dispatcher
+}
+
+// NewFactory returns the factory of the UDP trigger, to be registered with RegisterCustomTriggerFactory.
+func NewFactory(service interfaces.ApplicationService) func(interfaces.TriggerConfig) (interfaces.Trigger, error) {
+	return func(tc interfaces.TriggerConfig) (interfaces.Trigger, error) {
+		return &trigger{
+			tc:             tc,
+			metricsManager: service.MetricsManager(),
+		}, nil
+	}
+}
+
+func (t *trigger) Initialize(wg *sync.WaitGroup, ctx context.Context, _ <-chan interfaces.BackgroundMessage) (bootstrap.Deferred, error) {
+	sc := &serviceConfig{}
+	if err := t.tc.ConfigLoader(sc, sectionName); err != nil {
+		return nil, fmt.Errorf("failed to load the %s configuration: %w", sectionName, err)
+	}
+	t.cfg = sc.UdpTrigger
+	if t.cfg.ListenAddress == "" {
+		t.cfg.ListenAddress = defaultListenAddress
+	}
+	if t.cfg.Topic == "" {
+		t.cfg.Topic = defaultTopic
+	}
+	if t.cfg.ContentType == "" {
+		t.cfg.ContentType = common.ContentTypeJSON
+	}
+	if t.cfg.MaxDatagramSize <= 0 {
+		t.cfg.MaxDatagramSize = defaultMaxDatagramSize
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", t.cfg.ListenAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s configuration: %w", sectionName, err)
+	}
+	if t.replyPeers, err = parsePeers(t.cfg.ReplyPeers); err != nil {
+		return nil, fmt.Errorf("invalid %s ReplyPeers: %w", sectionName, err)
+	}
+	if t.dispatcher, err = dispatch.New(t.cfg.Dispatch); err != nil {
+		return nil, fmt.Errorf("invalid %s Dispatch configuration: %w", sectionName, err)
+	}
+	if err = t.dispatcher.RegisterMetrics(t.metricsManager, map[string]string{"trigger": "custom-udp"}); err != nil {
+		t.dispatcher.Stop()
+		return nil, fmt.Errorf("failed to register the dispatch metrics: %w", err)
+	}
+	if t.conn, err = net.ListenUDP("udp", addr); err != nil {
+		t.dispatcher.Stop()
+		t.dispatcher.UnregisterMetrics(t.metricsManager)
+		return nil, fmt.Errorf("failed to listen on %s: %w", t.cfg.ListenAddress, err)
+	}
+
+	received := make(chan struct{})
+	go func() {
+		defer close(received)
+		t.tc.Logger.Infof("UDP trigger listening on %s", t.conn.LocalAddr().String())
+		t.receive()
+	}()
+
+	// the datagrams received are processed, and replied to, before the socket is closed
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		_ = t.conn.SetReadDeadline(time.Now())
+		<-received
+		t.dispatcher.Stop()
+		_ = t.conn.Close()
+	}()
+
+	return func() {
+		t.dispatcher.UnregisterMetrics(t.metricsManager)
+	}, nil
+}
+
+// receive reads the datagrams until the socket fails to be read, such as when its read deadline is set.
+func (t *trigger) receive() {
+	// the extra byte reveals the datagrams larger than the maximum size, which are truncated
+	buf := make([]byte, t.cfg.MaxDatagramSize+1)
+	for {
+		n, source, err := t.conn.ReadFromUDP(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrDeadlineExceeded) {
+				t.tc.Logger.Errorf("failed to read UDP datagram: %s", err.Error())
+			}
+			return
+		}
+		if n > t.cfg.MaxDatagramSize {
+			t.tc.Logger.Warnf("UDP datagram from %s dropped: larger than %d bytes", source.String(), t.cfg.MaxDatagramSize)
+			continue
+		}
+
+		payload := make([]byte, n)
+		copy(payload, buf[:n])
+		t.submit(types.MessageEnvelope{
+			CorrelationID: uuid.NewString(),
+			ContentType:   t.cfg.ContentType,
+			Payload:       payload,
+			ReceivedTopic: t.cfg.Topic,
+			QueryParams:   map[string]string{SourceParam: source.String()},
+		}, source)
+	}
+}
+
+// submit runs the pipelines through the dispatcher, replying with the response data of the first one when Reply
+// is set.
+func (t *trigger) submit(env types.MessageEnvelope, source *net.UDPAddr) {
+	t.dispatcher.Process(t.tc, env, func(res dispatch.Result) {
+		switch {
+		case res.NotProcessed:
+			t.tc.Logger.Warnf("UDP datagram from %s (%s) not processed: %s", source.String(), env.CorrelationID, res.Err.Error())
+		case res.Err != nil:
+			t.tc.Logger.Errorf("failed to process UDP datagram from %s (%s): %s", source.String(), env.CorrelationID, res.Err.Error())
+		case t.cfg.Reply && len(res.Data) > 0:
+			t.reply(env, res.Data, source)
+		}
+	})
+}
+
+// reply sends the response data back to the source of the datagram. When ReplyPeers is set, only the peers are
+// replied to, otherwise the responses larger than the datagram are not sent, so that a spoofed source address
+// does not turn the trigger into an amplifier.
+func (t *trigger) reply(env types.MessageEnvelope, data []byte, source *net.UDPAddr) {
+	if len(t.replyPeers) > 0 {
+		if !containsIP(t.replyPeers, source.IP) {
+			t.tc.Logger.Debugf("not replying to %s (%s): not one of the ReplyPeers", source.String(), env.CorrelationID)
+			return
+		}
+	} else if len(data) > len(env.Payload) {
+		t.tc.Logger.Warnf("not replying to %s (%s): the response of %d bytes is larger than the datagram, "+
+			"set ReplyPeers to reply to trusted peers", source.String(), env.CorrelationID, len(data))
+		return
+	}
+	if _, err := t.conn.WriteToUDP(data, source); err != nil {
+		t.tc.Logger.Errorf("failed to reply to %s (%s): %s", source.String(), env.CorrelationID, err.Error())
+	}
+}
+
+// parsePeers parses the IP addresses and CIDR networks of the peers.
+func parsePeers(peers []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(peers))
+	for _, peer := range peers {
+		if ip := net.ParseIP(peer); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(peer)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is neither an IP address nor a CIDR network", peer)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
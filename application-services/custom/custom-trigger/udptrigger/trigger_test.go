@@ -0,0 +1,137 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package udptrigger
+
+import (
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"custom-trigger/internal/triggertest"
+)
+
+// startTrigger starts the trigger and returns a socket connected to it.
+func startTrigger(t *testing.T, cfg Config) (*net.UDPConn, *triggertest.Pipeline) {
+	return startTriggerWith(t, cfg, &triggertest.Pipeline{})
+}
+
+func startTriggerWith(t *testing.T, cfg Config, p *triggertest.Pipeline) (*net.UDPConn, *triggertest.Pipeline) {
+	cfg.ListenAddress = "127.0.0.1:0"
+	trigger := &trigger{
+		tc: triggertest.TriggerConfig(p, func(config interfaces.UpdatableConfig) {
+			config.(*serviceConfig).UdpTrigger = cfg
+		}),
+		metricsManager: triggertest.MetricsManager(),
+	}
+	_, err := triggertest.Start(t, trigger)
+	require.NoError(t, err)
+
+	conn, err := net.DialUDP("udp", nil, trigger.conn.LocalAddr().(*net.UDPAddr))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn, p
+}
+
+func TestReply(t *testing.T) {
+	conn, p := startTrigger(t, Config{Topic: "sensors/udp", Reply: true})
+
+	_, err := conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(triggertest.Timeout)))
+	reply := make([]byte, 16)
+	n, err := conn.Read(reply)
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO", string(reply[:n]))
+
+	env := p.Last()
+	assert.Equal(t, "sensors/udp", env.ReceivedTopic)
+	assert.Equal(t, common.ContentTypeJSON, env.ContentType)
+	assert.Equal(t, conn.LocalAddr().String(), env.QueryParams[SourceParam])
+}
+
+func TestNoReply(t *testing.T) {
+	conn, p := startTrigger(t, Config{ContentType: common.ContentTypeText})
+
+	_, err := conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(p.Payloads()) == 1 }, triggertest.Timeout, 10*time.Millisecond)
+	assert.Equal(t, defaultTopic, p.Last().ReceivedTopic)
+	assert.Equal(t, common.ContentTypeText, p.Last().ContentType)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+	_, err = conn.Read(make([]byte, 16))
+	assert.True(t, errors.Is(err, os.ErrDeadlineExceeded), "no reply is sent")
+}
+
+// readReply returns the reply to the datagram, or an empty string when there is none.
+func readReply(t *testing.T, conn *net.UDPConn, datagram string, timeout time.Duration) string {
+	_, err := conn.Write([]byte(datagram))
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(timeout)))
+	reply := make([]byte, 64)
+	n, err := conn.Read(reply)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return ""
+	}
+	require.NoError(t, err)
+	return string(reply[:n])
+}
+
+func TestReplyAmplification(t *testing.T) {
+	double := func(payload []byte) []byte { return append(payload, payload...) }
+
+	conn, _ := startTriggerWith(t, Config{Reply: true}, &triggertest.Pipeline{Respond: double})
+	assert.Empty(t, readReply(t, conn, "hello", 100*time.Millisecond),
+		"responses larger than the datagram are not sent to unknown sources")
+
+	conn, _ = startTriggerWith(t, Config{Reply: true, ReplyPeers: []string{"127.0.0.0/8"}},
+		&triggertest.Pipeline{Respond: double})
+	assert.Equal(t, "hellohello", readReply(t, conn, "hello", triggertest.Timeout))
+
+	conn, _ = startTrigger(t, Config{Reply: true, ReplyPeers: []string{"10.0.0.1", "::1"}})
+	assert.Empty(t, readReply(t, conn, "hello", 100*time.Millisecond), "only the ReplyPeers are replied to")
+}
+
+func TestParsePeers(t *testing.T) {
+	peers, err := parsePeers([]string{"192.168.1.10", "10.0.0.0/8", "fd00::1"})
+	require.NoError(t, err)
+	assert.True(t, containsIP(peers, net.ParseIP("192.168.1.10")))
+	assert.False(t, containsIP(peers, net.ParseIP("192.168.1.11")))
+	assert.True(t, containsIP(peers, net.ParseIP("10.1.2.3")))
+	assert.True(t, containsIP(peers, net.ParseIP("fd00::1")))
+
+	_, err = parsePeers([]string{"sensor.local"})
+	assert.Error(t, err)
+}
+
+func TestMaxDatagramSize(t *testing.T) {
+	conn, p := startTrigger(t, Config{MaxDatagramSize: 4})
+
+	for _, datagram := range []string{"too large", "fits"} {
+		_, err := conn.Write([]byte(datagram))
+		require.NoError(t, err)
+	}
+	require.Eventually(t, func() bool { return len(p.Payloads()) == 1 }, triggertest.Timeout, 10*time.Millisecond)
+	assert.Equal(t, []string{"fits"}, p.Payloads())
+}
@@ -0,0 +1,164 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"custom-trigger/dispatch"
+	"custom-trigger/internal/triggerconfig"
+)
+
+const (
+	sectionName = "WebhookTrigger"
+
+	defaultListenAddress = ":59781"
+	defaultMaxBodySize   = 1 << 20
+	defaultReplayWindow  = 5 * time.Minute
+	defaultSecretKey     = "secret"
+)
+
+// Signature schemes of the webhooks
+const (
+	// SchemeNone accepts the requests without verification
+	SchemeNone = "none"
+	// SchemeSharedSecret compares the Header with the secret, as GitLab does with X-Gitlab-Token
+	SchemeSharedSecret = "shared-secret"
+	// SchemeHMAC verifies the Header holding the hex encoded HMAC of the body, as GitHub does with X-Hub-Signature-256
+	SchemeHMAC = "hmac"
+	// SchemeStripe verifies the timestamped HMAC of the Stripe-Signature header
+	SchemeStripe = "stripe"
+)
+
+// Config holds the settings of the webhook trigger, loaded from the WebhookTrigger section
+type Config struct {
+	// ListenAddress is the address of the HTTP server receiving the webhooks, defaults to :59781
+	ListenAddress string
+	// MaxBodySize is the maximum size in bytes of a webhook body, defaults to 1MB
+	MaxBodySize int64
+	// Hooks are the webhooks received, by name
+	Hooks map[string]HookConfig
+	// Dispatch bounds the number of webhooks processed concurrently
+	Dispatch dispatch.Config
+}
+
+// HookConfig holds the settings of a webhook
+type HookConfig struct {
+	// Path is the url path the webhook is posted to
+	Path string
+	// Topic is the received topic of the messages, which defaults to webhook/<name>
+	Topic string
+	// EventHeader is a header whose value is appended to the Topic, such as X-GitHub-Event
+	EventHeader string
+	// Scheme is the verification of the requests: none, shared-secret, hmac or stripe
+	Scheme string
+	// Header holds the shared secret or the signature, defaults to Stripe-Signature with the stripe scheme
+	Header string
+	// Prefix precedes the signature in the Header, such as sha256=
+	Prefix string
+	// Algorithm is the hash of the HMAC: sha1, sha256 (default) or sha512
+	Algorithm string
+	// SecretName is the secret holding the key, which is read for each request so that it can be rotated
+	SecretName string
+	// SecretKey is the key of the secret holding the key, defaults to secret
+	SecretKey string
+	// ReplayWindow is the maximum age of a Stripe timestamp, and how long the ids of the DeliveryHeader are remembered
+	ReplayWindow string
+	// DeliveryHeader holds the unique id of each delivery, such as X-GitHub-Delivery, replays of which are rejected
+	DeliveryHeader string
+}
+
+// serviceConfig wraps the WebhookTrigger section so that it can be loaded by the ConfigLoader of the trigger
+type serviceConfig struct {
+	WebhookTrigger Config
+}
+
+// UpdateFromRaw updates the configuration from raw data received from the Configuration Provider.
+func (c *serviceConfig) UpdateFromRaw(rawConfig interface{}) bool {
+	return triggerconfig.UpdateFromRaw(c, rawConfig)
+}
+
+// hook is a parsed HookConfig
+type hook struct {
+	HookConfig
+	name         string
+	replayWindow time.Duration
+	deliveries   deliveries
+}
+
+// parseHooks applies the defaults and validates the configuration of the hooks, which are returned by path.
+func parseHooks(cfg Config) (map[string]*hook, error) {
+	if len(cfg.Hooks) == 0 {
+		return nil, errors.New("no Hooks configured")
+	}
+
+	hooks := make(map[string]*hook, len(cfg.Hooks))
+	for name, hc := range cfg.Hooks {
+		h := &hook{HookConfig: hc, name: name, deliveries: deliveries{seen: map[string]time.Time{}}}
+		if !strings.HasPrefix(h.Path, "/") {
+			return nil, fmt.Errorf("hook %s: Path '%s' must start with /", name, h.Path)
+		}
+		if _, ok := hooks[h.Path]; ok {
+			return nil, fmt.Errorf("hook %s: Path %s is used by several hooks", name, h.Path)
+		}
+		if h.Topic == "" {
+			h.Topic = "webhook/" + name
+		}
+		if h.SecretKey == "" {
+			h.SecretKey = defaultSecretKey
+		}
+		if h.Algorithm == "" {
+			h.Algorithm = "sha256"
+		}
+		if _, ok := hashes[h.Algorithm]; !ok {
+			return nil, fmt.Errorf("hook %s: invalid Algorithm '%s'", name, h.Algorithm)
+		}
+
+		switch h.Scheme {
+		case SchemeNone:
+		case SchemeSharedSecret, SchemeHMAC:
+			if h.Header == "" {
+				return nil, fmt.Errorf("hook %s: Header must be set with the %s scheme", name, h.Scheme)
+			}
+		case SchemeStripe:
+			if h.Header == "" {
+				h.Header = stripeSignatureHeader
+			}
+		default:
+			return nil, fmt.Errorf("hook %s: invalid Scheme '%s', must be %s, %s, %s or %s",
+				name, h.Scheme, SchemeNone, SchemeSharedSecret, SchemeHMAC, SchemeStripe)
+		}
+		if h.Scheme != SchemeNone && h.SecretName == "" {
+			return nil, fmt.Errorf("hook %s: SecretName must be set with the %s scheme", name, h.Scheme)
+		}
+
+		h.replayWindow = defaultReplayWindow
+		if h.ReplayWindow != "" {
+			window, err := time.ParseDuration(h.ReplayWindow)
+			if err != nil || window <= 0 {
+				return nil, fmt.Errorf("hook %s: invalid ReplayWindow '%s'", name, h.ReplayWindow)
+			}
+			h.replayWindow = window
+		}
+
+		hooks[h.Path] = h
+	}
+	return hooks, nil
+}
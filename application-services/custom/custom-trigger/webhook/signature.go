@@ -0,0 +1,142 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" // nolint:gosec // sha1 HMACs are still sent by some webhooks
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const stripeSignatureHeader = "Stripe-Signature"
+
+var hashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// verify checks the signature of the request with the key of the hook.
+func (h *hook) verify(header http.Header, body []byte, key []byte, now time.Time) error {
+	value := header.Get(h.Header)
+	if value == "" {
+		return fmt.Errorf("missing %s header", h.Header)
+	}
+
+	switch h.Scheme {
+	case SchemeSharedSecret:
+		if subtle.ConstantTimeCompare([]byte(value), key) != 1 {
+			return fmt.Errorf("invalid %s header", h.Header)
+		}
+
+	case SchemeHMAC:
+		signature, ok := strings.CutPrefix(value, h.Prefix)
+		if !ok || !h.validMAC(key, body, signature) {
+			return fmt.Errorf("invalid %s header", h.Header)
+		}
+
+	case SchemeStripe:
+		return h.verifyStripe(value, body, key, now)
+	}
+	return nil
+}
+
+// verifyStripe checks the t=<timestamp>,v1=<signature> header, which may hold several signatures while the
+// key is rolled. The signed payload is the timestamp and the body separated by a dot.
+func (h *hook) verifyStripe(value string, body []byte, key []byte, now time.Time) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(value, ",") {
+		name, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch name {
+		case "t":
+			timestamp = v
+		case "v1":
+			signatures = append(signatures, v)
+		}
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return fmt.Errorf("malformed %s header", h.Header)
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > h.replayWindow || age < -h.replayWindow {
+		return fmt.Errorf("timestamp of %s header is outside of the replay window", h.Header)
+	}
+
+	payload := append([]byte(timestamp+"."), body...)
+	for _, signature := range signatures {
+		if h.validMAC(key, payload, signature) {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid %s header", h.Header)
+}
+
+// validMAC reports whether signature is the hex encoded HMAC of the payload.
+func (h *hook) validMAC(key []byte, payload []byte, signature string) bool {
+	actual, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(hashes[h.Algorithm], key)
+	mac.Write(payload)
+	return hmac.Equal(actual, mac.Sum(nil))
+}
+
+var errReplayed = errors.New("delivery already received")
+
+// deliveries remembers the delivery ids received within the replay window of the hooks.
+type deliveries struct {
+	mutex sync.Mutex
+	seen  map[string]time.Time
+}
+
+// check records the delivery, rejecting the ids recorded within the window. A delivery being processed is
+// recorded as well, so that a concurrent retry is rejected.
+func (d *deliveries) check(id string, now time.Time, window time.Duration) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for seenID, at := range d.seen {
+		if now.Sub(at) > window {
+			delete(d.seen, seenID)
+		}
+	}
+	if _, ok := d.seen[id]; ok {
+		return errReplayed
+	}
+	d.seen[id] = now
+	return nil
+}
+
+// release forgets a delivery which failed, so that it can be retried.
+func (d *deliveries) release(id string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	delete(d.seen, id)
+}
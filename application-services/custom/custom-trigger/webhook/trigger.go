@@ -0,0 +1,273 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package webhook provides a custom trigger receiving webhooks over HTTP, such as the ones sent by SaaS
+// platforms. The requests are verified with keys from the secret store and answered with the pipeline response.
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+	"github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap"
+	bootstrapInterfaces "github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap/interfaces"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+	"github.com/google/uuid"
+
+	"custom-trigger/dispatch"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+type trigger struct {
+	tc             interfaces.TriggerConfig
+	secretProvider bootstrapInterfaces.SecretProvider
+	metricsManager bootstrapInterfaces.MetricsManager
+
+	maxBodySize int64
+	hooks       map[string]*hook
+	dispatcher  *dispatch.Dispatcher
+	// addr is the address the server listens on, once initialized
+	addr net.Addr
+}
+
+// NewFactory returns the factory of the webhook trigger, to be registered with RegisterCustomTriggerFactory.
+func NewFactory(service interfaces.ApplicationService) func(interfaces.TriggerConfig) (interfaces.Trigger, error) {
+	return func(tc interfaces.TriggerConfig) (interfaces.Trigger, error) {
+		return &trigger{
+			tc:             tc,
+			secretProvider: service.SecretProvider(),
+			metricsManager: service.MetricsManager(),
+		}, nil
+	}
+}
+
+func (t *trigger) Initialize(wg *sync.WaitGroup, ctx context.Context, _ <-chan interfaces.BackgroundMessage) (bootstrap.Deferred, error) {
+	sc := &serviceConfig{}
+	if err := t.tc.ConfigLoader(sc, sectionName); err != nil {
+		return nil, fmt.Errorf("failed to load the %s configuration: %w", sectionName, err)
+	}
+	cfg := sc.WebhookTrigger
+
+	var err error
+	if t.hooks, err = parseHooks(cfg); err != nil {
+		return nil, fmt.Errorf("invalid %s configuration: %w", sectionName, err)
+	}
+	// an empty key would let anyone sign the requests, so the keys are checked before the hooks are served
+	for _, h := range t.hooks {
+		if h.Scheme == SchemeNone {
+			continue
+		}
+		if _, err = t.key(h); err != nil {
+			return nil, fmt.Errorf("invalid %s configuration: hook %s: %w", sectionName, h.name, err)
+		}
+	}
+	t.maxBodySize = cfg.MaxBodySize
+	if t.maxBodySize <= 0 {
+		t.maxBodySize = defaultMaxBodySize
+	}
+	addr := cfg.ListenAddress
+	if addr == "" {
+		addr = defaultListenAddress
+	}
+
+	if t.dispatcher, err = dispatch.New(cfg.Dispatch); err != nil {
+		return nil, fmt.Errorf("invalid %s Dispatch configuration: %w", sectionName, err)
+	}
+	if err = t.dispatcher.RegisterMetrics(t.metricsManager, map[string]string{"trigger": "custom-webhook"}); err != nil {
+		t.dispatcher.Stop()
+		return nil, fmt.Errorf("failed to register the dispatch metrics: %w", err)
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.dispatcher.Stop()
+		t.dispatcher.UnregisterMetrics(t.metricsManager)
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	t.addr = listener.Addr()
+	server := &http.Server{
+		Handler:           t,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		t.tc.Logger.Infof("webhook trigger listening on %s", t.addr.String())
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			t.tc.Logger.Errorf("webhook server failed: %s", err.Error())
+		}
+	}()
+
+	// the requests being processed are completed before the service exits
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			t.tc.Logger.Warnf("webhook server shutdown: %s", err.Error())
+		}
+	}()
+
+	return func() {
+		t.dispatcher.Stop()
+		t.dispatcher.UnregisterMetrics(t.metricsManager)
+	}, nil
+}
+
+// ServeHTTP verifies the webhook, runs the pipelines and responds with the response data of the first one.
+func (t *trigger) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h, ok := t.hooks[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, t.maxBodySize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	if status, err := t.authorize(h, r.Header, body); err != nil {
+		t.tc.Logger.Warnf("webhook %s rejected: %s", h.name, err.Error())
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	env := t.envelope(h, r, body)
+	w.Header().Set(common.CorrelationHeader, env.CorrelationID)
+
+	data, contentType, status, err := t.process(env)
+	if err != nil {
+		// the delivery failed, so its retry is processed rather than rejected as a replay
+		if h.DeliveryHeader != "" {
+			h.deliveries.release(r.Header.Get(h.DeliveryHeader))
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if contentType != "" {
+		w.Header().Set(common.ContentType, contentType)
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
+
+// authorize verifies the signature of the request and rejects the replayed deliveries, returning the status
+// of the response when the request is rejected.
+func (t *trigger) authorize(h *hook, header http.Header, body []byte) (int, error) {
+	now := time.Now()
+	if h.Scheme != SchemeNone {
+		key, err := t.key(h)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		if err = h.verify(header, body, key, now); err != nil {
+			return http.StatusUnauthorized, err
+		}
+	}
+
+	if h.DeliveryHeader != "" {
+		id := header.Get(h.DeliveryHeader)
+		if id == "" {
+			return http.StatusBadRequest, fmt.Errorf("missing %s header", h.DeliveryHeader)
+		}
+		if err := h.deliveries.check(id, now, h.replayWindow); err != nil {
+			return http.StatusConflict, fmt.Errorf("%w: %s", err, id)
+		}
+	}
+	return http.StatusOK, nil
+}
+
+// key reads the key of the hook from its secret, which may be rotated, failing when it is empty.
+func (t *trigger) key(h *hook) ([]byte, error) {
+	secret, err := t.secretProvider.GetSecret(h.SecretName, h.SecretKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the %s secret: %w", h.SecretName, err)
+	}
+	key := secret[h.SecretKey]
+	if key == "" {
+		return nil, fmt.Errorf("the %s key of the %s secret is empty", h.SecretKey, h.SecretName)
+	}
+	return []byte(key), nil
+}
+
+// envelope builds the envelope of the request, with the topic of the hook followed by the EventHeader value.
+func (t *trigger) envelope(h *hook, r *http.Request, body []byte) types.MessageEnvelope {
+	topic := h.Topic
+	if h.EventHeader != "" {
+		if event := r.Header.Get(h.EventHeader); event != "" {
+			topic += "/" + event
+		}
+	}
+
+	env := types.MessageEnvelope{
+		CorrelationID: r.Header.Get(common.CorrelationHeader),
+		ContentType:   r.Header.Get(common.ContentType),
+		Payload:       body,
+		ReceivedTopic: topic,
+	}
+	if env.CorrelationID == "" {
+		env.CorrelationID = uuid.NewString()
+	}
+	if env.ContentType == "" {
+		env.ContentType = common.ContentTypeJSON
+	}
+	if query := r.URL.Query(); len(query) > 0 {
+		env.QueryParams = make(map[string]string, len(query))
+		for name := range query {
+			env.QueryParams[name] = query.Get(name)
+		}
+	}
+	return env
+}
+
+// process runs the pipelines through the dispatcher and waits for the response data of the first one.
+func (t *trigger) process(env types.MessageEnvelope) ([]byte, string, int, error) {
+	done := make(chan dispatch.Result, 1)
+	t.dispatcher.Process(t.tc, env, func(res dispatch.Result) { done <- res })
+
+	res := <-done
+	switch {
+	case res.NotProcessed:
+		t.tc.Logger.Warnf("webhook on %s (%s) not processed: %s", env.ReceivedTopic, env.CorrelationID, res.Err.Error())
+		return nil, "", http.StatusServiceUnavailable, res.Err
+	case res.Err != nil:
+		t.tc.Logger.Errorf("failed to process webhook on %s (%s): %s", env.ReceivedTopic, env.CorrelationID, res.Err.Error())
+		return nil, "", http.StatusInternalServerError, res.Err
+	}
+	return res.Data, res.ContentType, 0, nil
+}
@@ -0,0 +1,249 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+	"github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap/interfaces/mocks"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"custom-trigger/internal/triggertest"
+)
+
+const testKey = "s3cr3t"
+
+var testHooks = map[string]HookConfig{
+	"github": {
+		Path:           "/github",
+		EventHeader:    "X-GitHub-Event",
+		Scheme:         SchemeHMAC,
+		Header:         "X-Hub-Signature-256",
+		Prefix:         "sha256=",
+		SecretName:     "webhooks",
+		DeliveryHeader: "X-GitHub-Delivery",
+	},
+	"stripe": {
+		Path:         "/stripe",
+		Scheme:       SchemeStripe,
+		SecretName:   "webhooks",
+		ReplayWindow: "1m",
+	},
+	"gitlab": {
+		Path:       "/gitlab",
+		Topic:      "scm/gitlab",
+		Scheme:     SchemeSharedSecret,
+		Header:     "X-Gitlab-Token",
+		SecretName: "webhooks",
+	},
+	"open": {
+		Path:   "/open",
+		Scheme: SchemeNone,
+	},
+}
+
+func startTrigger(t *testing.T, cfg Config) (string, *triggertest.Pipeline) {
+	trigger, p := newTrigger(cfg, testKey)
+	_, err := triggertest.Start(t, trigger)
+	require.NoError(t, err)
+	return "http://" + trigger.addr.String(), p
+}
+
+// newTrigger returns a trigger whose hooks use the key of the webhooks secret
+func newTrigger(cfg Config, key string) (*trigger, *triggertest.Pipeline) {
+	secretProvider := &mocks.SecretProvider{}
+	secretProvider.On("GetSecret", "webhooks", defaultSecretKey).Return(map[string]string{defaultSecretKey: key}, nil)
+
+	p := &triggertest.Pipeline{}
+	trigger := &trigger{
+		tc: triggertest.TriggerConfig(p, func(config interfaces.UpdatableConfig) {
+			config.(*serviceConfig).WebhookTrigger = cfg
+		}),
+		secretProvider: secretProvider,
+		metricsManager: triggertest.MetricsManager(),
+	}
+	return trigger, p
+}
+
+func testConfig() Config {
+	return Config{ListenAddress: "127.0.0.1:0", MaxBodySize: 64, Hooks: testHooks}
+}
+
+func hexMAC(payload string) string {
+	mac := hmac.New(sha256.New, []byte(testKey))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func post(t *testing.T, url string, body string, headers map[string]string) (*http.Response, string) {
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	require.NoError(t, err)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(data)
+}
+
+func TestHMAC(t *testing.T) {
+	url, p := startTrigger(t, testConfig())
+
+	headers := map[string]string{
+		"X-Hub-Signature-256":    "sha256=" + hexMAC("hello"),
+		"X-GitHub-Event":         "push",
+		"X-GitHub-Delivery":      "delivery-1",
+		common.CorrelationHeader: "correlation-1",
+	}
+	resp, body := post(t, url+"/github?ref=main", "hello", headers)
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, "HELLO", body)
+	assert.Equal(t, common.ContentTypeText, resp.Header.Get(common.ContentType))
+	assert.Equal(t, "correlation-1", resp.Header.Get(common.CorrelationHeader))
+
+	env := p.Last()
+	assert.Equal(t, "webhook/github/push", env.ReceivedTopic)
+	assert.Equal(t, common.ContentTypeJSON, env.ContentType)
+	assert.Equal(t, map[string]string{"ref": "main"}, env.QueryParams)
+
+	resp, _ = post(t, url+"/github", "hello", headers)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode, "replayed delivery")
+
+	headers["X-GitHub-Delivery"] = "delivery-2"
+	headers["X-Hub-Signature-256"] = "sha256=" + hexMAC("other")
+	resp, _ = post(t, url+"/github", "hello", headers)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "invalid signature")
+
+	delete(headers, "X-Hub-Signature-256")
+	resp, _ = post(t, url+"/github", "hello", headers)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "missing signature")
+}
+
+func TestFailedDeliveryIsRetried(t *testing.T) {
+	url, p := startTrigger(t, testConfig())
+
+	headers := map[string]string{
+		"X-Hub-Signature-256": "sha256=" + hexMAC("fail"),
+		"X-GitHub-Delivery":   "delivery-1",
+	}
+	resp, _ := post(t, url+"/github", "fail", headers)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	// the retry of the failed delivery is processed again rather than rejected as a replay
+	resp, _ = post(t, url+"/github", "fail", headers)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(t, 2, p.Count())
+}
+
+func TestEmptyKeyRejected(t *testing.T) {
+	trigger, _ := newTrigger(testConfig(), "")
+	_, err := trigger.Initialize(&sync.WaitGroup{}, context.Background(), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "secret is empty")
+}
+
+func TestStripe(t *testing.T) {
+	url, _ := startTrigger(t, testConfig())
+
+	stripeHeader := func(at time.Time, body string) map[string]string {
+		timestamp := fmt.Sprint(at.Unix())
+		return map[string]string{stripeSignatureHeader: "t=" + timestamp + ",v1=00ff,v1=" + hexMAC(timestamp+"."+body)}
+	}
+
+	resp, body := post(t, url+"/stripe", "hello", stripeHeader(time.Now(), "hello"))
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, "HELLO", body)
+
+	resp, _ = post(t, url+"/stripe", "hello", stripeHeader(time.Now().Add(-2*time.Minute), "hello"))
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "timestamp outside of the replay window")
+
+	resp, _ = post(t, url+"/stripe", "hello", stripeHeader(time.Now(), "other"))
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "invalid signature")
+}
+
+func TestSharedSecret(t *testing.T) {
+	url, p := startTrigger(t, testConfig())
+
+	resp, body := post(t, url+"/gitlab", "hello", map[string]string{"X-Gitlab-Token": testKey})
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	assert.Equal(t, "scm/gitlab", p.Last().ReceivedTopic)
+
+	resp, _ = post(t, url+"/gitlab", "hello", map[string]string{"X-Gitlab-Token": "wrong"})
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestRequestErrors(t *testing.T) {
+	url, _ := startTrigger(t, testConfig())
+
+	resp, body := post(t, url+"/open", "fail", nil)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Contains(t, body, "pipeline failed")
+
+	resp, _ = post(t, url+"/unknown", "hello", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, _ = post(t, url+"/open", strings.Repeat("x", 65), nil)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	resp, err := http.Get(url + "/open")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestParseHooks(t *testing.T) {
+	tests := []struct {
+		name string
+		hook HookConfig
+	}{
+		{"relative path", HookConfig{Path: "hook", Scheme: SchemeNone}},
+		{"missing scheme", HookConfig{Path: "/hook"}},
+		{"invalid scheme", HookConfig{Path: "/hook", Scheme: "basic"}},
+		{"hmac without header", HookConfig{Path: "/hook", Scheme: SchemeHMAC, SecretName: "s"}},
+		{"hmac without secret", HookConfig{Path: "/hook", Scheme: SchemeHMAC, Header: "X-Signature"}},
+		{"invalid algorithm", HookConfig{Path: "/hook", Scheme: SchemeHMAC, Header: "X-Signature", SecretName: "s", Algorithm: "md5"}},
+		{"invalid replay window", HookConfig{Path: "/hook", Scheme: SchemeStripe, SecretName: "s", ReplayWindow: "0s"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseHooks(Config{Hooks: map[string]HookConfig{"hook": test.hook}})
+			require.Error(t, err)
+		})
+	}
+
+	hooks, err := parseHooks(Config{Hooks: testHooks})
+	require.NoError(t, err)
+	assert.Equal(t, "webhook/stripe", hooks["/stripe"].Topic)
+	assert.Equal(t, stripeSignatureHeader, hooks["/stripe"].Header)
+	assert.Equal(t, time.Minute, hooks["/stripe"].replayWindow)
+}
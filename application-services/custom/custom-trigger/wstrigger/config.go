@@ -0,0 +1,130 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package wstrigger
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"custom-trigger/dispatch"
+	"custom-trigger/internal/triggerconfig"
+)
+
+const (
+	sectionName = "WebSocketTrigger"
+
+	defaultListenAddress      = ":59783"
+	defaultPath               = "/ws"
+	defaultMaxConnections     = 100
+	defaultMaxMessageSize     = 64 << 10
+	defaultPingInterval       = 30 * time.Second
+	defaultPongTimeout        = 60 * time.Second
+	defaultCorrelationIDField = "correlationId"
+)
+
+// Config holds the settings of the WebSocket trigger, loaded from the WebSocketTrigger section
+type Config struct {
+	// ListenAddress is the address of the HTTP server accepting the connections, defaults to :59783
+	ListenAddress string
+	// Path is the url path of the connections, defaults to /ws. The rest of the path is the topic of the
+	// messages, so that the messages of a connection to /ws/commands/pump are received on commands/pump.
+	Path string
+	// TopicField is a field of the JSON messages holding their topic, which takes precedence over the path
+	TopicField string
+	// CorrelationIDField is a field of the JSON messages holding their correlation id, defaults to correlationId
+	CorrelationIDField string
+	// AllowedOrigins are the origins of the browsers allowed to connect, * allowing all. The connections from
+	// the origin of the service itself are allowed when not set.
+	AllowedOrigins []string
+	// MaxConnections is the maximum number of open connections, defaults to 100
+	MaxConnections int
+	// MaxMessageSize is the maximum size in bytes of a message, the connection is closed when exceeded.
+	// Defaults to 64KB.
+	MaxMessageSize int64
+	// PingInterval is the interval of the pings sent to the clients, defaults to 30s
+	PingInterval string
+	// PongTimeout is the time a connection is closed after when no pong or message is received, defaults to 60s
+	PongTimeout string
+	// Dispatch bounds the number of messages processed concurrently
+	Dispatch dispatch.Config
+}
+
+// serviceConfig wraps the WebSocketTrigger section so that it can be loaded by the ConfigLoader of the trigger
+type serviceConfig struct {
+	WebSocketTrigger Config
+}
+
+// UpdateFromRaw updates the configuration from raw data received from the Configuration Provider.
+func (c *serviceConfig) UpdateFromRaw(rawConfig interface{}) bool {
+	return triggerconfig.UpdateFromRaw(c, rawConfig)
+}
+
+// settings are the parsed Config
+type settings struct {
+	Config
+	pingInterval time.Duration
+	pongTimeout  time.Duration
+}
+
+// parseConfig applies the defaults and validates the configuration.
+func parseConfig(cfg Config) (settings, error) {
+	s := settings{Config: cfg}
+	if s.ListenAddress == "" {
+		s.ListenAddress = defaultListenAddress
+	}
+	if s.Path == "" {
+		s.Path = defaultPath
+	}
+	if !strings.HasPrefix(s.Path, "/") {
+		return s, fmt.Errorf("Path '%s' must start with /", s.Path)
+	}
+	s.Path = strings.TrimSuffix(s.Path, "/")
+	if s.CorrelationIDField == "" {
+		s.CorrelationIDField = defaultCorrelationIDField
+	}
+	if s.MaxConnections <= 0 {
+		s.MaxConnections = defaultMaxConnections
+	}
+	if s.MaxMessageSize <= 0 {
+		s.MaxMessageSize = defaultMaxMessageSize
+	}
+
+	var err error
+	if s.pingInterval, err = parseDuration("PingInterval", s.PingInterval, defaultPingInterval); err != nil {
+		return s, err
+	}
+	if s.pongTimeout, err = parseDuration("PongTimeout", s.PongTimeout, defaultPongTimeout); err != nil {
+		return s, err
+	}
+	if s.pongTimeout <= s.pingInterval {
+		return s, errors.New("PongTimeout must be longer than PingInterval")
+	}
+	return s, nil
+}
+
+func parseDuration(name string, value string, defaultValue time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s '%s'", name, value)
+	}
+	return d, nil
+}
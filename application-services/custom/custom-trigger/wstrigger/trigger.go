@@ -0,0 +1,423 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package wstrigger provides a custom trigger running the pipelines for the messages received on WebSocket
+// connections, such as the commands of browser dashboards, and writing the pipeline responses back on the
+// connection they were received on.
+package wstrigger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+	"github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap"
+	bootstrapInterfaces "github.com/edgexfoundry/go-mod-bootstrap/v3/bootstrap/interfaces"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/clients/logger"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/edgexfoundry/go-mod-messaging/v3/pkg/types"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+
+	"custom-trigger/dispatch"
+)
+
+const (
+	shutdownTimeout = 5 * time.Second
+	writeTimeout    = 10 * time.Second
+
+	contentTypeBinary = "application/octet-stream"
+)
+
+// Response is written on the connection for each message received, once processed by the pipelines
+type Response struct {
+	CorrelationID string `json:"correlationId"`
+	Topic         string `json:"topic,omitempty"`
+	ContentType   string `json:"contentType,omitempty"`
+	// Payload is the response data of the first pipeline: JSON data as is, other text as a string and binary
+	// data as a base64 string
+	Payload json.RawMessage `json:"payload,omitempty"`
+	// Error is set when the message failed to be processed
+	Error string `json:"error,omitempty"`
+}
+
+type trigger struct {
+	tc             interfaces.TriggerConfig
+	metricsManager bootstrapInterfaces.MetricsManager
+
+	cfg        settings
+	upgrader   websocket.Upgrader
+	dispatcher *dispatch.Dispatcher
+	// slots holds a value for each open connection, bounding their number
+	slots chan struct{}
+
+	mutex       sync.Mutex
+	connections map[*connection]struct{}
+	closing     bool
+	// serving tracks the connections until they are closed
+	serving sync.WaitGroup
+	// addr is the address the server listens on, once initialized
+	addr net.Addr
+}
+
+// connection is a WebSocket connection, whose messages are received on the topic of its path
+type connection struct {
+	ws          *websocket.Conn
+	lc          logger.LoggingClient
+	topic       string
+	queryParams map[string]string
+	// writeMutex serializes the responses written by the pipelines
+	writeMutex sync.Mutex
+}
+
+// NewFactory returns the factory of the WebSocket trigger, to be registered with RegisterCustomTriggerFactory.
+func NewFactory(service interfaces.ApplicationService) func(interfaces.TriggerConfig) (interfaces.Trigger, error) {
+	return func(tc interfaces.TriggerConfig) (interfaces.Trigger, error) {
+		return &trigger{
+			tc:             tc,
+			metricsManager: service.MetricsManager(),
+		}, nil
+	}
+}
+
+func (t *trigger) Initialize(wg *sync.WaitGroup, ctx context.Context, _ <-chan interfaces.BackgroundMessage) (bootstrap.Deferred, error) {
+	sc := &serviceConfig{}
+	if err := t.tc.ConfigLoader(sc, sectionName); err != nil {
+		return nil, fmt.Errorf("failed to load the %s configuration: %w", sectionName, err)
+	}
+
+	var err error
+	if t.cfg, err = parseConfig(sc.WebSocketTrigger); err != nil {
+		return nil, fmt.Errorf("invalid %s configuration: %w", sectionName, err)
+	}
+	t.slots = make(chan struct{}, t.cfg.MaxConnections)
+	t.connections = map[*connection]struct{}{}
+	if len(t.cfg.AllowedOrigins) > 0 {
+		// the default check of the upgrader only allows the origin of the service itself
+		t.upgrader.CheckOrigin = t.checkOrigin
+	}
+
+	if t.dispatcher, err = dispatch.New(t.cfg.Dispatch); err != nil {
+		return nil, fmt.Errorf("invalid %s Dispatch configuration: %w", sectionName, err)
+	}
+	if err = t.dispatcher.RegisterMetrics(t.metricsManager, map[string]string{"trigger": "custom-websocket"}); err != nil {
+		t.dispatcher.Stop()
+		return nil, fmt.Errorf("failed to register the dispatch metrics: %w", err)
+	}
+
+	listener, err := net.Listen("tcp", t.cfg.ListenAddress)
+	if err != nil {
+		t.dispatcher.Stop()
+		t.dispatcher.UnregisterMetrics(t.metricsManager)
+		return nil, fmt.Errorf("failed to listen on %s: %w", t.cfg.ListenAddress, err)
+	}
+	t.addr = listener.Addr()
+	server := &http.Server{
+		Handler:           t,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		t.tc.Logger.Infof("WebSocket trigger listening on %s%s", t.addr.String(), t.cfg.Path)
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			t.tc.Logger.Errorf("WebSocket server failed: %s", err.Error())
+		}
+	}()
+
+	// the connections are not tracked by the server once upgraded, they are closed once their messages being
+	// processed are answered
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			t.tc.Logger.Warnf("WebSocket server shutdown: %s", err.Error())
+		}
+		t.closeConnections()
+
+		served := make(chan struct{})
+		go func() {
+			t.serving.Wait()
+			close(served)
+		}()
+		select {
+		case <-served:
+		case <-shutdownCtx.Done():
+			t.tc.Logger.Warn("WebSocket connections still open after the shutdown timeout")
+		}
+	}()
+
+	return func() {
+		t.dispatcher.Stop()
+		t.dispatcher.UnregisterMetrics(t.metricsManager)
+	}, nil
+}
+
+// checkOrigin allows the connections from the AllowedOrigins, and the clients which are not browsers.
+func (t *trigger) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range t.cfg.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(origin, allowed) {
+			return true
+		}
+	}
+	t.tc.Logger.Warnf("WebSocket connection from origin %s rejected", origin)
+	return false
+}
+
+// ServeHTTP upgrades the requests to WebSocket connections and receives their messages until they are closed.
+func (t *trigger) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != t.cfg.Path && !strings.HasPrefix(r.URL.Path, t.cfg.Path+"/") {
+		http.NotFound(w, r)
+		return
+	}
+	select {
+	case t.slots <- struct{}{}:
+	default:
+		t.tc.Logger.Warnf("WebSocket connection from %s rejected: %d connections open", r.RemoteAddr, t.cfg.MaxConnections)
+		http.Error(w, "too many connections", http.StatusServiceUnavailable)
+		return
+	}
+	defer func() { <-t.slots }()
+
+	// the upgrader responds to the failed requests
+	ws, err := t.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := &connection{
+		ws:    ws,
+		lc:    t.tc.Logger,
+		topic: strings.Trim(strings.TrimPrefix(r.URL.Path, t.cfg.Path), "/"),
+	}
+	if query := r.URL.Query(); len(query) > 0 {
+		c.queryParams = make(map[string]string, len(query))
+		for name := range query {
+			c.queryParams[name] = query.Get(name)
+		}
+	}
+
+	t.mutex.Lock()
+	if t.closing {
+		t.mutex.Unlock()
+		c.close(websocket.CloseGoingAway)
+		return
+	}
+	t.connections[c] = struct{}{}
+	t.serving.Add(1)
+	t.mutex.Unlock()
+
+	t.tc.Logger.Debugf("WebSocket connection from %s on %s", r.RemoteAddr, r.URL.Path)
+	t.serve(c)
+
+	t.mutex.Lock()
+	delete(t.connections, c)
+	t.mutex.Unlock()
+	t.serving.Done()
+}
+
+// closeConnections stops reading the messages of the open connections, which are then closed by serve.
+func (t *trigger) closeConnections() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.closing = true
+	for c := range t.connections {
+		_ = c.ws.SetReadDeadline(time.Now())
+	}
+}
+
+// serve receives the messages of the connection and sends them through the pipelines. The connection is closed
+// once it fails to be read and the responses to the messages received are written.
+func (t *trigger) serve(c *connection) {
+	var pending sync.WaitGroup
+	done := make(chan struct{})
+	defer func() {
+		close(done)
+		pending.Wait()
+		code := websocket.CloseNormalClosure
+		t.mutex.Lock()
+		if t.closing {
+			code = websocket.CloseGoingAway
+		}
+		t.mutex.Unlock()
+		c.close(code)
+	}()
+
+	c.ws.SetReadLimit(t.cfg.MaxMessageSize)
+	// the deadline is no longer extended once closeConnections has set it
+	extendDeadline := func(string) error {
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+		if t.closing {
+			return nil
+		}
+		return c.ws.SetReadDeadline(time.Now().Add(t.cfg.pongTimeout))
+	}
+	_ = extendDeadline("")
+	c.ws.SetPongHandler(extendDeadline)
+
+	go func() {
+		ticker := time.NewTicker(t.cfg.pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		messageType, data, err := c.ws.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				t.tc.Logger.Debugf("WebSocket connection closed: %s", err.Error())
+			}
+			return
+		}
+		_ = extendDeadline("")
+
+		env, err := t.envelope(c, messageType, data)
+		if err != nil {
+			c.write(Response{CorrelationID: env.CorrelationID, Error: err.Error()})
+			continue
+		}
+		pending.Add(1)
+		t.submit(env, func(resp Response) {
+			defer pending.Done()
+			c.write(resp)
+		})
+	}
+}
+
+// envelope builds the envelope of a message, whose topic and correlation id are read from the TopicField and
+// CorrelationIDField of JSON messages.
+func (t *trigger) envelope(c *connection, messageType int, data []byte) (types.MessageEnvelope, error) {
+	env := types.MessageEnvelope{
+		ContentType:   common.ContentTypeJSON,
+		Payload:       data,
+		ReceivedTopic: c.topic,
+	}
+	if messageType == websocket.BinaryMessage {
+		env.ContentType = contentTypeBinary
+	} else {
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(data, &fields) == nil {
+			if topic := stringField(fields, t.cfg.TopicField); topic != "" {
+				env.ReceivedTopic = topic
+			}
+			env.CorrelationID = stringField(fields, t.cfg.CorrelationIDField)
+		}
+	}
+	if env.CorrelationID == "" {
+		env.CorrelationID = uuid.NewString()
+	}
+	if len(c.queryParams) > 0 {
+		env.QueryParams = make(map[string]string, len(c.queryParams))
+		for name, value := range c.queryParams {
+			env.QueryParams[name] = value
+		}
+	}
+
+	if env.ReceivedTopic == "" {
+		if t.cfg.TopicField != "" {
+			return env, fmt.Errorf("missing topic: connect to %s/<topic> or set the %s field", t.cfg.Path, t.cfg.TopicField)
+		}
+		return env, fmt.Errorf("missing topic: connect to %s/<topic>", t.cfg.Path)
+	}
+	return env, nil
+}
+
+// stringField returns the value of a string field, or an empty string when it is not set.
+func stringField(fields map[string]json.RawMessage, name string) string {
+	var value string
+	if name != "" {
+		_ = json.Unmarshal(fields[name], &value)
+	}
+	return value
+}
+
+// submit runs the pipelines through the dispatcher, calling respond with the response data of the first one.
+func (t *trigger) submit(env types.MessageEnvelope, respond func(Response)) {
+	t.dispatcher.Process(t.tc, env, func(res dispatch.Result) {
+		resp := Response{CorrelationID: env.CorrelationID, Topic: env.ReceivedTopic}
+		switch {
+		case res.NotProcessed:
+			t.tc.Logger.Warnf("WebSocket message on %s (%s) not processed: %s", env.ReceivedTopic, env.CorrelationID, res.Err.Error())
+			resp.Error = res.Err.Error()
+		case res.Err != nil:
+			t.tc.Logger.Errorf("failed to process WebSocket message on %s (%s): %s", env.ReceivedTopic, env.CorrelationID, res.Err.Error())
+			resp.Error = res.Err.Error()
+		default:
+			resp.ContentType = res.ContentType
+			resp.Payload = encodePayload(res.Data, res.ContentType)
+		}
+		respond(resp)
+	})
+}
+
+// encodePayload returns the response data as JSON: as is when it is JSON, as a string when it is other text,
+// and as a base64 string otherwise.
+func encodePayload(data []byte, contentType string) json.RawMessage {
+	if len(data) == 0 {
+		return nil
+	}
+	if (contentType == "" || strings.HasPrefix(contentType, common.ContentTypeJSON)) && json.Valid(data) {
+		return data
+	}
+	var encoded []byte
+	if utf8.Valid(data) {
+		encoded, _ = json.Marshal(string(data))
+	} else {
+		encoded, _ = json.Marshal(data)
+	}
+	return encoded
+}
+
+// write writes the response as a JSON text message.
+func (c *connection) write(resp Response) {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	_ = c.ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := c.ws.WriteJSON(resp); err != nil {
+		c.lc.Warnf("failed to write the WebSocket response (%s): %s", resp.CorrelationID, err.Error())
+	}
+}
+
+// close sends a close message with the code, then closes the connection.
+func (c *connection) close(code int) {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	_ = c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, ""), time.Now().Add(writeTimeout))
+	_ = c.ws.Close()
+}
@@ -0,0 +1,290 @@
+//
+// Copyright (c) 2023 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package wstrigger
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"custom-trigger/internal/triggertest"
+)
+
+// startTrigger starts the trigger and returns the url of its Path, along with the function stopping it.
+func startTrigger(t *testing.T, cfg Config) (string, *triggertest.Pipeline, func()) {
+	if cfg.ListenAddress == "" {
+		cfg.ListenAddress = "127.0.0.1:0"
+	}
+	p := &triggertest.Pipeline{}
+	trigger := &trigger{
+		tc: triggertest.TriggerConfig(p, func(config interfaces.UpdatableConfig) {
+			config.(*serviceConfig).WebSocketTrigger = cfg
+		}),
+		metricsManager: triggertest.MetricsManager(),
+	}
+	stop, err := triggertest.Start(t, trigger)
+	require.NoError(t, err)
+	return "ws://" + trigger.addr.String() + trigger.cfg.Path, p, stop
+}
+
+func dial(t *testing.T, url string, header http.Header) *websocket.Conn {
+	conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func request(t *testing.T, conn *websocket.Conn, message string) Response {
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(message)))
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(triggertest.Timeout)))
+	var resp Response
+	require.NoError(t, conn.ReadJSON(&resp))
+	return resp
+}
+
+func TestTopicFromPath(t *testing.T) {
+	url, p, _ := startTrigger(t, Config{})
+	conn := dial(t, url+"/commands/pump?device=d1", nil)
+
+	resp := request(t, conn, "start")
+	assert.Equal(t, "commands/pump", resp.Topic)
+	assert.Equal(t, common.ContentTypeText, resp.ContentType)
+	assert.Equal(t, `"START"`, string(resp.Payload))
+	assert.Empty(t, resp.Error)
+
+	env := p.Last()
+	assert.Equal(t, "commands/pump", env.ReceivedTopic)
+	assert.Equal(t, resp.CorrelationID, env.CorrelationID)
+	assert.Equal(t, common.ContentTypeJSON, env.ContentType)
+	assert.Equal(t, "d1", env.QueryParams["device"])
+
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, []byte{0x01}))
+	var binary Response
+	require.NoError(t, conn.ReadJSON(&binary))
+	assert.Equal(t, contentTypeBinary, p.Last().ContentType)
+
+	resp = request(t, conn, "fail")
+	assert.Equal(t, "pipeline failed", resp.Error)
+	assert.Empty(t, resp.Payload)
+}
+
+func TestTopicFromField(t *testing.T) {
+	url, _, _ := startTrigger(t, Config{TopicField: "topic"})
+	conn := dial(t, url+"/commands", nil)
+
+	resp := request(t, conn, `{"topic":"commands/valve","correlationId":"c1"}`)
+	assert.Equal(t, "commands/valve", resp.Topic)
+	assert.Equal(t, "c1", resp.CorrelationID)
+
+	resp = request(t, conn, `{"correlationId":"c2"}`)
+	assert.Equal(t, "commands", resp.Topic, "the path is used without the field")
+
+	conn = dial(t, url, nil)
+	resp = request(t, conn, `{"correlationId":"c3"}`)
+	assert.Equal(t, "c3", resp.CorrelationID)
+	assert.True(t, strings.HasPrefix(resp.Error, "missing topic"), resp.Error)
+}
+
+func TestMaxConnections(t *testing.T) {
+	url, _, _ := startTrigger(t, Config{MaxConnections: 1})
+	conn := dial(t, url+"/a", nil)
+
+	_, resp, err := websocket.DefaultDialer.Dial(url+"/a", nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	// the connection is released once closed
+	require.NoError(t, conn.Close())
+	require.Eventually(t, func() bool {
+		conn, _, err := websocket.DefaultDialer.Dial(url+"/a", nil)
+		if err == nil {
+			_ = conn.Close()
+		}
+		return err == nil
+	}, triggertest.Timeout, 10*time.Millisecond)
+}
+
+func TestAllowedOrigins(t *testing.T) {
+	url, _, _ := startTrigger(t, Config{AllowedOrigins: []string{"https://dashboard.example.com"}})
+
+	dial(t, url+"/a", http.Header{"Origin": {"https://dashboard.example.com"}})
+	dial(t, url+"/a", nil)
+	_, resp, err := websocket.DefaultDialer.Dial(url+"/a", http.Header{"Origin": {"https://evil.example.com"}})
+	require.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// only the origin of the service is allowed by default
+	url, _, _ = startTrigger(t, Config{})
+	_, resp, err = websocket.DefaultDialer.Dial(url+"/a", http.Header{"Origin": {"https://dashboard.example.com"}})
+	require.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	_, resp, err = websocket.DefaultDialer.Dial(strings.TrimSuffix(url, "/ws")+"/other", nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestKeepalive(t *testing.T) {
+	url, _, _ := startTrigger(t, Config{PingInterval: "20ms", PongTimeout: "100ms"})
+
+	// the pings are answered while the client reads
+	alive := dial(t, url+"/a", nil)
+	responses := make(chan Response)
+	go func() {
+		for {
+			var resp Response
+			if err := alive.ReadJSON(&resp); err != nil {
+				close(responses)
+				return
+			}
+			responses <- resp
+		}
+	}()
+	// the pings are left unanswered by the client which does not read
+	silent := dial(t, url+"/a", nil)
+
+	time.Sleep(300 * time.Millisecond)
+	require.NoError(t, alive.WriteMessage(websocket.TextMessage, []byte("ping")))
+	select {
+	case resp, ok := <-responses:
+		require.True(t, ok, "the connection is closed")
+		assert.Equal(t, `"PING"`, string(resp.Payload))
+	case <-time.After(triggertest.Timeout):
+		require.Fail(t, "no response")
+	}
+
+	// the connection is closed by the server rather than timing out
+	require.NoError(t, silent.SetReadDeadline(time.Now().Add(triggertest.Timeout)))
+	for {
+		if _, _, err := silent.ReadMessage(); err != nil {
+			assert.False(t, errors.Is(err, os.ErrDeadlineExceeded), err.Error())
+			break
+		}
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	url, _, stop := startTrigger(t, Config{})
+	conn := dial(t, url+"/a", nil)
+	request(t, conn, "hello")
+
+	stop()
+	_, _, err := conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err.Error())
+}
+
+func TestShutdownWithPingingClient(t *testing.T) {
+	url, _, stop := startTrigger(t, Config{PingInterval: "10ms", PongTimeout: "50ms"})
+	conn := dial(t, url+"/a", nil)
+
+	// the client answers the pings while it reads, and keeps sending pongs which used to extend the read deadline
+	// set by the shutdown
+	closed := make(chan error, 1)
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				closed <- err
+				return
+			}
+		}
+	}()
+	go func() {
+		for {
+			if err := conn.WriteControl(websocket.PongMessage, nil, time.Now().Add(time.Second)); err != nil {
+				return
+			}
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		require.Fail(t, "the shutdown waits for the pinging client")
+	}
+	// the pongs written while the server closes may reset the connection before the close message is read
+	select {
+	case <-closed:
+	case <-time.After(triggertest.Timeout):
+		require.Fail(t, "the connection is not closed")
+	}
+}
+
+func TestEncodePayload(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+		expected    string
+	}{
+		{"json", []byte(`{"a":1}`), common.ContentTypeJSON, `{"a":1}`},
+		{"json without content type", []byte(`[1,2]`), "", `[1,2]`},
+		{"invalid json", []byte(`{"a"`), common.ContentTypeJSON, `"{\"a\""`},
+		{"text", []byte("42"), common.ContentTypeText, `"42"`},
+		{"binary", []byte{0xff, 0x00}, common.ContentTypeCBOR, `"/wA="`},
+		{"empty", nil, common.ContentTypeJSON, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			encoded := encodePayload(test.data, test.contentType)
+			assert.Equal(t, test.expected, string(encoded))
+			if len(encoded) > 0 {
+				assert.True(t, json.Valid(encoded))
+			}
+		})
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"invalid path", Config{Path: "ws"}},
+		{"invalid ping interval", Config{PingInterval: "often"}},
+		{"negative pong timeout", Config{PongTimeout: "-1s"}},
+		{"pong timeout shorter than ping interval", Config{PingInterval: "1m", PongTimeout: "30s"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseConfig(test.cfg)
+			require.Error(t, err)
+		})
+	}
+
+	s, err := parseConfig(Config{Path: "/dashboard/"})
+	require.NoError(t, err)
+	assert.Equal(t, "/dashboard", s.Path)
+	assert.Equal(t, defaultPingInterval, s.pingInterval)
+	assert.Equal(t, defaultMaxConnections, s.MaxConnections)
+}